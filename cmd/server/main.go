@@ -4,10 +4,12 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -79,6 +81,15 @@ func main() {
 	// Initialize structured logger
 	logger := NewLogger()
 
+	// "server migrate ..." manages the database schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			logger.Error("Migration failed", err, nil)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Initialize a new router
 	r := chi.NewRouter()
 
@@ -92,18 +103,26 @@ func main() {
 	r.Use(app.RateLimitMiddleware(rateLimiter))
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000", "http://localhost:8080", "http://localhost"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Sec-WebSocket-Key", "Sec-WebSocket-Version", "Sec-WebSocket-Extensions", "Sec-WebSocket-Protocol"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
-	// Create a new Ollama embedding client
-	ollamaClient := emb.NewOllamaClient()
+	// Create the LLM provider selected by configuration
+	llmProvider, err := emb.NewProvider()
+	if err != nil {
+		logger.Error("Failed to initialize LLM provider", err, nil)
+		os.Exit(1)
+	}
 
-	// Initialize Qdrant client
-	qdrantClient := vec.NewQdrantClient()
+	// Create the vector store selected by configuration
+	vectorStore, err := vec.NewStore()
+	if err != nil {
+		logger.Error("Failed to initialize vector store", err, nil)
+		os.Exit(1)
+	}
 
 	// Initialize PostgreSQL store
 	postgresStore, err := repo.NewPostgresStore()
@@ -137,18 +156,30 @@ func main() {
 	defer kafkaProducer.Close()
 
 	// Create the main application service and handlers
-	svc := app.NewService(ollamaClient, qdrantClient, postgresStore, kafkaProducer, redisCache)
+	svc := app.NewService(llmProvider, vectorStore, postgresStore, postgresStore, kafkaProducer, redisCache)
 	handler := app.NewHandler(svc)
 	wsHandler := app.NewWebSocketHandler(svc)
 
 	// Set up API routes
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Post("/chat", handler.HandleChat)
+		r.Post("/chat/stream", handler.HandleChatStream)
 		r.Post("/chat/history", handler.HandleChatWithHistory)
 		r.Get("/chat/history", handler.HandleGetChatHistory)
 		r.Get("/chat/insights", handler.HandleGetConversationInsights)
 		r.Post("/documents", handler.HandleAddDocument)
+		r.Get("/documents", handler.HandleListDocuments)
+		r.Get("/documents/{id}", handler.HandleGetDocument)
+		r.Put("/documents/{id}", handler.HandleUpdateDocument)
+		r.Patch("/documents/{id}", handler.HandlePatchDocument)
+		r.Delete("/documents/{id}", handler.HandleDeleteDocument)
 		r.Post("/scrape", handler.HandleScrapeDocument)
+		r.Get("/scrape", handler.HandleListScrapeJobs)
+		r.Get("/scrape/{job_id}", handler.HandleGetScrapeJob)
+		r.Post("/crawl", handler.HandleCrawlSite)
+		r.Post("/sitemap", handler.HandleIngestSitemap)
+		r.Get("/admin/dead-letters", handler.HandleListDeadLetters)
+		r.Post("/admin/dead-letters/{job_id}/replay", handler.HandleReplayDeadLetter)
 		r.Get("/documents/search", handler.HandleSearchDocuments)
 		r.Post("/tutorials/generate", handler.HandleGenerateTutorial)
 		r.Post("/tutorials/scrape-and-generate", handler.HandleScrapeAndGenerateTutorial)
@@ -209,6 +240,67 @@ func main() {
 	logger.Info("Server stopped gracefully", nil)
 }
 
+// runMigrate runs the migrate subcommand: "up" applies pending migrations,
+// "down [n]" reverts the last n (default 1) and "status" lists them.
+func runMigrate(args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	db, err := repo.OpenDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := repo.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", command)
+	}
+}
+
 // getEnv gets an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
@@ -3,11 +3,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"tech-docs-ai/internal/cache"
 	"tech-docs-ai/internal/emb"
 	"tech-docs-ai/internal/kafka"
 	"tech-docs-ai/internal/repo"
@@ -18,8 +21,14 @@ func main() {
 	log.Println("Starting Tech Docs AI Worker Service...")
 
 	// Initialize components
-	ollamaClient := emb.NewOllamaClient()
-	qdrantClient := vec.NewQdrantClient()
+	llmProvider, err := emb.NewProvider()
+	if err != nil {
+		log.Fatalf("Failed to initialize LLM provider: %v", err)
+	}
+	vectorStore, err := vec.NewStore()
+	if err != nil {
+		log.Fatalf("Failed to initialize vector store: %v", err)
+	}
 
 	postgresStore, err := repo.NewPostgresStore()
 	if err != nil {
@@ -27,8 +36,15 @@ func main() {
 	}
 	defer postgresStore.Close()
 
+	// The server caches documents, so updated ones are evicted from its cache
+	redisCache, err := cache.NewRedisCache()
+	if err != nil {
+		log.Fatalf("Failed to initialize Redis cache: %v", err)
+	}
+	defer redisCache.Close()
+
 	// Create Kafka consumer for processing scraping jobs
-	consumer := kafka.NewConsumer(postgresStore, ollamaClient, qdrantClient)
+	consumer := kafka.NewConsumer(postgresStore, llmProvider, vectorStore, redisCache)
 	defer consumer.Close()
 
 	// Set up graceful shutdown
@@ -46,7 +62,29 @@ func main() {
 	}()
 
 	// Start the consumer
-	if err := consumer.Start(ctx); err != nil {
-		log.Fatalf("Consumer failed: %v", err)
+	go func() {
+		if err := consumer.Start(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Fatalf("Consumer failed: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+
+	// Let in-flight jobs finish and commit; unfinished ones are delivered again after a restart
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout())
+	defer cancelShutdown()
+	if err := consumer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("In-flight jobs did not finish before shutdown: %v", err)
+	}
+
+	log.Println("Worker stopped")
+}
+
+// shutdownTimeout is how long the worker waits for in-flight jobs on
+// shutdown, set with WORKER_SHUTDOWN_TIMEOUT. It defaults to 30 seconds.
+func shutdownTimeout() time.Duration {
+	if timeout, err := time.ParseDuration(os.Getenv("WORKER_SHUTDOWN_TIMEOUT")); err == nil && timeout > 0 {
+		return timeout
 	}
+	return 30 * time.Second
 }
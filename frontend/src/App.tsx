@@ -10,6 +10,25 @@ interface ChatMessage {
   content: string;
 }
 
+interface Source {
+  document_id: string;
+  title: string;
+  url?: string;
+  category: string;
+  score: number;
+}
+
+// withSources appends the cited documents to an answer as a numbered Markdown list
+const withSources = (content: string, sources?: Source[]): string => {
+  if (!sources || sources.length === 0) {
+    return content;
+  }
+  const list = sources
+    .map((s, i) => `${i + 1}. ${s.url ? `[${s.title}](${s.url})` : s.title} (${s.category})`)
+    .join("\n");
+  return `${content}\n\n---\n**Sources**\n\n${list}`;
+};
+
 interface ChatWindowMessage {
   id: string;
   content: string;
@@ -27,6 +46,7 @@ export default function App() {
   const [chatHistory, setChatHistory] = useState<ChatMessage[]>([]);
   const [loading, setLoading] = useState<boolean>(false);
   const ws = useRef<WebSocket | null>(null);
+  const streaming = useRef<boolean>(false);
 
   useEffect(() => {
     let reconnectAttempts = 0;
@@ -52,11 +72,33 @@ export default function App() {
           if (data.type === "connection") {
             console.log("WebSocket connection confirmed:", data.response);
           } else if (data.type === "chat_response") {
-            setChatHistory((prev) => [...prev, { role: "assistant", content: data.response || data.message }]);
+            setChatHistory((prev) => [...prev, { role: "assistant", content: withSources(data.response || data.message, data.sources) }]);
+            setLoading(false);
+          } else if (data.type === "chat_delta") {
+            // Append streamed tokens to the assistant message being generated
+            const appending = streaming.current;
+            streaming.current = true;
+            setChatHistory((prev) => {
+              if (appending) {
+                const last = prev[prev.length - 1];
+                return [...prev.slice(0, -1), { ...last, content: last.content + data.delta }];
+              }
+              return [...prev, { role: "assistant", content: data.delta }];
+            });
+            setLoading(false);
+          } else if (data.type === "chat_done") {
+            // Replace the streamed text with the final answer
+            const replacing = streaming.current;
+            streaming.current = false;
+            const content = withSources(data.response, data.sources);
+            setChatHistory((prev) => replacing
+              ? [...prev.slice(0, -1), { role: "assistant", content }]
+              : [...prev, { role: "assistant", content }]);
             setLoading(false);
           } else if (data.type === "typing") {
             setLoading(true);
           } else if (data.type === "error") {
+            streaming.current = false;
             setLoading(false);
             setChatHistory((prev) => [...prev, { role: "assistant", content: `❌ **Error occurred**\n\n${data.error}` }]);
           }
@@ -100,7 +142,7 @@ export default function App() {
     if (ws.current && ws.current.readyState === WebSocket.OPEN) {
       setChatHistory((prev) => [...prev, { role: "user", content: msg }]);
       setLoading(true);
-      ws.current.send(JSON.stringify({ type: "chat", message: msg }));
+      ws.current.send(JSON.stringify({ type: "chat", message: msg, stream: true }));
     }
   };
 
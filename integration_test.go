@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -88,39 +89,94 @@ func TestHandlerCreation(t *testing.T) {
 // MockServiceImpl is a simple mock implementation for testing
 type MockServiceImpl struct{}
 
-func (m *MockServiceImpl) Chat(message string) (string, error) {
-	return "Mock response", nil
+func (m *MockServiceImpl) Chat(ctx context.Context, message string, opts app.ChatOptions) (*app.ChatResult, error) {
+	return &app.ChatResult{Response: "Mock response"}, nil
 }
 
-func (m *MockServiceImpl) ChatWithHistory(sessionID, message string) (string, error) {
-	return "Mock response with history", nil
+func (m *MockServiceImpl) ChatStream(ctx context.Context, message string, opts app.ChatOptions, onDelta func(delta string) error) (*app.ChatResult, error) {
+	if err := onDelta("Mock response"); err != nil {
+		return nil, err
+	}
+	return &app.ChatResult{Response: "Mock response"}, nil
+}
+
+func (m *MockServiceImpl) ChatWithHistory(ctx context.Context, sessionID, message string, opts app.ChatOptions) (*app.ChatResult, error) {
+	return &app.ChatResult{Response: "Mock response with history"}, nil
 }
 
-func (m *MockServiceImpl) GetChatHistory(sessionID string, limit int) ([]*types.ChatMessage, error) {
+func (m *MockServiceImpl) ChatWithHistoryStream(ctx context.Context, sessionID, message string, opts app.ChatOptions, onDelta func(delta string) error) (*app.ChatResult, error) {
+	if err := onDelta("Mock response with history"); err != nil {
+		return nil, err
+	}
+	return &app.ChatResult{Response: "Mock response with history"}, nil
+}
+
+func (m *MockServiceImpl) GetChatHistory(ctx context.Context, sessionID string, limit int) ([]*types.ChatMessage, error) {
 	return []*types.ChatMessage{}, nil
 }
 
-func (m *MockServiceImpl) AddDocument(doc *types.Document) error {
+func (m *MockServiceImpl) AddDocument(ctx context.Context, doc *types.Document) error {
+	return nil
+}
+
+func (m *MockServiceImpl) GetDocument(ctx context.Context, id string) (*types.Document, error) {
+	return &types.Document{ID: id}, nil
+}
+
+func (m *MockServiceImpl) ListDocuments(ctx context.Context, opts types.DocumentListOptions) (*types.DocumentList, error) {
+	return &types.DocumentList{Documents: []*types.Document{}, Limit: opts.Limit, Offset: opts.Offset}, nil
+}
+
+func (m *MockServiceImpl) UpdateDocument(ctx context.Context, id string, doc *types.Document) (*types.Document, error) {
+	doc.ID = id
+	return doc, nil
+}
+
+func (m *MockServiceImpl) PatchDocument(ctx context.Context, id string, patch types.DocumentPatch) (*types.Document, error) {
+	return &types.Document{ID: id}, nil
+}
+
+func (m *MockServiceImpl) DeleteDocument(ctx context.Context, id string) error {
 	return nil
 }
 
-func (m *MockServiceImpl) SearchDocuments(query string, limit int) ([]*types.Document, error) {
+func (m *MockServiceImpl) SearchDocuments(ctx context.Context, query string, limit int, filter *types.VectorFilter) ([]*types.Document, error) {
 	return []*types.Document{}, nil
 }
 
-func (m *MockServiceImpl) ScrapeDocument(url, category string, tags []string) error {
+func (m *MockServiceImpl) ScrapeDocument(ctx context.Context, url, category string, tags []string) (string, error) {
+	return "job_1", nil
+}
+
+func (m *MockServiceImpl) CrawlSite(ctx context.Context, seedURL, category string, tags []string, opts types.CrawlOptions) (string, error) {
+	return "job_2", nil
+}
+
+func (m *MockServiceImpl) IngestSitemap(ctx context.Context, sitemapURL, category string, tags []string, opts types.SitemapOptions) (string, error) {
+	return "job_3", nil
+}
+
+func (m *MockServiceImpl) GetScrapeJob(ctx context.Context, jobID string) (*types.ScrapeJobRecord, error) {
+	return &types.ScrapeJobRecord{JobID: jobID, URL: "https://example.com", Status: types.JobSucceeded}, nil
+}
+
+func (m *MockServiceImpl) ReplayDeadLetter(ctx context.Context, id string) error {
 	return nil
 }
 
-func (m *MockServiceImpl) GenerateTutorialFromScrapedData(url, topic string) (string, error) {
+func (m *MockServiceImpl) ListScrapeJobs(ctx context.Context, opts types.ScrapeJobListOptions) (*types.ScrapeJobList, error) {
+	return &types.ScrapeJobList{Jobs: []*types.ScrapeJobRecord{}, Limit: opts.Limit, Offset: opts.Offset}, nil
+}
+
+func (m *MockServiceImpl) GenerateTutorialFromScrapedData(ctx context.Context, url, topic string) (string, error) {
 	return "Mock tutorial", nil
 }
 
-func (m *MockServiceImpl) ScrapeAndGenerateTutorial(url, topic string) (string, error) {
+func (m *MockServiceImpl) ScrapeAndGenerateTutorial(ctx context.Context, url, topic string) (string, error) {
 	return "Mock scrape and tutorial", nil
 }
 
-func (m *MockServiceImpl) GetConversationInsights(sessionID string) (map[string]interface{}, error) {
+func (m *MockServiceImpl) GetConversationInsights(ctx context.Context, sessionID string) (map[string]interface{}, error) {
 	return map[string]interface{}{}, nil
 }
 
@@ -136,11 +192,19 @@ func TestAPIEndpointsIntegration(t *testing.T) {
 	r := chi.NewRouter()
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Post("/chat", handler.HandleChat)
+		r.Post("/chat/stream", handler.HandleChatStream)
 		r.Post("/chat/history", handler.HandleChatWithHistory)
 		r.Get("/chat/history", handler.HandleGetChatHistory)
 		r.Get("/chat/insights", handler.HandleGetConversationInsights)
 		r.Post("/documents", handler.HandleAddDocument)
+		r.Get("/documents", handler.HandleListDocuments)
+		r.Get("/documents/{id}", handler.HandleGetDocument)
+		r.Put("/documents/{id}", handler.HandleUpdateDocument)
+		r.Patch("/documents/{id}", handler.HandlePatchDocument)
+		r.Delete("/documents/{id}", handler.HandleDeleteDocument)
 		r.Post("/scrape", handler.HandleScrapeDocument)
+		r.Get("/scrape", handler.HandleListScrapeJobs)
+		r.Get("/scrape/{job_id}", handler.HandleGetScrapeJob)
 		r.Get("/documents/search", handler.HandleSearchDocuments)
 		r.Post("/tutorials/generate", handler.HandleGenerateTutorial)
 		r.Post("/tutorials/scrape-and-generate", handler.HandleScrapeAndGenerateTutorial)
@@ -158,7 +222,7 @@ func TestAPIEndpointsIntegration(t *testing.T) {
 		
 		assert.Equal(t, http.StatusOK, w.Code)
 		
-		var response map[string]string
+		var response map[string]interface{}
 		err := json.NewDecoder(w.Body).Decode(&response)
 		require.NoError(t, err)
 		assert.Equal(t, "Mock response", response["response"])
@@ -193,6 +257,30 @@ func TestAPIEndpointsIntegration(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 	})
 
+	// Test document endpoints
+	t.Run("Document endpoints", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/documents?category=HTML&limit=5", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/documents/doc_1", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var doc types.Document
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&doc))
+		assert.Equal(t, "doc_1", doc.ID)
+
+		body, _ := json.Marshal(map[string]string{"title": "Updated"})
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("PATCH", "/api/v1/documents/doc_1", bytes.NewReader(body)))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/v1/documents/doc_1", nil))
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
 	// Test scrape endpoint
 	t.Run("Scrape endpoint", func(t *testing.T) {
 		reqBody := map[string]interface{}{
@@ -208,6 +296,18 @@ func TestAPIEndpointsIntegration(t *testing.T) {
 		r.ServeHTTP(w, req)
 		
 		assert.Equal(t, http.StatusOK, w.Code)
+
+		var queued map[string]string
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&queued))
+		assert.Equal(t, "job_1", queued["job_id"])
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/scrape/job_1", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/scrape?status=succeeded", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
 	})
 
 	t.Log("API endpoints integration test completed successfully")
@@ -217,7 +317,7 @@ func TestAPIEndpointsIntegration(t *testing.T) {
 func TestScrapersIntegration(t *testing.T) {
 	// Test W3Schools scraper
 	t.Run("W3Schools scraper", func(t *testing.T) {
-		scraper := scraper.NewW3SchoolsScraper()
+		scraper := scraper.NewW3SchoolsScraper(scraper.NewFetcher())
 		assert.NotNil(t, scraper)
 		
 		// Test with a mock HTML server
@@ -248,7 +348,7 @@ func TestScrapersIntegration(t *testing.T) {
 
 	// Test Universal scraper
 	t.Run("Universal scraper", func(t *testing.T) {
-		scraper := scraper.NewUniversalScraper()
+		scraper := scraper.NewUniversalScraper(scraper.NewFetcher())
 		assert.NotNil(t, scraper)
 		
 		// Test with a mock HTML server
@@ -308,7 +408,8 @@ func TestFullWorkflowIntegration(t *testing.T) {
 	defer kafkaProducer.Close()
 	
 	// Create service
-	service := app.NewService(ollamaClient, qdrantClient, postgresStore, kafkaProducer, redisCache)
+	service := app.NewService(ollamaClient, qdrantClient, postgresStore, postgresStore, kafkaProducer, redisCache)
+	ctx := context.Background()
 	
 	// Test adding a document
 	doc := &types.Document{
@@ -319,17 +420,17 @@ func TestFullWorkflowIntegration(t *testing.T) {
 		Author:   "Integration Test",
 	}
 	
-	err = service.AddDocument(doc)
+	err = service.AddDocument(ctx, doc)
 	require.NoError(t, err)
 	
 	// Test searching for the document
-	docs, err := service.SearchDocuments("integration", 10)
+	docs, err := service.SearchDocuments(ctx, "integration", 10, nil)
 	require.NoError(t, err)
 	assert.Len(t, docs, 1)
 	assert.Equal(t, "Test Integration Document", docs[0].Title)
 	
 	// Test chat functionality
-	response, err := service.Chat("Tell me about integration testing")
+	response, err := service.Chat(ctx, "Tell me about integration testing", app.ChatOptions{})
 	require.NoError(t, err)
 	assert.NotEmpty(t, response)
 	
@@ -426,38 +527,86 @@ func TestErrorHandling(t *testing.T) {
 // ErrorMockService is a mock service that returns errors for testing
 type ErrorMockService struct{}
 
-func (m *ErrorMockService) Chat(message string) (string, error) {
-	return "", fmt.Errorf("mock chat error")
+func (m *ErrorMockService) Chat(ctx context.Context, message string, opts app.ChatOptions) (*app.ChatResult, error) {
+	return nil, fmt.Errorf("mock chat error")
 }
 
-func (m *ErrorMockService) ChatWithHistory(sessionID, message string) (string, error) {
-	return "", fmt.Errorf("mock chat with history error")
+func (m *ErrorMockService) ChatStream(ctx context.Context, message string, opts app.ChatOptions, onDelta func(delta string) error) (*app.ChatResult, error) {
+	return nil, fmt.Errorf("mock chat stream error")
 }
 
-func (m *ErrorMockService) GetChatHistory(sessionID string, limit int) ([]*types.ChatMessage, error) {
+func (m *ErrorMockService) ChatWithHistory(ctx context.Context, sessionID, message string, opts app.ChatOptions) (*app.ChatResult, error) {
+	return nil, fmt.Errorf("mock chat with history error")
+}
+
+func (m *ErrorMockService) ChatWithHistoryStream(ctx context.Context, sessionID, message string, opts app.ChatOptions, onDelta func(delta string) error) (*app.ChatResult, error) {
+	return nil, fmt.Errorf("mock chat with history stream error")
+}
+
+func (m *ErrorMockService) GetChatHistory(ctx context.Context, sessionID string, limit int) ([]*types.ChatMessage, error) {
 	return nil, fmt.Errorf("mock get chat history error")
 }
 
-func (m *ErrorMockService) AddDocument(doc *types.Document) error {
+func (m *ErrorMockService) AddDocument(ctx context.Context, doc *types.Document) error {
 	return fmt.Errorf("mock add document error")
 }
 
-func (m *ErrorMockService) SearchDocuments(query string, limit int) ([]*types.Document, error) {
+func (m *ErrorMockService) GetDocument(ctx context.Context, id string) (*types.Document, error) {
+	return nil, fmt.Errorf("mock get document error")
+}
+
+func (m *ErrorMockService) ListDocuments(ctx context.Context, opts types.DocumentListOptions) (*types.DocumentList, error) {
+	return nil, fmt.Errorf("mock list documents error")
+}
+
+func (m *ErrorMockService) UpdateDocument(ctx context.Context, id string, doc *types.Document) (*types.Document, error) {
+	return nil, fmt.Errorf("mock update document error")
+}
+
+func (m *ErrorMockService) PatchDocument(ctx context.Context, id string, patch types.DocumentPatch) (*types.Document, error) {
+	return nil, fmt.Errorf("mock patch document error")
+}
+
+func (m *ErrorMockService) DeleteDocument(ctx context.Context, id string) error {
+	return fmt.Errorf("mock delete document error")
+}
+
+func (m *ErrorMockService) SearchDocuments(ctx context.Context, query string, limit int, filter *types.VectorFilter) ([]*types.Document, error) {
 	return nil, fmt.Errorf("mock search documents error")
 }
 
-func (m *ErrorMockService) ScrapeDocument(url, category string, tags []string) error {
-	return fmt.Errorf("mock scrape document error")
+func (m *ErrorMockService) ScrapeDocument(ctx context.Context, url, category string, tags []string) (string, error) {
+	return "", fmt.Errorf("mock scrape document error")
+}
+
+func (m *ErrorMockService) CrawlSite(ctx context.Context, seedURL, category string, tags []string, opts types.CrawlOptions) (string, error) {
+	return "", fmt.Errorf("mock crawl site error")
+}
+
+func (m *ErrorMockService) IngestSitemap(ctx context.Context, sitemapURL, category string, tags []string, opts types.SitemapOptions) (string, error) {
+	return "", fmt.Errorf("mock ingest sitemap error")
+}
+
+func (m *ErrorMockService) GetScrapeJob(ctx context.Context, jobID string) (*types.ScrapeJobRecord, error) {
+	return nil, fmt.Errorf("mock get scrape job error")
+}
+
+func (m *ErrorMockService) ReplayDeadLetter(ctx context.Context, id string) error {
+	return fmt.Errorf("mock replay dead letter error")
+}
+
+func (m *ErrorMockService) ListScrapeJobs(ctx context.Context, opts types.ScrapeJobListOptions) (*types.ScrapeJobList, error) {
+	return nil, fmt.Errorf("mock list scrape jobs error")
 }
 
-func (m *ErrorMockService) GenerateTutorialFromScrapedData(url, topic string) (string, error) {
+func (m *ErrorMockService) GenerateTutorialFromScrapedData(ctx context.Context, url, topic string) (string, error) {
 	return "", fmt.Errorf("mock generate tutorial error")
 }
 
-func (m *ErrorMockService) ScrapeAndGenerateTutorial(url, topic string) (string, error) {
+func (m *ErrorMockService) ScrapeAndGenerateTutorial(ctx context.Context, url, topic string) (string, error) {
 	return "", fmt.Errorf("mock scrape and generate tutorial error")
 }
 
-func (m *ErrorMockService) GetConversationInsights(sessionID string) (map[string]interface{}, error) {
+func (m *ErrorMockService) GetConversationInsights(ctx context.Context, sessionID string) (map[string]interface{}, error) {
 	return nil, fmt.Errorf("mock get conversation insights error")
 }
\ No newline at end of file
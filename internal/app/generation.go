@@ -0,0 +1,76 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"tech-docs-ai/internal/types"
+)
+
+// Defaults for the admin-defined bounds on per-request generation options.
+const (
+	DefaultMaxTemperature = 2.0
+	DefaultMaxNumCtx      = 8192
+	DefaultMaxNumPredict  = 4096
+	DefaultMaxStop        = 4
+)
+
+// generationLimits bounds the generation options a client may set on a single request.
+type generationLimits struct {
+	maxTemperature float64
+	maxNumCtx      int
+	maxNumPredict  int
+	maxStop        int
+}
+
+// loadGenerationLimits reads the bounds from the LLM_MAX_TEMPERATURE,
+// LLM_MAX_NUM_CTX, LLM_MAX_NUM_PREDICT and LLM_MAX_STOP environment variables.
+func loadGenerationLimits() generationLimits {
+	limits := generationLimits{
+		maxTemperature: DefaultMaxTemperature,
+		maxNumCtx:      DefaultMaxNumCtx,
+		maxNumPredict:  DefaultMaxNumPredict,
+		maxStop:        DefaultMaxStop,
+	}
+
+	if v, err := strconv.ParseFloat(os.Getenv("LLM_MAX_TEMPERATURE"), 64); err == nil && v >= 0 {
+		limits.maxTemperature = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("LLM_MAX_NUM_CTX")); err == nil && v > 0 {
+		limits.maxNumCtx = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("LLM_MAX_NUM_PREDICT")); err == nil && v > 0 {
+		limits.maxNumPredict = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("LLM_MAX_STOP")); err == nil && v >= 0 {
+		limits.maxStop = v
+	}
+
+	return limits
+}
+
+// validate checks per-request generation options against the limits.
+func (l generationLimits) validate(opts types.GenerationOptions) error {
+	if t := opts.Temperature; t != nil && (*t < 0 || *t > l.maxTemperature) {
+		return fmt.Errorf("temperature must be between 0 and %g", l.maxTemperature)
+	}
+	if p := opts.TopP; p != nil && (*p <= 0 || *p > 1) {
+		return fmt.Errorf("top_p must be greater than 0 and at most 1")
+	}
+	if n := opts.NumCtx; n != nil && (*n < 1 || *n > l.maxNumCtx) {
+		return fmt.Errorf("num_ctx must be between 1 and %d", l.maxNumCtx)
+	}
+	if n := opts.NumPredict; n != nil && (*n < 1 || *n > l.maxNumPredict) {
+		return fmt.Errorf("num_predict must be between 1 and %d", l.maxNumPredict)
+	}
+	if len(opts.Stop) > l.maxStop {
+		return fmt.Errorf("at most %d stop sequences are allowed", l.maxStop)
+	}
+	for _, stop := range opts.Stop {
+		if stop == "" {
+			return fmt.Errorf("stop sequences cannot be empty")
+		}
+	}
+	return nil
+}
@@ -2,48 +2,94 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"tech-docs-ai/internal/types"
+
+	"github.com/go-chi/chi/v5"
 )
 
 // ServiceInterface defines the interface that Service implements
 type ServiceInterface interface {
-	Chat(message string) (string, error)
-	ChatWithHistory(sessionID, message string) (string, error)
-	GetChatHistory(sessionID string, limit int) ([]*types.ChatMessage, error)
-	AddDocument(doc *types.Document) error
-	SearchDocuments(query string, limit int) ([]*types.Document, error)
-	ScrapeDocument(url, category string, tags []string) error
-	GenerateTutorialFromScrapedData(url, topic string) (string, error)
-	ScrapeAndGenerateTutorial(url, topic string) (string, error)
-	GetConversationInsights(sessionID string) (map[string]interface{}, error)
+	Chat(ctx context.Context, message string, opts ChatOptions) (*ChatResult, error)
+	ChatStream(ctx context.Context, message string, opts ChatOptions, onDelta func(delta string) error) (*ChatResult, error)
+	ChatWithHistory(ctx context.Context, sessionID, message string, opts ChatOptions) (*ChatResult, error)
+	ChatWithHistoryStream(ctx context.Context, sessionID, message string, opts ChatOptions, onDelta func(delta string) error) (*ChatResult, error)
+	GetChatHistory(ctx context.Context, sessionID string, limit int) ([]*types.ChatMessage, error)
+	AddDocument(ctx context.Context, doc *types.Document) error
+	GetDocument(ctx context.Context, id string) (*types.Document, error)
+	ListDocuments(ctx context.Context, opts types.DocumentListOptions) (*types.DocumentList, error)
+	UpdateDocument(ctx context.Context, id string, doc *types.Document) (*types.Document, error)
+	PatchDocument(ctx context.Context, id string, patch types.DocumentPatch) (*types.Document, error)
+	DeleteDocument(ctx context.Context, id string) error
+	SearchDocuments(ctx context.Context, query string, limit int, filter *types.VectorFilter) ([]*types.Document, error)
+	ScrapeDocument(ctx context.Context, url, category string, tags []string) (string, error)
+	CrawlSite(ctx context.Context, seedURL, category string, tags []string, opts types.CrawlOptions) (string, error)
+	IngestSitemap(ctx context.Context, sitemapURL, category string, tags []string, opts types.SitemapOptions) (string, error)
+	GetScrapeJob(ctx context.Context, id string) (*types.ScrapeJobRecord, error)
+	ListScrapeJobs(ctx context.Context, opts types.ScrapeJobListOptions) (*types.ScrapeJobList, error)
+	ReplayDeadLetter(ctx context.Context, id string) error
+	GenerateTutorialFromScrapedData(ctx context.Context, url, topic string) (string, error)
+	ScrapeAndGenerateTutorial(ctx context.Context, url, topic string) (string, error)
+	GetConversationInsights(ctx context.Context, sessionID string) (map[string]interface{}, error)
 }
 
 // Handler handles HTTP requests for the application.
 type Handler struct {
 	service ServiceInterface
+	limits  generationLimits
 }
 
 // NewHandler creates a new Handler with the given service.
 func NewHandler(svc ServiceInterface) *Handler {
-	return &Handler{service: svc}
+	return &Handler{service: svc, limits: loadGenerationLimits()}
 }
 
 // chatRequest defines the structure for an incoming chat request.
+// Options overrides the configured generation defaults within the admin-defined bounds;
+// Filter restricts which documents may be used to answer, and ScoreThreshold
+// overrides the minimum similarity a document needs to be used.
 type chatRequest struct {
-	Message string `json:"message"`
+	Message        string                  `json:"message"`
+	Options        types.GenerationOptions `json:"options"`
+	Filter         *types.VectorFilter     `json:"filter"`
+	ScoreThreshold *float32                `json:"score_threshold"`
+}
+
+// chatStreamRequest defines the structure for a streaming chat request.
+// SessionID is optional; when set the conversation history is used.
+type chatStreamRequest struct {
+	SessionID      string                  `json:"session_id"`
+	Message        string                  `json:"message"`
+	Options        types.GenerationOptions `json:"options"`
+	Filter         *types.VectorFilter     `json:"filter"`
+	ScoreThreshold *float32                `json:"score_threshold"`
 }
 
 // chatResponse defines the structure for a chat response.
+// The answer cites Sources[i] as [i+1].
 type chatResponse struct {
-	Response string `json:"response"`
+	Response string         `json:"response"`
+	Sources  []types.Source `json:"sources"`
+}
+
+// newChatResponse converts a service result into the API response shape.
+func newChatResponse(result *ChatResult) chatResponse {
+	sources := result.Sources
+	if sources == nil {
+		sources = []types.Source{}
+	}
+	return chatResponse{Response: result.Response, Sources: sources}
 }
 
 // documentRequest defines the structure for adding a document.
@@ -63,6 +109,79 @@ type scrapeRequest struct {
 	Tags     []string `json:"tags"`
 }
 
+// crawlRequest defines the structure for a site crawl request. Unset limits use the defaults.
+type crawlRequest struct {
+	URL        string   `json:"url"`
+	Category   string   `json:"category"`
+	Tags       []string `json:"tags"`
+	MaxDepth   *int     `json:"max_depth"`
+	MaxPages   *int     `json:"max_pages"`
+	Include    []string `json:"include"`
+	Exclude    []string `json:"exclude"`
+	SameDomain *bool    `json:"same_domain"`
+}
+
+// Crawl limits. A crawl stays on the seed URL's domain unless same_domain is false.
+const (
+	defaultCrawlMaxDepth = 2
+	maxCrawlDepth        = 10
+	defaultCrawlMaxPages = 100
+	maxCrawlPages        = 5000
+)
+
+// options returns the crawl options of the request with the defaults applied.
+func (req *crawlRequest) options() types.CrawlOptions {
+	opts := types.CrawlOptions{
+		MaxDepth:   defaultCrawlMaxDepth,
+		MaxPages:   defaultCrawlMaxPages,
+		Include:    req.Include,
+		Exclude:    req.Exclude,
+		SameDomain: true,
+	}
+	if req.MaxDepth != nil {
+		opts.MaxDepth = *req.MaxDepth
+	}
+	if req.MaxPages != nil {
+		opts.MaxPages = *req.MaxPages
+	}
+	if req.SameDomain != nil {
+		opts.SameDomain = *req.SameDomain
+	}
+	return opts
+}
+
+// sitemapRequest defines the structure for a sitemap ingestion request.
+// modified_since takes RFC 3339 or YYYY-MM-DD.
+type sitemapRequest struct {
+	URL           string   `json:"url"`
+	Category      string   `json:"category"`
+	Tags          []string `json:"tags"`
+	Include       []string `json:"include"`
+	Exclude       []string `json:"exclude"`
+	ModifiedSince string   `json:"modified_since"`
+	MaxPages      *int     `json:"max_pages"`
+}
+
+// defaultSitemapMaxPages is the page limit of a sitemap job that sets none.
+const defaultSitemapMaxPages = 500
+
+// options returns the sitemap options of the request with the defaults
+// applied. The request must have passed validation.
+func (req *sitemapRequest) options() types.SitemapOptions {
+	opts := types.SitemapOptions{
+		Include:  req.Include,
+		Exclude:  req.Exclude,
+		MaxPages: defaultSitemapMaxPages,
+	}
+	if since, _ := parseDate("modified_since", req.ModifiedSince); !since.IsZero() {
+		opts.ModifiedSince = &since
+	}
+	if req.MaxPages != nil {
+		opts.MaxPages = *req.MaxPages
+	}
+	return opts
+}
+
 // tutorialRequest defines the structure for generating a tutorial.
 type tutorialRequest struct {
 	URL   string `json:"url"`
@@ -77,8 +196,11 @@ type scrapeTutorialRequest struct {
 
 // chatWithHistoryRequest defines the structure for chat with history.
 type chatWithHistoryRequest struct {
-	SessionID string `json:"session_id"`
-	Message   string `json:"message"`
+	SessionID      string                  `json:"session_id"`
+	Message        string                  `json:"message"`
+	Options        types.GenerationOptions `json:"options"`
+	Filter         *types.VectorFilter     `json:"filter"`
+	ScoreThreshold *float32                `json:"score_threshold"`
 }
 
 // ErrorResponse represents a standardized error response
@@ -94,6 +216,7 @@ const (
 	ErrValidation        = "VALIDATION_ERROR"
 	ErrResourceNotFound  = "RESOURCE_NOT_FOUND"
 	ErrUnauthorized      = "UNAUTHORIZED"
+	ErrConflict          = "CONFLICT"
 )
 
 // sendError sends a standardized error response
@@ -121,6 +244,21 @@ func validateRequest(r *http.Request, v interface{}) error {
 		if len(req.Message) > 2000 {
 			return fmt.Errorf("message too long (max 2000 characters)")
 		}
+		if err := validateScoreThreshold(req.ScoreThreshold); err != nil {
+			return err
+		}
+		return validateFilter(req.Filter)
+	case *chatStreamRequest:
+		if strings.TrimSpace(req.Message) == "" {
+			return fmt.Errorf("message cannot be empty")
+		}
+		if len(req.Message) > 2000 {
+			return fmt.Errorf("message too long (max 2000 characters)")
+		}
+		if err := validateScoreThreshold(req.ScoreThreshold); err != nil {
+			return err
+		}
+		return validateFilter(req.Filter)
 	case *documentRequest:
 		if strings.TrimSpace(req.Title) == "" {
 			return fmt.Errorf("title cannot be empty")
@@ -128,6 +266,16 @@ func validateRequest(r *http.Request, v interface{}) error {
 		if strings.TrimSpace(req.Content) == "" {
 			return fmt.Errorf("content cannot be empty")
 		}
+	case *types.DocumentPatch:
+		if req.Title == nil && req.Content == nil && req.Category == nil && req.Tags == nil && req.Author == nil && req.Metadata == nil {
+			return fmt.Errorf("no fields to update")
+		}
+		if req.Title != nil && strings.TrimSpace(*req.Title) == "" {
+			return fmt.Errorf("title cannot be empty")
+		}
+		if req.Content != nil && strings.TrimSpace(*req.Content) == "" {
+			return fmt.Errorf("content cannot be empty")
+		}
 	case *scrapeRequest:
 		if strings.TrimSpace(req.URL) == "" {
 			return fmt.Errorf("URL cannot be empty")
@@ -135,11 +283,117 @@ func validateRequest(r *http.Request, v interface{}) error {
 		if _, err := url.Parse(req.URL); err != nil {
 			return fmt.Errorf("invalid URL format")
 		}
+	case *crawlRequest:
+		seed, err := url.Parse(strings.TrimSpace(req.URL))
+		if err != nil || (seed.Scheme != "http" && seed.Scheme != "https") || seed.Host == "" {
+			return fmt.Errorf("url must be an absolute http or https URL")
+		}
+		if req.MaxDepth != nil && (*req.MaxDepth < 0 || *req.MaxDepth > maxCrawlDepth) {
+			return fmt.Errorf("max_depth must be between 0 and %d", maxCrawlDepth)
+		}
+		if req.MaxPages != nil && (*req.MaxPages < 1 || *req.MaxPages > maxCrawlPages) {
+			return fmt.Errorf("max_pages must be between 1 and %d", maxCrawlPages)
+		}
+		for _, pattern := range append(append([]string{}, req.Include...), req.Exclude...) {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid URL pattern %q", pattern)
+			}
+		}
+	case *sitemapRequest:
+		sitemap, err := url.Parse(strings.TrimSpace(req.URL))
+		if err != nil || (sitemap.Scheme != "http" && sitemap.Scheme != "https") || sitemap.Host == "" {
+			return fmt.Errorf("url must be an absolute http or https URL")
+		}
+		if req.MaxPages != nil && (*req.MaxPages < 1 || *req.MaxPages > maxCrawlPages) {
+			return fmt.Errorf("max_pages must be between 1 and %d", maxCrawlPages)
+		}
+		if _, err := parseDate("modified_since", req.ModifiedSince); err != nil {
+			return err
+		}
+		for _, pattern := range append(append([]string{}, req.Include...), req.Exclude...) {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid path pattern %q", pattern)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateScoreThreshold checks that a requested similarity threshold is a valid cosine score.
+func validateScoreThreshold(threshold *float32) error {
+	if threshold != nil && (*threshold < 0 || *threshold > 1) {
+		return fmt.Errorf("score_threshold must be between 0 and 1")
 	}
+	return nil
+}
 
+// filterableFields are the vector payload fields a search filter may refer to.
+var filterableFields = map[string]bool{
+	"category":    true,
+	"tags":        true,
+	"source":      true,
+	"author":      true,
+	"document_id": true,
+}
+
+// validateFilter checks that a filter only refers to known payload fields and
+// that every condition has a value to match.
+func validateFilter(filter *types.VectorFilter) error {
+	if filter == nil {
+		return nil
+	}
+	for _, conditions := range [][]types.FieldCondition{filter.Must, filter.Should, filter.MustNot} {
+		for _, cond := range conditions {
+			if !filterableFields[cond.Key] {
+				return fmt.Errorf("cannot filter on field %q", cond.Key)
+			}
+			if len(cond.Values) == 0 {
+				return fmt.Errorf("filter on %q needs at least one value", cond.Key)
+			}
+		}
+	}
 	return nil
 }
 
+// searchFilterParams maps search query parameters to the payload fields they filter on.
+var searchFilterParams = []struct{ param, key string }{
+	{"category", "category"},
+	{"tag", "tags"},
+	{"source", "source"},
+}
+
+// searchFilterFromQuery builds a filter from the category, tag and source query
+// parameters, which restrict results to the given values, and their exclude_
+// counterparts, which remove them. Parameters may be repeated. It returns nil
+// when none are set.
+func searchFilterFromQuery(query url.Values) *types.VectorFilter {
+	filter := &types.VectorFilter{}
+	for _, p := range searchFilterParams {
+		if values := nonEmpty(query[p.param]); len(values) > 0 {
+			filter.Must = append(filter.Must, types.FieldCondition{Key: p.key, Values: values})
+		}
+		if values := nonEmpty(query["exclude_"+p.param]); len(values) > 0 {
+			filter.MustNot = append(filter.MustNot, types.FieldCondition{Key: p.key, Values: values})
+		}
+	}
+	if filter.IsEmpty() {
+		return nil
+	}
+	return filter
+}
+
+// nonEmpty returns the values that are not blank.
+func nonEmpty(values []string) []string {
+	var result []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
 // HandleChat handles requests to the chat endpoint with improved error handling
 func (h *Handler) HandleChat(w http.ResponseWriter, r *http.Request) {
 	var req chatRequest
@@ -147,8 +401,12 @@ func (h *Handler) HandleChat(w http.ResponseWriter, r *http.Request) {
 		sendError(w, http.StatusBadRequest, ErrValidation, err.Error())
 		return
 	}
+	if err := h.limits.validate(req.Options); err != nil {
+		sendError(w, http.StatusBadRequest, ErrValidation, err.Error())
+		return
+	}
 
-	response, err := h.service.Chat(req.Message)
+	result, err := h.service.Chat(r.Context(), req.Message, ChatOptions{Generation: req.Options, Filter: req.Filter, ScoreThreshold: req.ScoreThreshold})
 	if err != nil {
 		sendError(w, http.StatusInternalServerError, ErrInternalServer, "Failed to process chat request")
 		log.Printf("Chat error: %v", err)
@@ -156,7 +414,90 @@ func (h *Handler) HandleChat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(chatResponse{Response: response})
+	json.NewEncoder(w).Encode(newChatResponse(result))
+}
+
+// HandleChatStream streams a chat answer as Server-Sent Events.
+// It emits "chunk" events while the answer is generated, then a "sources"
+// event and a final "done" event. A client disconnect cancels generation.
+func (h *Handler) HandleChatStream(w http.ResponseWriter, r *http.Request) {
+	var req chatStreamRequest
+	if err := validateRequest(r, &req); err != nil {
+		sendError(w, http.StatusBadRequest, ErrValidation, err.Error())
+		return
+	}
+	if err := h.limits.validate(req.Options); err != nil {
+		sendError(w, http.StatusBadRequest, ErrValidation, err.Error())
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		sendError(w, http.StatusInternalServerError, ErrInternalServer, "Streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable NGINX response buffering
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx := r.Context()
+	start := time.Now()
+	chunks := 0
+
+	onDelta := func(delta string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		chunks++
+		return writeSSE(w, flusher, "chunk", map[string]string{"delta": delta})
+	}
+
+	opts := ChatOptions{Generation: req.Options, Filter: req.Filter, ScoreThreshold: req.ScoreThreshold}
+
+	var result *ChatResult
+	var err error
+	if req.SessionID != "" {
+		result, err = h.service.ChatWithHistoryStream(ctx, req.SessionID, req.Message, opts, onDelta)
+	} else {
+		result, err = h.service.ChatStream(ctx, req.Message, opts, onDelta)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			log.Printf("Chat stream cancelled by client: %v", ctx.Err())
+			return
+		}
+		log.Printf("Chat stream error: %v", err)
+		writeSSE(w, flusher, "error", ErrorResponse{
+			Error: "Failed to process chat request",
+			Code:  ErrInternalServer,
+		})
+		return
+	}
+
+	writeSSE(w, flusher, "sources", newChatResponse(result).Sources)
+	writeSSE(w, flusher, "done", map[string]interface{}{
+		"response":    result.Response,
+		"session_id":  req.SessionID,
+		"chunks":      chunks,
+		"duration_ms": time.Since(start).Milliseconds(),
+	})
+}
+
+// writeSSE writes a single Server-Sent Event with a JSON payload and flushes it.
+func writeSSE(w http.ResponseWriter, flusher http.Flusher, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", event, err)
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
 }
 
 // HandleAddDocument handles requests to add a new document with improved validation
@@ -176,7 +517,7 @@ func (h *Handler) HandleAddDocument(w http.ResponseWriter, r *http.Request) {
 		Metadata: req.Metadata,
 	}
 
-	if err := h.service.AddDocument(doc); err != nil {
+	if err := h.service.AddDocument(r.Context(), doc); err != nil {
 		sendError(w, http.StatusInternalServerError, ErrInternalServer, "Failed to add document")
 		log.Printf("Add document error: %v", err)
 		return
@@ -187,6 +528,173 @@ func (h *Handler) HandleAddDocument(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "Document added successfully"})
 }
 
+// Page sizes for listing documents and scrape jobs.
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// HandleListDocuments handles requests to list documents a page at a time.
+// The category, tag and author parameters filter the list, and created_after
+// and created_before bound the creation date (RFC 3339 or YYYY-MM-DD).
+func (h *Handler) HandleListDocuments(w http.ResponseWriter, r *http.Request) {
+	opts, err := documentListOptionsFromQuery(r.URL.Query())
+	if err != nil {
+		sendError(w, http.StatusBadRequest, ErrValidation, err.Error())
+		return
+	}
+
+	list, err := h.service.ListDocuments(r.Context(), opts)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, ErrInternalServer, "Failed to list documents")
+		log.Printf("List documents error: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// documentListOptionsFromQuery parses the filter and paging parameters of a document listing.
+func documentListOptionsFromQuery(query url.Values) (types.DocumentListOptions, error) {
+	opts := types.DocumentListOptions{
+		Category: strings.TrimSpace(query.Get("category")),
+		Tag:      strings.TrimSpace(query.Get("tag")),
+		Author:   strings.TrimSpace(query.Get("author")),
+	}
+
+	var err error
+	if opts.Limit, opts.Offset, err = parsePaging(query); err != nil {
+		return opts, err
+	}
+	if opts.CreatedAfter, err = parseDateParam(query, "created_after"); err != nil {
+		return opts, err
+	}
+	if opts.CreatedBefore, err = parseDateParam(query, "created_before"); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+// parsePaging parses the limit and offset parameters of a listing.
+func parsePaging(query url.Values) (limit, offset int, err error) {
+	limit = defaultListLimit
+	if v := query.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			return 0, 0, fmt.Errorf("invalid limit parameter (must be between 1 and %d)", maxListLimit)
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset parameter (must be 0 or more)")
+		}
+	}
+	return limit, offset, nil
+}
+
+// parseDateParam parses an RFC 3339 timestamp or a YYYY-MM-DD date. A missing
+// parameter gives the zero time.
+func parseDateParam(query url.Values, name string) (time.Time, error) {
+	return parseDate(name, query.Get(name))
+}
+
+// parseDate parses the value v of the date field name, which may be empty.
+func parseDate(name, v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid %s parameter (use RFC 3339 or YYYY-MM-DD)", name)
+}
+
+// HandleGetDocument handles requests to fetch a document by ID.
+func (h *Handler) HandleGetDocument(w http.ResponseWriter, r *http.Request) {
+	doc, err := h.service.GetDocument(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		sendDocumentError(w, err, "Failed to get document")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(doc)
+}
+
+// HandleUpdateDocument handles requests to replace a document. The document is
+// re-embedded and its vectors re-indexed.
+func (h *Handler) HandleUpdateDocument(w http.ResponseWriter, r *http.Request) {
+	var req documentRequest
+	if err := validateRequest(r, &req); err != nil {
+		sendError(w, http.StatusBadRequest, ErrValidation, err.Error())
+		return
+	}
+
+	doc := &types.Document{
+		Title:    req.Title,
+		Content:  req.Content,
+		Category: req.Category,
+		Tags:     req.Tags,
+		Author:   req.Author,
+		Metadata: req.Metadata,
+	}
+
+	updated, err := h.service.UpdateDocument(r.Context(), chi.URLParam(r, "id"), doc)
+	if err != nil {
+		sendDocumentError(w, err, "Failed to update document")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// HandlePatchDocument handles requests to change some fields of a document.
+// The document is re-embedded and its vectors re-indexed.
+func (h *Handler) HandlePatchDocument(w http.ResponseWriter, r *http.Request) {
+	var patch types.DocumentPatch
+	if err := validateRequest(r, &patch); err != nil {
+		sendError(w, http.StatusBadRequest, ErrValidation, err.Error())
+		return
+	}
+
+	updated, err := h.service.PatchDocument(r.Context(), chi.URLParam(r, "id"), patch)
+	if err != nil {
+		sendDocumentError(w, err, "Failed to update document")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// HandleDeleteDocument handles requests to delete a document together with
+// its vectors and cache entries.
+func (h *Handler) HandleDeleteDocument(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteDocument(r.Context(), chi.URLParam(r, "id")); err != nil {
+		sendDocumentError(w, err, "Failed to delete document")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendDocumentError responds 404 for a missing document and 500 otherwise.
+func sendDocumentError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, ErrDocumentNotFound) {
+		sendError(w, http.StatusNotFound, ErrResourceNotFound, "Document not found")
+		return
+	}
+	sendError(w, http.StatusInternalServerError, ErrInternalServer, message)
+	log.Printf("%s: %v", message, err)
+}
+
 // HandleScrapeDocument handles requests to scrape a document from a URL.
 func (h *Handler) HandleScrapeDocument(w http.ResponseWriter, r *http.Request) {
 	var req scrapeRequest
@@ -195,16 +703,159 @@ func (h *Handler) HandleScrapeDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.ScrapeDocument(req.URL, req.Category, req.Tags); err != nil {
+	jobID, err := h.service.ScrapeDocument(r.Context(), req.URL, req.Category, req.Tags)
+	if err != nil {
 		http.Error(w, "Failed to scrape document", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "Scraping job queued successfully"})
+	json.NewEncoder(w).Encode(map[string]string{"status": "Scraping job queued successfully", "job_id": jobID})
 }
 
-// HandleSearchDocuments handles requests to search documents with improved validation
+// HandleCrawlSite handles requests to crawl a site from a seed URL.
+func (h *Handler) HandleCrawlSite(w http.ResponseWriter, r *http.Request) {
+	var req crawlRequest
+	if err := validateRequest(r, &req); err != nil {
+		sendError(w, http.StatusBadRequest, ErrValidation, err.Error())
+		return
+	}
+
+	jobID, err := h.service.CrawlSite(r.Context(), strings.TrimSpace(req.URL), req.Category, req.Tags, req.options())
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, ErrInternalServer, "Failed to queue crawl")
+		log.Printf("Crawl site error: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "Crawl job queued successfully", "job_id": jobID})
+}
+
+// HandleIngestSitemap handles requests to scrape the pages listed in a sitemap.
+func (h *Handler) HandleIngestSitemap(w http.ResponseWriter, r *http.Request) {
+	var req sitemapRequest
+	if err := validateRequest(r, &req); err != nil {
+		sendError(w, http.StatusBadRequest, ErrValidation, err.Error())
+		return
+	}
+
+	jobID, err := h.service.IngestSitemap(r.Context(), strings.TrimSpace(req.URL), req.Category, req.Tags, req.options())
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, ErrInternalServer, "Failed to queue sitemap ingestion")
+		log.Printf("Ingest sitemap error: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "Sitemap job queued successfully", "job_id": jobID})
+}
+
+// HandleGetScrapeJob handles requests for the state of a scrape job.
+func (h *Handler) HandleGetScrapeJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.service.GetScrapeJob(r.Context(), chi.URLParam(r, "job_id"))
+	if err != nil {
+		if errors.Is(err, ErrJobNotFound) {
+			sendError(w, http.StatusNotFound, ErrResourceNotFound, "Scrape job not found")
+			return
+		}
+		sendError(w, http.StatusInternalServerError, ErrInternalServer, "Failed to get scrape job")
+		log.Printf("Get scrape job error: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// jobStatuses are the values accepted by the status filter of the job listing.
+var jobStatuses = map[types.JobStatus]bool{
+	types.JobQueued:     true,
+	types.JobRunning:    true,
+	types.JobRetrying:   true,
+	types.JobSucceeded:  true,
+	types.JobFailed:     true,
+	types.JobSkipped:    true,
+	types.JobDisallowed: true,
+}
+
+// HandleListScrapeJobs handles requests to list scrape jobs a page at a time,
+// newest first, optionally only those with the given status or the pages of
+// the crawl job given as parent_id.
+func (h *Handler) HandleListScrapeJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var opts types.ScrapeJobListOptions
+	var err error
+	if opts.Limit, opts.Offset, err = parsePaging(query); err != nil {
+		sendError(w, http.StatusBadRequest, ErrValidation, err.Error())
+		return
+	}
+	if status := types.JobStatus(query.Get("status")); status != "" {
+		if !jobStatuses[status] {
+			sendError(w, http.StatusBadRequest, ErrValidation, fmt.Sprintf("unknown job status %q", status))
+			return
+		}
+		opts.Status = status
+	}
+	opts.ParentID = query.Get("parent_id")
+
+	list, err := h.service.ListScrapeJobs(r.Context(), opts)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, ErrInternalServer, "Failed to list scrape jobs")
+		log.Printf("List scrape jobs error: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// HandleListDeadLetters handles admin requests to list the scrape jobs that
+// failed their last attempt and were dead-lettered, a page at a time.
+func (h *Handler) HandleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	opts := types.ScrapeJobListOptions{DeadLettered: true}
+	var err error
+	if opts.Limit, opts.Offset, err = parsePaging(r.URL.Query()); err != nil {
+		sendError(w, http.StatusBadRequest, ErrValidation, err.Error())
+		return
+	}
+
+	list, err := h.service.ListScrapeJobs(r.Context(), opts)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, ErrInternalServer, "Failed to list dead-lettered jobs")
+		log.Printf("List dead-lettered jobs error: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// HandleReplayDeadLetter handles admin requests to queue a dead-lettered scrape job again.
+func (h *Handler) HandleReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "job_id")
+	if err := h.service.ReplayDeadLetter(r.Context(), jobID); err != nil {
+		switch {
+		case errors.Is(err, ErrJobNotFound):
+			sendError(w, http.StatusNotFound, ErrResourceNotFound, "Scrape job not found")
+		case errors.Is(err, ErrJobNotDeadLettered):
+			sendError(w, http.StatusConflict, ErrConflict, "Scrape job is not dead-lettered")
+		default:
+			sendError(w, http.StatusInternalServerError, ErrInternalServer, "Failed to replay scrape job")
+			log.Printf("Replay dead-lettered job error: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "Scraping job requeued successfully", "job_id": jobID})
+}
+
+// HandleSearchDocuments handles requests to search documents with improved validation.
+// The category, tag and source parameters and their exclude_ forms filter the results.
 func (h *Handler) HandleSearchDocuments(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 	if strings.TrimSpace(query) == "" {
@@ -223,7 +874,10 @@ func (h *Handler) HandleSearchDocuments(w http.ResponseWriter, r *http.Request)
 		limit = l
 	}
 
-	documents, err := h.service.SearchDocuments(query, limit)
+	// Filtering by payload fields switches to a semantic search over the document chunks
+	filter := searchFilterFromQuery(r.URL.Query())
+
+	documents, err := h.service.SearchDocuments(r.Context(), query, limit, filter)
 	if err != nil {
 		sendError(w, http.StatusInternalServerError, ErrInternalServer, "Failed to search documents")
 		log.Printf("Search documents error: %v", err)
@@ -247,7 +901,7 @@ func (h *Handler) HandleGenerateTutorial(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	tutorial, err := h.service.GenerateTutorialFromScrapedData(req.URL, req.Topic)
+	tutorial, err := h.service.GenerateTutorialFromScrapedData(r.Context(), req.URL, req.Topic)
 	if err != nil {
 		http.Error(w, "Failed to generate tutorial", http.StatusInternalServerError)
 		return
@@ -273,7 +927,7 @@ func (h *Handler) HandleScrapeAndGenerateTutorial(w http.ResponseWriter, r *http
 		return
 	}
 
-	tutorial, err := h.service.ScrapeAndGenerateTutorial(req.URL, req.Topic)
+	tutorial, err := h.service.ScrapeAndGenerateTutorial(r.Context(), req.URL, req.Topic)
 	if err != nil {
 		http.Error(w, "Failed to scrape and generate tutorial", http.StatusInternalServerError)
 		return
@@ -300,14 +954,29 @@ func (h *Handler) HandleChatWithHistory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	response, err := h.service.ChatWithHistory(req.SessionID, req.Message)
+	if err := h.limits.validate(req.Options); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateFilter(req.Filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateScoreThreshold(req.ScoreThreshold); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.ChatWithHistory(r.Context(), req.SessionID, req.Message, ChatOptions{Generation: req.Options, Filter: req.Filter, ScoreThreshold: req.ScoreThreshold})
 	if err != nil {
 		http.Error(w, "Failed to get chat response", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(chatResponse{Response: response})
+	json.NewEncoder(w).Encode(newChatResponse(result))
 }
 
 // HandleGetChatHistory handles requests to get chat history.
@@ -326,7 +995,7 @@ func (h *Handler) HandleGetChatHistory(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	history, err := h.service.GetChatHistory(sessionID, limit)
+	history, err := h.service.GetChatHistory(r.Context(), sessionID, limit)
 	if err != nil {
 		http.Error(w, "Failed to get chat history", http.StatusInternalServerError)
 		return
@@ -348,7 +1017,7 @@ func (h *Handler) HandleGetConversationInsights(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	insights, err := h.service.GetConversationInsights(sessionID)
+	insights, err := h.service.GetConversationInsights(r.Context(), sessionID)
 	if err != nil {
 		http.Error(w, "Failed to get conversation insights", http.StatusInternalServerError)
 		return
@@ -2,14 +2,18 @@ package app
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"tech-docs-ai/internal/types"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -20,54 +24,129 @@ type MockServiceForTesting struct {
 	mock.Mock
 }
 
-func (m *MockServiceForTesting) Chat(message string) (string, error) {
-	args := m.Called(message)
-	return args.String(0), args.Error(1)
+func (m *MockServiceForTesting) Chat(ctx context.Context, message string, opts ChatOptions) (*ChatResult, error) {
+	args := m.Called(ctx, message, opts)
+	result, _ := args.Get(0).(*ChatResult)
+	return result, args.Error(1)
 }
 
-func (m *MockServiceForTesting) ChatWithHistory(sessionID, message string) (string, error) {
-	args := m.Called(sessionID, message)
-	return args.String(0), args.Error(1)
+func (m *MockServiceForTesting) ChatStream(ctx context.Context, message string, opts ChatOptions, onDelta func(delta string) error) (*ChatResult, error) {
+	args := m.Called(ctx, message, opts, onDelta)
+	result, _ := args.Get(0).(*ChatResult)
+	return result, args.Error(1)
+}
+
+func (m *MockServiceForTesting) ChatWithHistory(ctx context.Context, sessionID, message string, opts ChatOptions) (*ChatResult, error) {
+	args := m.Called(ctx, sessionID, message, opts)
+	result, _ := args.Get(0).(*ChatResult)
+	return result, args.Error(1)
 }
 
-func (m *MockServiceForTesting) GetChatHistory(sessionID string, limit int) ([]*types.ChatMessage, error) {
-	args := m.Called(sessionID, limit)
+func (m *MockServiceForTesting) ChatWithHistoryStream(ctx context.Context, sessionID, message string, opts ChatOptions, onDelta func(delta string) error) (*ChatResult, error) {
+	args := m.Called(ctx, sessionID, message, opts, onDelta)
+	result, _ := args.Get(0).(*ChatResult)
+	return result, args.Error(1)
+}
+
+func (m *MockServiceForTesting) GetChatHistory(ctx context.Context, sessionID string, limit int) ([]*types.ChatMessage, error) {
+	args := m.Called(ctx, sessionID, limit)
 	return args.Get(0).([]*types.ChatMessage), args.Error(1)
 }
 
-func (m *MockServiceForTesting) AddDocument(doc *types.Document) error {
-	args := m.Called(doc)
+func (m *MockServiceForTesting) AddDocument(ctx context.Context, doc *types.Document) error {
+	args := m.Called(ctx, doc)
+	return args.Error(0)
+}
+
+func (m *MockServiceForTesting) GetDocument(ctx context.Context, id string) (*types.Document, error) {
+	args := m.Called(ctx, id)
+	doc, _ := args.Get(0).(*types.Document)
+	return doc, args.Error(1)
+}
+
+func (m *MockServiceForTesting) ListDocuments(ctx context.Context, opts types.DocumentListOptions) (*types.DocumentList, error) {
+	args := m.Called(ctx, opts)
+	list, _ := args.Get(0).(*types.DocumentList)
+	return list, args.Error(1)
+}
+
+func (m *MockServiceForTesting) UpdateDocument(ctx context.Context, id string, doc *types.Document) (*types.Document, error) {
+	args := m.Called(ctx, id, doc)
+	updated, _ := args.Get(0).(*types.Document)
+	return updated, args.Error(1)
+}
+
+func (m *MockServiceForTesting) PatchDocument(ctx context.Context, id string, patch types.DocumentPatch) (*types.Document, error) {
+	args := m.Called(ctx, id, patch)
+	updated, _ := args.Get(0).(*types.Document)
+	return updated, args.Error(1)
+}
+
+func (m *MockServiceForTesting) DeleteDocument(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
-func (m *MockServiceForTesting) SearchDocuments(query string, limit int) ([]*types.Document, error) {
-	args := m.Called(query, limit)
+func (m *MockServiceForTesting) SearchDocuments(ctx context.Context, query string, limit int, filter *types.VectorFilter) ([]*types.Document, error) {
+	args := m.Called(ctx, query, limit, filter)
 	return args.Get(0).([]*types.Document), args.Error(1)
 }
 
-func (m *MockServiceForTesting) ScrapeDocument(url, category string, tags []string) error {
-	args := m.Called(url, category, tags)
+func (m *MockServiceForTesting) ScrapeDocument(ctx context.Context, url, category string, tags []string) (string, error) {
+	args := m.Called(ctx, url, category, tags)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockServiceForTesting) CrawlSite(ctx context.Context, seedURL, category string, tags []string, opts types.CrawlOptions) (string, error) {
+	args := m.Called(ctx, seedURL, category, tags, opts)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockServiceForTesting) IngestSitemap(ctx context.Context, sitemapURL, category string, tags []string, opts types.SitemapOptions) (string, error) {
+	args := m.Called(ctx, sitemapURL, category, tags, opts)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockServiceForTesting) GetScrapeJob(ctx context.Context, jobID string) (*types.ScrapeJobRecord, error) {
+	args := m.Called(ctx, jobID)
+	job, _ := args.Get(0).(*types.ScrapeJobRecord)
+	return job, args.Error(1)
+}
+
+func (m *MockServiceForTesting) ListScrapeJobs(ctx context.Context, opts types.ScrapeJobListOptions) (*types.ScrapeJobList, error) {
+	args := m.Called(ctx, opts)
+	list, _ := args.Get(0).(*types.ScrapeJobList)
+	return list, args.Error(1)
+}
+
+func (m *MockServiceForTesting) ReplayDeadLetter(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
-func (m *MockServiceForTesting) GenerateTutorialFromScrapedData(url, topic string) (string, error) {
-	args := m.Called(url, topic)
+func (m *MockServiceForTesting) GenerateTutorialFromScrapedData(ctx context.Context, url, topic string) (string, error) {
+	args := m.Called(ctx, url, topic)
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockServiceForTesting) ScrapeAndGenerateTutorial(url, topic string) (string, error) {
-	args := m.Called(url, topic)
+func (m *MockServiceForTesting) ScrapeAndGenerateTutorial(ctx context.Context, url, topic string) (string, error) {
+	args := m.Called(ctx, url, topic)
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockServiceForTesting) GetConversationInsights(sessionID string) (map[string]interface{}, error) {
-	args := m.Called(sessionID)
+func (m *MockServiceForTesting) GetConversationInsights(ctx context.Context, sessionID string) (map[string]interface{}, error) {
+	args := m.Called(ctx, sessionID)
 	return args.Get(0).(map[string]interface{}), args.Error(1)
 }
 
 func TestHandler_HandleChat_Success(t *testing.T) {
 	mockService := new(MockServiceForTesting)
-	mockService.On("Chat", "Hello").Return("Hi there!", nil)
+	mockService.On("Chat", mock.Anything, "Hello", ChatOptions{}).Return(&ChatResult{
+		Response: "Hi there! [1]",
+		Sources: []types.Source{
+			{DocumentID: "doc_1", Title: "Greetings", URL: "https://example.com/greetings", Category: "Basics", Score: 0.91},
+		},
+	}, nil)
 
 	handler := NewHandler(mockService)
 
@@ -84,7 +163,31 @@ func TestHandler_HandleChat_Success(t *testing.T) {
 	var response chatResponse
 	err := json.NewDecoder(w.Body).Decode(&response)
 	require.NoError(t, err)
-	assert.Equal(t, "Hi there!", response.Response)
+	assert.Equal(t, "Hi there! [1]", response.Response)
+	require.Len(t, response.Sources, 1)
+	assert.Equal(t, "doc_1", response.Sources[0].DocumentID)
+	assert.Equal(t, "https://example.com/greetings", response.Sources[0].URL)
+	assert.Equal(t, "Basics", response.Sources[0].Category)
+	assert.InDelta(t, 0.91, response.Sources[0].Score, 0.0001)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleChat_NoSources(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	mockService.On("Chat", mock.Anything, "Hello", ChatOptions{}).Return(&ChatResult{Response: "Hi there!"}, nil)
+
+	handler := NewHandler(mockService)
+
+	body, _ := json.Marshal(chatRequest{Message: "Hello"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.HandleChat(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"sources":[]`)
 
 	mockService.AssertExpectations(t)
 }
@@ -132,9 +235,145 @@ func TestHandler_HandleChat_MessageTooLong(t *testing.T) {
 	assert.Contains(t, errorResp.Error, "message too long")
 }
 
+func TestHandler_HandleChat_WithOptions(t *testing.T) {
+	temperature := 0.2
+	seed := 42
+	opts := ChatOptions{Generation: types.GenerationOptions{Temperature: &temperature, Seed: &seed}}
+
+	mockService := new(MockServiceForTesting)
+	mockService.On("Chat", mock.Anything, "Hello", opts).Return(&ChatResult{Response: "Hi there!"}, nil)
+
+	handler := NewHandler(mockService)
+
+	body := []byte(`{"message":"Hello","options":{"temperature":0.2,"seed":42}}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.HandleChat(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleChat_OptionsOutOfBounds(t *testing.T) {
+	t.Setenv("LLM_MAX_NUM_PREDICT", "512")
+
+	mockService := new(MockServiceForTesting)
+	handler := NewHandler(mockService)
+
+	body := []byte(`{"message":"Hello","options":{"num_predict":1024}}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.HandleChat(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var errorResp ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&errorResp))
+	assert.Equal(t, ErrValidation, errorResp.Code)
+	assert.Contains(t, errorResp.Error, "num_predict must be between 1 and 512")
+
+	mockService.AssertNotCalled(t, "Chat", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandler_HandleChat_TooManyStopSequences(t *testing.T) {
+	t.Setenv("LLM_MAX_STOP", "1")
+
+	mockService := new(MockServiceForTesting)
+	handler := NewHandler(mockService)
+
+	body := []byte(`{"message":"Hello","options":{"stop":["\n\n","User:"]}}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.HandleChat(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var errorResp ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&errorResp))
+	assert.Contains(t, errorResp.Error, "at most 1 stop sequences are allowed")
+
+	mockService.AssertNotCalled(t, "Chat", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandler_HandleChat_WithFilter(t *testing.T) {
+	filter := &types.VectorFilter{Must: []types.FieldCondition{{Key: "category", Values: []string{"Go"}}}}
+
+	mockService := new(MockServiceForTesting)
+	mockService.On("Chat", mock.Anything, "Hello", ChatOptions{Filter: filter}).Return(&ChatResult{Response: "Hi there!"}, nil)
+
+	handler := NewHandler(mockService)
+
+	body, _ := json.Marshal(chatRequest{Message: "Hello", Filter: filter})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.HandleChat(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleChat_InvalidFilter(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	handler := NewHandler(mockService)
+
+	body := []byte(`{"message":"Hello","filter":{"must":[{"key":"content","values":["x"]}]}}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.HandleChat(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `cannot filter on field \"content\"`)
+	mockService.AssertNotCalled(t, "Chat", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandler_HandleChat_WithScoreThreshold(t *testing.T) {
+	threshold := float32(0.5)
+
+	mockService := new(MockServiceForTesting)
+	mockService.On("Chat", mock.Anything, "Hello", ChatOptions{ScoreThreshold: &threshold}).Return(&ChatResult{Response: "Hi there!"}, nil)
+
+	handler := NewHandler(mockService)
+
+	body := []byte(`{"message":"Hello","score_threshold":0.5}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.HandleChat(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleChat_ScoreThresholdOutOfRange(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	handler := NewHandler(mockService)
+
+	body := []byte(`{"message":"Hello","score_threshold":1.5}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.HandleChat(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "score_threshold must be between 0 and 1")
+	mockService.AssertNotCalled(t, "Chat", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestHandler_HandleChat_ServiceError(t *testing.T) {
 	mockService := new(MockServiceForTesting)
-	mockService.On("Chat", "Hello").Return("", fmt.Errorf("service error"))
+	mockService.On("Chat", mock.Anything, "Hello", ChatOptions{}).Return(nil, fmt.Errorf("service error"))
 
 	handler := NewHandler(mockService)
 
@@ -156,9 +395,113 @@ func TestHandler_HandleChat_ServiceError(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestHandler_HandleChatStream_Success(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	mockService.On("ChatStream", mock.Anything, "Hello", ChatOptions{}, mock.Anything).
+		Run(func(args mock.Arguments) {
+			onDelta := args.Get(3).(func(string) error)
+			onDelta("Hi ")
+			onDelta("there!")
+		}).
+		Return(&ChatResult{
+			Response: "Hi there!",
+			Sources:  []types.Source{{DocumentID: "doc_1", Title: "Greetings", Score: 0.9}},
+		}, nil)
+
+	handler := NewHandler(mockService)
+
+	body, _ := json.Marshal(chatStreamRequest{Message: "Hello"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat/stream", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.HandleChatStream(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+
+	stream := w.Body.String()
+	assert.Contains(t, stream, "event: chunk\ndata: {\"delta\":\"Hi \"}\n\n")
+	assert.Contains(t, stream, "event: chunk\ndata: {\"delta\":\"there!\"}\n\n")
+	assert.Contains(t, stream, "event: sources\ndata: [{\"document_id\":\"doc_1\"")
+	assert.Contains(t, stream, "event: done\ndata: {")
+	assert.Contains(t, stream, "\"response\":\"Hi there!\"")
+	assert.Less(t, strings.Index(stream, "event: chunk"), strings.Index(stream, "event: sources"))
+	assert.Less(t, strings.Index(stream, "event: sources"), strings.Index(stream, "event: done"))
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleChatStream_WithSession(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	mockService.On("ChatWithHistoryStream", mock.Anything, "session123", "Hello", ChatOptions{}, mock.Anything).
+		Return(&ChatResult{Response: "Hi again!"}, nil)
+
+	handler := NewHandler(mockService)
+
+	body, _ := json.Marshal(chatStreamRequest{SessionID: "session123", Message: "Hello"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat/stream", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.HandleChatStream(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "event: sources\ndata: []\n\n")
+	assert.Contains(t, w.Body.String(), "\"session_id\":\"session123\"")
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleChatStream_ClientDisconnect(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	mockService.On("ChatStream", mock.Anything, "Hello", ChatOptions{}, mock.Anything).
+		Return(nil, context.Canceled).
+		Run(func(args mock.Arguments) {
+			onDelta := args.Get(3).(func(string) error)
+			assert.ErrorIs(t, onDelta("ignored"), context.Canceled)
+		})
+
+	handler := NewHandler(mockService)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	body, _ := json.Marshal(chatStreamRequest{Message: "Hello"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat/stream", bytes.NewReader(body)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.HandleChatStream(w, req)
+
+	assert.NotContains(t, w.Body.String(), "event: chunk")
+	assert.NotContains(t, w.Body.String(), "event: done")
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleChatStream_EmptyMessage(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	handler := NewHandler(mockService)
+
+	body, _ := json.Marshal(chatStreamRequest{Message: ""})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat/stream", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.HandleChatStream(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var errorResp ErrorResponse
+	err := json.NewDecoder(w.Body).Decode(&errorResp)
+	require.NoError(t, err)
+	assert.Equal(t, ErrValidation, errorResp.Code)
+}
+
 func TestHandler_HandleAddDocument_Success(t *testing.T) {
 	mockService := new(MockServiceForTesting)
-	mockService.On("AddDocument", mock.AnythingOfType("*types.Document")).Return(nil)
+	mockService.On("AddDocument", mock.Anything, mock.AnythingOfType("*types.Document")).Return(nil)
 
 	handler := NewHandler(mockService)
 
@@ -210,12 +553,213 @@ func TestHandler_HandleAddDocument_EmptyTitle(t *testing.T) {
 	assert.Contains(t, errorResp.Error, "title cannot be empty")
 }
 
+func TestHandler_HandleAddDocument_ServiceError(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	mockService.On("AddDocument", mock.Anything, mock.AnythingOfType("*types.Document")).Return(fmt.Errorf("service error"))
+
+	handler := NewHandler(mockService)
+
+	reqBody := documentRequest{
+		Title:   "Test Document",
+		Content: "Test content",
+	}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/documents", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.HandleAddDocument(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	var errorResp ErrorResponse
+	err := json.NewDecoder(w.Body).Decode(&errorResp)
+	require.NoError(t, err)
+	assert.Equal(t, ErrInternalServer, errorResp.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+// withURLParam sets a chi route parameter on a request, as the router would.
+func withURLParam(r *http.Request, key, value string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add(key, value)
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestHandler_HandleGetDocument_Success(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	expected := &types.Document{ID: "doc_1", Title: "Goroutines", Content: "Lightweight threads."}
+	mockService.On("GetDocument", mock.Anything, "doc_1").Return(expected, nil)
+
+	handler := NewHandler(mockService)
+
+	req := withURLParam(httptest.NewRequest(http.MethodGet, "/api/v1/documents/doc_1", nil), "id", "doc_1")
+	w := httptest.NewRecorder()
+	handler.HandleGetDocument(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var doc types.Document
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&doc))
+	assert.Equal(t, "Goroutines", doc.Title)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleGetDocument_NotFound(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	mockService.On("GetDocument", mock.Anything, "missing").Return(nil, ErrDocumentNotFound)
+
+	handler := NewHandler(mockService)
+
+	req := withURLParam(httptest.NewRequest(http.MethodGet, "/api/v1/documents/missing", nil), "id", "missing")
+	w := httptest.NewRecorder()
+	handler.HandleGetDocument(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var errorResp ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&errorResp))
+	assert.Equal(t, ErrResourceNotFound, errorResp.Code)
+}
+
+func TestHandler_HandleListDocuments_Filters(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	expectedOpts := types.DocumentListOptions{
+		Category:      "Go",
+		Tag:           "concurrency",
+		Author:        "gopher",
+		CreatedAfter:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		CreatedBefore: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+		Limit:         5,
+		Offset:        10,
+	}
+	list := &types.DocumentList{Documents: []*types.Document{{ID: "doc_1"}}, Total: 11, Limit: 5, Offset: 10}
+	mockService.On("ListDocuments", mock.Anything, expectedOpts).Return(list, nil)
+
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/documents?category=Go&tag=concurrency&author=gopher&created_after=2024-01-01&created_before=2024-06-01T12:00:00Z&limit=5&offset=10", nil)
+	w := httptest.NewRecorder()
+	handler.HandleListDocuments(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response types.DocumentList
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, 11, response.Total)
+	assert.Len(t, response.Documents, 1)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleListDocuments_InvalidParams(t *testing.T) {
+	handler := NewHandler(new(MockServiceForTesting))
+
+	for _, query := range []string{"limit=0", "limit=101", "offset=-1", "created_after=yesterday"} {
+		t.Run(query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/documents?"+query, nil)
+			w := httptest.NewRecorder()
+			handler.HandleListDocuments(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestHandler_HandleUpdateDocument_Success(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	updated := &types.Document{ID: "doc_1", Title: "Channels", Content: "Pipes between goroutines."}
+	mockService.On("UpdateDocument", mock.Anything, "doc_1", mock.MatchedBy(func(doc *types.Document) bool {
+		return doc.Title == "Channels" && doc.Content == "Pipes between goroutines."
+	})).Return(updated, nil)
+
+	handler := NewHandler(mockService)
+
+	body, _ := json.Marshal(documentRequest{Title: "Channels", Content: "Pipes between goroutines."})
+	req := withURLParam(httptest.NewRequest(http.MethodPut, "/api/v1/documents/doc_1", bytes.NewReader(body)), "id", "doc_1")
+	w := httptest.NewRecorder()
+	handler.HandleUpdateDocument(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var doc types.Document
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&doc))
+	assert.Equal(t, "doc_1", doc.ID)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleUpdateDocument_NotFound(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	mockService.On("UpdateDocument", mock.Anything, "missing", mock.Anything).Return(nil, ErrDocumentNotFound)
+
+	handler := NewHandler(mockService)
+
+	body, _ := json.Marshal(documentRequest{Title: "Channels", Content: "Pipes."})
+	req := withURLParam(httptest.NewRequest(http.MethodPut, "/api/v1/documents/missing", bytes.NewReader(body)), "id", "missing")
+	w := httptest.NewRecorder()
+	handler.HandleUpdateDocument(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandler_HandlePatchDocument_Success(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	tags := []string{"concurrency"}
+	mockService.On("PatchDocument", mock.Anything, "doc_1", mock.MatchedBy(func(patch types.DocumentPatch) bool {
+		return patch.Title == nil && patch.Tags != nil && assert.ObjectsAreEqual(tags, *patch.Tags)
+	})).Return(&types.Document{ID: "doc_1", Tags: tags}, nil)
+
+	handler := NewHandler(mockService)
+
+	req := withURLParam(httptest.NewRequest(http.MethodPatch, "/api/v1/documents/doc_1", strings.NewReader(`{"tags": ["concurrency"]}`)), "id", "doc_1")
+	w := httptest.NewRecorder()
+	handler.HandlePatchDocument(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandlePatchDocument_Invalid(t *testing.T) {
+	handler := NewHandler(new(MockServiceForTesting))
+
+	for _, body := range []string{`{}`, `{"title": " "}`, `{"content": ""}`} {
+		t.Run(body, func(t *testing.T) {
+			req := withURLParam(httptest.NewRequest(http.MethodPatch, "/api/v1/documents/doc_1", strings.NewReader(body)), "id", "doc_1")
+			w := httptest.NewRecorder()
+			handler.HandlePatchDocument(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestHandler_HandleDeleteDocument(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	mockService.On("DeleteDocument", mock.Anything, "doc_1").Return(nil)
+	mockService.On("DeleteDocument", mock.Anything, "missing").Return(ErrDocumentNotFound)
+
+	handler := NewHandler(mockService)
+
+	w := httptest.NewRecorder()
+	handler.HandleDeleteDocument(w, withURLParam(httptest.NewRequest(http.MethodDelete, "/api/v1/documents/doc_1", nil), "id", "doc_1"))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.HandleDeleteDocument(w, withURLParam(httptest.NewRequest(http.MethodDelete, "/api/v1/documents/missing", nil), "id", "missing"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
 func TestHandler_HandleSearchDocuments_Success(t *testing.T) {
 	mockService := new(MockServiceForTesting)
 	expectedDocs := []*types.Document{
 		{ID: "1", Title: "Test Doc", Content: "Test content"},
 	}
-	mockService.On("SearchDocuments", "test", 10).Return(expectedDocs, nil)
+	mockService.On("SearchDocuments", mock.Anything, "test", 10, (*types.VectorFilter)(nil)).Return(expectedDocs, nil)
 
 	handler := NewHandler(mockService)
 
@@ -233,6 +777,48 @@ func TestHandler_HandleSearchDocuments_Success(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestHandler_HandleSearchDocuments_RankedSnippets(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	expectedDocs := []*types.Document{
+		{ID: "1", Title: "Goroutines", Content: "Goroutines are lightweight threads.", Rank: 0.8, Snippet: "**Goroutines** are lightweight threads."},
+	}
+	mockService.On("SearchDocuments", mock.Anything, `"lightweight threads" -java`, 10, (*types.VectorFilter)(nil)).Return(expectedDocs, nil)
+
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/documents/search?q=%22lightweight+threads%22+-java", nil)
+	w := httptest.NewRecorder()
+	handler.HandleSearchDocuments(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response []map[string]interface{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	require.Len(t, response, 1)
+	assert.Equal(t, "**Goroutines** are lightweight threads.", response[0]["snippet"])
+	assert.InDelta(t, 0.8, response[0]["rank"], 0.0001)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleSearchDocuments_WithFilter(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	expectedFilter := &types.VectorFilter{
+		Must:    []types.FieldCondition{{Key: "category", Values: []string{"Go"}}, {Key: "tags", Values: []string{"concurrency", "channels"}}},
+		MustNot: []types.FieldCondition{{Key: "source", Values: []string{"ai-response"}}},
+	}
+	mockService.On("SearchDocuments", mock.Anything, "goroutines", 10, expectedFilter).Return([]*types.Document{}, nil)
+
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/documents/search?q=goroutines&category=Go&tag=concurrency&tag=channels&exclude_source=ai-response", nil)
+	w := httptest.NewRecorder()
+	handler.HandleSearchDocuments(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
 func TestHandler_HandleSearchDocuments_EmptyQuery(t *testing.T) {
 	mockService := new(MockServiceForTesting)
 	handler := NewHandler(mockService)
@@ -267,9 +853,29 @@ func TestHandler_HandleSearchDocuments_InvalidLimit(t *testing.T) {
 	assert.Contains(t, errorResp.Error, "Invalid limit parameter")
 }
 
+func TestHandler_HandleSearchDocuments_ServiceError(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	mockService.On("SearchDocuments", mock.Anything, "test", 10, (*types.VectorFilter)(nil)).Return([]*types.Document(nil), fmt.Errorf("service error"))
+
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/documents/search?q=test", nil)
+	w := httptest.NewRecorder()
+	handler.HandleSearchDocuments(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	var errorResp ErrorResponse
+	err := json.NewDecoder(w.Body).Decode(&errorResp)
+	require.NoError(t, err)
+	assert.Equal(t, ErrInternalServer, errorResp.Code)
+
+	mockService.AssertExpectations(t)
+}
+
 func TestHandler_HandleScrapeDocument_Success(t *testing.T) {
 	mockService := new(MockServiceForTesting)
-	mockService.On("ScrapeDocument", "https://example.com", "Test", []string{"test"}).Return(nil)
+	mockService.On("ScrapeDocument", mock.Anything, "https://example.com", "Test", []string{"test"}).Return("job_1", nil)
 
 	handler := NewHandler(mockService)
 
@@ -291,13 +897,203 @@ func TestHandler_HandleScrapeDocument_Success(t *testing.T) {
 	err := json.NewDecoder(w.Body).Decode(&response)
 	require.NoError(t, err)
 	assert.Equal(t, "Scraping job queued successfully", response["status"])
+	assert.Equal(t, "job_1", response["job_id"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleCrawlSite(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	mockService.On("CrawlSite", mock.Anything, "https://go.dev/doc/", "Go", []string(nil), types.CrawlOptions{
+		MaxDepth:   defaultCrawlMaxDepth,
+		MaxPages:   50,
+		Exclude:    []string{"/blog/"},
+		SameDomain: true,
+	}).Return("job_1", nil)
+
+	handler := NewHandler(mockService)
+
+	body := `{"url": "https://go.dev/doc/", "category": "Go", "max_pages": 50, "exclude": ["/blog/"]}`
+	w := httptest.NewRecorder()
+	handler.HandleCrawlSite(w, httptest.NewRequest(http.MethodPost, "/api/v1/crawl", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	var response map[string]string
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "job_1", response["job_id"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleCrawlSite_Invalid(t *testing.T) {
+	handler := NewHandler(new(MockServiceForTesting))
+
+	for _, body := range []string{
+		`{"url": "/docs"}`,
+		`{"url": "ftp://example.com/"}`,
+		`{"url": "https://example.com/", "max_depth": 11}`,
+		`{"url": "https://example.com/", "max_pages": 0}`,
+		`{"url": "https://example.com/", "include": ["("]}`,
+	} {
+		w := httptest.NewRecorder()
+		handler.HandleCrawlSite(w, httptest.NewRequest(http.MethodPost, "/api/v1/crawl", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestHandler_HandleIngestSitemap(t *testing.T) {
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	mockService := new(MockServiceForTesting)
+	mockService.On("IngestSitemap", mock.Anything, "https://go.dev/sitemap.xml", "Go", []string(nil), types.SitemapOptions{
+		Include:       []string{"^/doc/"},
+		ModifiedSince: &since,
+		MaxPages:      defaultSitemapMaxPages,
+	}).Return("job_1", nil)
+
+	handler := NewHandler(mockService)
+
+	body := `{"url": "https://go.dev/sitemap.xml", "category": "Go", "include": ["^/doc/"], "modified_since": "2024-03-01"}`
+	w := httptest.NewRecorder()
+	handler.HandleIngestSitemap(w, httptest.NewRequest(http.MethodPost, "/api/v1/sitemap", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	var response map[string]string
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "job_1", response["job_id"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleIngestSitemap_Invalid(t *testing.T) {
+	handler := NewHandler(new(MockServiceForTesting))
+
+	for _, body := range []string{
+		`{"url": "sitemap.xml"}`,
+		`{"url": "https://example.com/sitemap.xml", "max_pages": 5001}`,
+		`{"url": "https://example.com/sitemap.xml", "modified_since": "last week"}`,
+		`{"url": "https://example.com/sitemap.xml", "exclude": ["["]}`,
+	} {
+		w := httptest.NewRecorder()
+		handler.HandleIngestSitemap(w, httptest.NewRequest(http.MethodPost, "/api/v1/sitemap", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestHandler_HandleGetScrapeJob(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	mockService.On("GetScrapeJob", mock.Anything, "job_1").Return(&types.ScrapeJobRecord{
+		JobID:       "job_1",
+		URL:         "https://example.com",
+		Status:      types.JobSucceeded,
+		DocumentIDs: []string{"doc_1"},
+	}, nil)
+	mockService.On("GetScrapeJob", mock.Anything, "missing").Return(nil, ErrJobNotFound)
+
+	handler := NewHandler(mockService)
+
+	w := httptest.NewRecorder()
+	handler.HandleGetScrapeJob(w, withURLParam(httptest.NewRequest(http.MethodGet, "/api/v1/scrape/job_1", nil), "job_id", "job_1"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var job types.ScrapeJobRecord
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&job))
+	assert.Equal(t, types.JobSucceeded, job.Status)
+	assert.Equal(t, []string{"doc_1"}, job.DocumentIDs)
+
+	w = httptest.NewRecorder()
+	handler.HandleGetScrapeJob(w, withURLParam(httptest.NewRequest(http.MethodGet, "/api/v1/scrape/missing", nil), "job_id", "missing"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleListScrapeJobs(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	opts := types.ScrapeJobListOptions{Status: types.JobFailed, Limit: 5, Offset: 10}
+	mockService.On("ListScrapeJobs", mock.Anything, opts).Return(&types.ScrapeJobList{
+		Jobs:   []*types.ScrapeJobRecord{{JobID: "job_1", Status: types.JobFailed, Error: "timeout"}},
+		Total:  11,
+		Limit:  5,
+		Offset: 10,
+	}, nil)
+
+	handler := NewHandler(mockService)
+
+	w := httptest.NewRecorder()
+	handler.HandleListScrapeJobs(w, httptest.NewRequest(http.MethodGet, "/api/v1/scrape?status=failed&limit=5&offset=10", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var list types.ScrapeJobList
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	assert.Equal(t, 11, list.Total)
+	require.Len(t, list.Jobs, 1)
+	assert.Equal(t, "timeout", list.Jobs[0].Error)
+
+	w = httptest.NewRecorder()
+	handler.HandleListScrapeJobs(w, httptest.NewRequest(http.MethodGet, "/api/v1/scrape?status=done", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleListDeadLetters(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	opts := types.ScrapeJobListOptions{DeadLettered: true, Limit: defaultListLimit}
+	mockService.On("ListScrapeJobs", mock.Anything, opts).Return(&types.ScrapeJobList{
+		Jobs:  []*types.ScrapeJobRecord{{JobID: "job_1", Status: types.JobFailed, Attempts: 5}},
+		Total: 1,
+		Limit: defaultListLimit,
+	}, nil)
+
+	handler := NewHandler(mockService)
+
+	w := httptest.NewRecorder()
+	handler.HandleListDeadLetters(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/dead-letters", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var list types.ScrapeJobList
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	require.Len(t, list.Jobs, 1)
+	assert.Equal(t, 5, list.Jobs[0].Attempts)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleReplayDeadLetter(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	mockService.On("ReplayDeadLetter", mock.Anything, "job_1").Return(nil)
+	mockService.On("ReplayDeadLetter", mock.Anything, "job_2").Return(ErrJobNotDeadLettered)
+	mockService.On("ReplayDeadLetter", mock.Anything, "missing").Return(ErrJobNotFound)
+
+	handler := NewHandler(mockService)
+
+	tests := []struct {
+		jobID string
+		code  int
+	}{
+		{"job_1", http.StatusOK},
+		{"job_2", http.StatusConflict},
+		{"missing", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/dead-letters/"+tt.jobID+"/replay", nil)
+		w := httptest.NewRecorder()
+		handler.HandleReplayDeadLetter(w, withURLParam(req, "job_id", tt.jobID))
+		assert.Equal(t, tt.code, w.Code, tt.jobID)
+	}
 
 	mockService.AssertExpectations(t)
 }
 
 func TestHandler_HandleChatWithHistory_Success(t *testing.T) {
 	mockService := new(MockServiceForTesting)
-	mockService.On("ChatWithHistory", "session123", "Hello").Return("Hi there!", nil)
+	mockService.On("ChatWithHistory", mock.Anything, "session123", "Hello", ChatOptions{}).Return(&ChatResult{Response: "Hi there!"}, nil)
 
 	handler := NewHandler(mockService)
 
@@ -328,7 +1124,7 @@ func TestHandler_HandleGetChatHistory_Success(t *testing.T) {
 		{ID: "1", Role: "user", Content: "Hello"},
 		{ID: "2", Role: "assistant", Content: "Hi there!"},
 	}
-	mockService.On("GetChatHistory", "session123", 20).Return(expectedHistory, nil)
+	mockService.On("GetChatHistory", mock.Anything, "session123", 20).Return(expectedHistory, nil)
 
 	handler := NewHandler(mockService)
 
@@ -353,7 +1149,7 @@ func TestHandler_HandleGetConversationInsights_Success(t *testing.T) {
 		"total_messages": 5,
 		"topics":         []string{"javascript", "react"},
 	}
-	mockService.On("GetConversationInsights", "session123").Return(expectedInsights, nil)
+	mockService.On("GetConversationInsights", mock.Anything, "session123").Return(expectedInsights, nil)
 
 	handler := NewHandler(mockService)
 
@@ -0,0 +1,340 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tech-docs-ai/internal/types"
+)
+
+// Defaults for reciprocal rank fusion of keyword and vector search results.
+const (
+	DefaultVectorWeight  = 1.0
+	DefaultKeywordWeight = 1.0
+	DefaultRRFK          = 60
+)
+
+// retrievalConfig tunes how keyword and vector rankings are fused. A document
+// at rank r (from 1) in a ranking contributes weight / (k + r) to its score.
+type retrievalConfig struct {
+	vectorWeight  float64
+	keywordWeight float64
+	k             int
+}
+
+// loadRetrievalConfig reads the fusion settings from the RETRIEVAL_VECTOR_WEIGHT,
+// RETRIEVAL_KEYWORD_WEIGHT and RETRIEVAL_RRF_K environment variables. A weight
+// of 0 turns that kind of search off; without vector search there is no
+// similarity to apply a score threshold to.
+func loadRetrievalConfig() retrievalConfig {
+	cfg := retrievalConfig{
+		vectorWeight:  DefaultVectorWeight,
+		keywordWeight: DefaultKeywordWeight,
+		k:             DefaultRRFK,
+	}
+
+	if v, err := strconv.ParseFloat(os.Getenv("RETRIEVAL_VECTOR_WEIGHT"), 64); err == nil && v >= 0 {
+		cfg.vectorWeight = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("RETRIEVAL_KEYWORD_WEIGHT"), 64); err == nil && v >= 0 {
+		cfg.keywordWeight = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("RETRIEVAL_RRF_K")); err == nil && v > 0 {
+		cfg.k = v
+	}
+	if cfg.vectorWeight+cfg.keywordWeight == 0 {
+		cfg.vectorWeight, cfg.keywordWeight = DefaultVectorWeight, DefaultKeywordWeight
+	}
+
+	return cfg
+}
+
+// retrievedDoc is a document found for a query, with the excerpts used as context.
+type retrievedDoc struct {
+	doc      *types.Document
+	excerpts []string
+	// score is the similarity of the document's best matching chunk to the query
+	score float32
+	// rank is the fused score scaled so that first place in every ranking is 1
+	rank float32
+}
+
+// fusedRank is a document ID with its reciprocal rank fusion score.
+type fusedRank struct {
+	id    string
+	score float64
+}
+
+// fuse combines the vector and keyword rankings of document IDs, best first.
+// Ties keep the vector ranking's order.
+func (c retrievalConfig) fuse(vectorIDs, keywordIDs []string) []fusedRank {
+	scores := make(map[string]float64)
+	var order []string
+	add := func(ids []string, weight float64) {
+		for i, id := range ids {
+			if _, seen := scores[id]; !seen {
+				order = append(order, id)
+			}
+			scores[id] += weight / float64(c.k+i+1)
+		}
+	}
+	add(vectorIDs, c.vectorWeight)
+	add(keywordIDs, c.keywordWeight)
+
+	fused := make([]fusedRank, len(order))
+	for i, id := range order {
+		fused[i] = fusedRank{id: id, score: scores[id]}
+	}
+	sort.SliceStable(fused, func(i, j int) bool {
+		return fused[i].score > fused[j].score
+	})
+	return fused
+}
+
+// maxScore is the fused score of a document that is first in both rankings.
+func (c retrievalConfig) maxScore() float64 {
+	return (c.vectorWeight + c.keywordWeight) / float64(c.k+1)
+}
+
+// retrieve finds up to limit documents for query by running a vector search over
+// the document chunks and a keyword search over whole documents, then fusing
+// both rankings. Vector matches contribute their chunks as excerpts; keyword-only
+// matches contribute the chunk that mentions the query terms most, and are kept
+// only if one of their chunks meets the search's score threshold.
+func (s *Service) retrieve(ctx context.Context, query string, limit int, opts types.SearchOptions) ([]retrievedDoc, error) {
+	// The keyword search runs alongside the embedding and vector search
+	type keywordResult struct {
+		docs []*types.Document
+		err  error
+	}
+	keywordDone := make(chan keywordResult, 1)
+	if s.retrieval.keywordWeight > 0 && canFilterDocuments(opts.Filter) {
+		go func() {
+			docs, err := s.searchDocumentsByKeyword(ctx, query, limit)
+			keywordDone <- keywordResult{docs: docs, err: err}
+		}()
+	} else {
+		keywordDone <- keywordResult{}
+	}
+
+	var queryVector []float32
+	var searchResults []types.SearchResult
+	if s.retrieval.vectorWeight > 0 {
+		var err error
+		queryVector, err = s.embedQuery(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+
+		searchResults, err = s.vecClient.SearchVector(ctx, queryVector, limit, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search vectors: %w", err)
+		}
+	}
+
+	// Keyword search only adds to the vector results, so its failure is not fatal
+	keyword := <-keywordDone
+	if keyword.err != nil {
+		log.Printf("Keyword search failed, using vector results only: %v", keyword.err)
+	}
+
+	byID := make(map[string]*retrievedDoc)
+	var vectorIDs, keywordIDs []string
+
+	for _, result := range searchResults {
+		docID, ok := result.Metadata["document_id"].(string)
+		if !ok {
+			continue
+		}
+
+		r, seen := byID[docID]
+		if !seen {
+			doc, err := s.getDocumentWithCache(ctx, docID)
+			if err != nil || doc == nil {
+				continue
+			}
+			// Results come best first, so the first chunk has the document's similarity
+			r = &retrievedDoc{doc: doc, score: result.Score}
+			byID[docID] = r
+			vectorIDs = append(vectorIDs, docID)
+		}
+
+		// Chunk points carry their own text; whole-document points from before chunking do not
+		if content, ok := result.Metadata["content"].(string); ok && content != "" {
+			r.excerpts = append(r.excerpts, content)
+		} else if len(r.excerpts) == 0 {
+			r.excerpts = append(r.excerpts, r.doc.Content)
+		}
+	}
+
+	var keywordOnly []string
+	for _, doc := range keyword.docs {
+		if !documentMatchesFilter(doc, opts.Filter) {
+			continue
+		}
+		if _, seen := byID[doc.ID]; !seen {
+			keywordOnly = append(keywordOnly, doc.ID)
+		}
+	}
+
+	// Documents only the keyword search found are held to the same threshold
+	var similarities map[string]float32
+	if len(keywordOnly) > 0 && queryVector != nil {
+		var err error
+		similarities, err = s.documentSimilarities(ctx, queryVector, keywordOnly, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, doc := range keyword.docs {
+		if !documentMatchesFilter(doc, opts.Filter) {
+			continue
+		}
+		if _, seen := byID[doc.ID]; !seen {
+			score, ok := similarities[doc.ID]
+			if queryVector != nil && !ok {
+				continue
+			}
+			byID[doc.ID] = &retrievedDoc{doc: doc, excerpts: []string{s.keywordExcerpt(doc, query)}, score: score}
+		}
+		keywordIDs = append(keywordIDs, doc.ID)
+	}
+
+	var docs []retrievedDoc
+	for _, ranked := range s.retrieval.fuse(vectorIDs, keywordIDs) {
+		r := byID[ranked.id]
+		r.rank = float32(ranked.score / s.retrieval.maxScore())
+		docs = append(docs, *r)
+		if len(docs) == limit {
+			break
+		}
+	}
+
+	return docs, nil
+}
+
+// documentSimilarities returns the similarity of each document's best matching
+// chunk to queryVector. Documents with no chunk meeting the score threshold of
+// opts are left out.
+func (s *Service) documentSimilarities(ctx context.Context, queryVector []float32, docIDs []string, opts types.SearchOptions) (map[string]float32, error) {
+	opts.Filter = &types.VectorFilter{Must: []types.FieldCondition{{Key: "document_id", Values: docIDs}}}
+	opts.Offset = 0
+
+	results, err := s.vecClient.SearchVector(ctx, queryVector, len(docIDs)*chunksPerDocument, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search vectors: %w", err)
+	}
+
+	similarities := make(map[string]float32)
+	for _, result := range results {
+		docID, ok := result.Metadata["document_id"].(string)
+		if !ok {
+			continue
+		}
+		if _, seen := similarities[docID]; !seen {
+			similarities[docID] = result.Score
+		}
+	}
+	return similarities, nil
+}
+
+// keywordExcerpt returns the chunk of a document that contains the most query terms.
+func (s *Service) keywordExcerpt(doc *types.Document, query string) string {
+	chunks := s.splitter.Split(doc.Content)
+	if len(chunks) == 0 {
+		return doc.Content
+	}
+
+	terms := strings.Fields(strings.ToLower(query))
+	best, bestHits := 0, -1
+	for i, c := range chunks {
+		text := strings.ToLower(c.Content)
+		hits := 0
+		for _, term := range terms {
+			hits += strings.Count(text, strings.Trim(term, `"-`))
+		}
+		if hits > bestHits {
+			best, bestHits = i, hits
+		}
+	}
+	return chunks[best].Content
+}
+
+// documentFilterFields are the filterable payload fields that are also document fields.
+var documentFilterFields = map[string]bool{
+	"category":    true,
+	"tags":        true,
+	"author":      true,
+	"document_id": true,
+}
+
+// canFilterDocuments reports whether a filter can be checked against documents
+// found by keyword search. Fields such as source only exist on vector payloads.
+func canFilterDocuments(filter *types.VectorFilter) bool {
+	if filter.IsEmpty() {
+		return true
+	}
+	for _, conditions := range [][]types.FieldCondition{filter.Must, filter.Should, filter.MustNot} {
+		for _, cond := range conditions {
+			if !documentFilterFields[cond.Key] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// documentMatchesFilter applies a payload filter to a document's own fields.
+func documentMatchesFilter(doc *types.Document, filter *types.VectorFilter) bool {
+	if filter.IsEmpty() {
+		return true
+	}
+
+	matches := func(cond types.FieldCondition) bool {
+		var values []string
+		switch cond.Key {
+		case "category":
+			values = []string{doc.Category}
+		case "tags":
+			values = doc.Tags
+		case "author":
+			values = []string{doc.Author}
+		case "document_id":
+			values = []string{doc.ID}
+		}
+		for _, v := range values {
+			for _, want := range cond.Values {
+				if v == want {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for _, cond := range filter.Must {
+		if !matches(cond) {
+			return false
+		}
+	}
+	for _, cond := range filter.MustNot {
+		if matches(cond) {
+			return false
+		}
+	}
+	if len(filter.Should) == 0 {
+		return true
+	}
+	for _, cond := range filter.Should {
+		if matches(cond) {
+			return true
+		}
+	}
+	return false
+}
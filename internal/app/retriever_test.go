@@ -0,0 +1,157 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"tech-docs-ai/internal/chunk"
+	"tech-docs-ai/internal/types"
+	"tech-docs-ai/internal/vec"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fusedIDs(fused []fusedRank) []string {
+	ids := make([]string, len(fused))
+	for i, f := range fused {
+		ids[i] = f.id
+	}
+	return ids
+}
+
+func TestRetrievalConfig_Fuse(t *testing.T) {
+	cfg := retrievalConfig{vectorWeight: 1, keywordWeight: 1, k: 60}
+
+	fused := cfg.fuse([]string{"a", "b", "c"}, []string{"c", "d"})
+
+	// c is found by both searches, so it overtakes a, which only the vector search found
+	assert.Equal(t, []string{"c", "a", "b", "d"}, fusedIDs(fused))
+	assert.InDelta(t, 1.0/63+1.0/61, fused[0].score, 1e-9)
+	assert.InDelta(t, 1.0/61, fused[1].score, 1e-9)
+}
+
+func TestRetrievalConfig_FuseWeights(t *testing.T) {
+	vectorIDs := []string{"semantic", "both"}
+	keywordIDs := []string{"exact", "both"}
+
+	// Favouring keyword matches puts the exact API name ahead of the semantic match
+	cfg := retrievalConfig{vectorWeight: 1, keywordWeight: 3, k: 60}
+	assert.Equal(t, []string{"both", "exact", "semantic"}, fusedIDs(cfg.fuse(vectorIDs, keywordIDs)))
+
+	// Ties keep the vector order
+	cfg = retrievalConfig{vectorWeight: 1, keywordWeight: 1, k: 60}
+	assert.Equal(t, []string{"both", "semantic", "exact"}, fusedIDs(cfg.fuse(vectorIDs, keywordIDs)))
+
+	// A document first in both rankings scores the maximum
+	fused := cfg.fuse([]string{"top"}, []string{"top"})
+	assert.InDelta(t, 1.0, fused[0].score/cfg.maxScore(), 1e-9)
+}
+
+func TestLoadRetrievalConfig(t *testing.T) {
+	t.Setenv("RETRIEVAL_VECTOR_WEIGHT", "")
+	t.Setenv("RETRIEVAL_KEYWORD_WEIGHT", "")
+	t.Setenv("RETRIEVAL_RRF_K", "")
+	assert.Equal(t, retrievalConfig{vectorWeight: 1, keywordWeight: 1, k: 60}, loadRetrievalConfig())
+
+	t.Setenv("RETRIEVAL_VECTOR_WEIGHT", "0.5")
+	t.Setenv("RETRIEVAL_KEYWORD_WEIGHT", "0")
+	t.Setenv("RETRIEVAL_RRF_K", "10")
+	assert.Equal(t, retrievalConfig{vectorWeight: 0.5, keywordWeight: 0, k: 10}, loadRetrievalConfig())
+
+	// Turning both searches off is not allowed
+	t.Setenv("RETRIEVAL_VECTOR_WEIGHT", "0")
+	assert.Equal(t, retrievalConfig{vectorWeight: 1, keywordWeight: 1, k: 10}, loadRetrievalConfig())
+}
+
+func TestDocumentMatchesFilter(t *testing.T) {
+	doc := &types.Document{ID: "doc_1", Category: "Go", Tags: []string{"concurrency", "channels"}, Author: "gopher"}
+
+	tests := []struct {
+		name     string
+		filter   *types.VectorFilter
+		expected bool
+	}{
+		{"no filter", nil, true},
+		{"must match", &types.VectorFilter{Must: []types.FieldCondition{{Key: "category", Values: []string{"Go", "Rust"}}}}, true},
+		{"must miss", &types.VectorFilter{Must: []types.FieldCondition{{Key: "category", Values: []string{"CSS"}}}}, false},
+		{"tag match", &types.VectorFilter{Must: []types.FieldCondition{{Key: "tags", Values: []string{"channels"}}}}, true},
+		{"must not", &types.VectorFilter{MustNot: []types.FieldCondition{{Key: "author", Values: []string{"gopher"}}}}, false},
+		{"should", &types.VectorFilter{Should: []types.FieldCondition{
+			{Key: "document_id", Values: []string{"doc_2"}},
+			{Key: "tags", Values: []string{"concurrency"}},
+		}}, true},
+		{"should miss", &types.VectorFilter{Should: []types.FieldCondition{{Key: "document_id", Values: []string{"doc_2"}}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, documentMatchesFilter(doc, tt.filter))
+		})
+	}
+}
+
+func TestCanFilterDocuments(t *testing.T) {
+	assert.True(t, canFilterDocuments(nil))
+	assert.True(t, canFilterDocuments(&types.VectorFilter{Must: []types.FieldCondition{{Key: "category", Values: []string{"Go"}}}}))
+	assert.False(t, canFilterDocuments(&types.VectorFilter{MustNot: []types.FieldCondition{{Key: "source", Values: []string{"ai-response"}}}}))
+}
+
+func TestService_KeywordExcerpt(t *testing.T) {
+	s := &Service{splitter: chunk.NewSplitter()}
+
+	doc := &types.Document{Content: "# Intro\n\n" + strings.Repeat("Some general text about Go. ", 60) +
+		"\n\n# Errors\n\nThe ErrUnexpectedEOF error means the input ended early. Check ErrUnexpectedEOF with errors.Is."}
+
+	excerpt := s.keywordExcerpt(doc, "ErrUnexpectedEOF")
+	require.NotEmpty(t, excerpt)
+	assert.Contains(t, excerpt, "ErrUnexpectedEOF")
+	assert.NotContains(t, excerpt, "general text")
+}
+
+func TestService_DocumentSimilarities(t *testing.T) {
+	ctx := context.Background()
+	store := vec.NewMemoryStore(vec.DistanceCosine)
+	require.NoError(t, store.StoreVectors(ctx, []types.VectorPoint{
+		{DocumentID: "doc_1", ChunkIndex: 0, Vector: []float32{0.6, 0.8}, Payload: map[string]interface{}{"document_id": "doc_1"}},
+		{DocumentID: "doc_1", ChunkIndex: 1, Vector: []float32{1, 0}, Payload: map[string]interface{}{"document_id": "doc_1"}},
+		{DocumentID: "doc_2", ChunkIndex: 0, Vector: []float32{0, 1}, Payload: map[string]interface{}{"document_id": "doc_2"}},
+		{DocumentID: "doc_3", ChunkIndex: 0, Vector: []float32{1, 0}, Payload: map[string]interface{}{"document_id": "doc_3"}},
+	}))
+	s := &Service{vecClient: store}
+	threshold := float32(0.5)
+
+	similarities, err := s.documentSimilarities(ctx, []float32{1, 0}, []string{"doc_1", "doc_2"}, types.SearchOptions{ScoreThreshold: &threshold})
+	require.NoError(t, err)
+
+	// doc_1 scores its best chunk, doc_2 is below the threshold and doc_3 was not asked for
+	require.Len(t, similarities, 1)
+	assert.InDelta(t, 1.0, similarities["doc_1"], 1e-6)
+}
+
+func TestTutorialContext(t *testing.T) {
+	retrieved := []retrievedDoc{
+		{doc: &types.Document{Title: "Goroutines"}, excerpts: []string{"First.", "Second."}},
+	}
+
+	assert.Equal(t, []string{"Title: Goroutines\nContent: First.\n\n...\n\nSecond."}, tutorialContext(retrieved))
+}
+
+func TestCitedContext(t *testing.T) {
+	retrieved := []retrievedDoc{
+		{doc: &types.Document{ID: "doc_1", Title: "Goroutines", Category: "Go"}, excerpts: []string{"Lightweight threads."}, score: 0.9, rank: 1},
+		{doc: &types.Document{ID: "doc_2", Title: "Channels", Metadata: map[string]string{"url": "https://go.dev/ch"}}, excerpts: []string{"Pipes."}, score: 0.75, rank: 0.5},
+	}
+
+	contextDocs, sources := citedContext(retrieved)
+
+	assert.Equal(t, []string{
+		"[1] Title: Goroutines\nContent: Lightweight threads.",
+		"[2] Title: Channels\nURL: https://go.dev/ch\nContent: Pipes.",
+	}, contextDocs)
+	assert.Equal(t, []types.Source{
+		{DocumentID: "doc_1", Title: "Goroutines", Category: "Go", Score: 0.9, Rank: 1},
+		{DocumentID: "doc_2", Title: "Channels", URL: "https://go.dev/ch", Score: 0.75, Rank: 0.5},
+	}, sources)
+}
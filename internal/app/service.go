@@ -4,12 +4,14 @@ package app
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
 	"tech-docs-ai/internal/cache"
+	"tech-docs-ai/internal/chunk"
 	"tech-docs-ai/internal/types"
 )
 
@@ -19,89 +21,139 @@ type Service struct {
 	embClient embClient
 	vecClient vecClient
 	docStore  docStore
+	jobStore  jobStore
 	kafkaProd kafkaProducer
 	cache     *cache.RedisCache
+	splitter  *chunk.Splitter
+	retrieval retrievalConfig
 }
 
 // NewService creates a new Service instance.
-func NewService(embClient embClient, vecClient vecClient, docStore docStore, kafkaProd kafkaProducer, cache *cache.RedisCache) *Service {
+func NewService(embClient embClient, vecClient vecClient, docStore docStore, jobStore jobStore, kafkaProd kafkaProducer, cache *cache.RedisCache) *Service {
 	return &Service{
 		embClient: embClient,
 		vecClient: vecClient,
 		docStore:  docStore,
+		jobStore:  jobStore,
 		kafkaProd: kafkaProd,
 		cache:     cache,
+		splitter:  chunk.NewSplitter(),
+		retrieval: loadRetrievalConfig(),
 	}
 }
 
 // embClient is an interface that defines the contract for an embedding client.
 type embClient interface {
 	// Embed will take a text and return its vector representation.
-	Embed(text string) ([]float32, error)
+	Embed(ctx context.Context, text string) ([]float32, error)
+	// EmbedBatch will take many texts and return their vectors in input order.
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
 	// Chat will take a message and return a response from the LLM.
-	Chat(message string) (string, error)
+	Chat(ctx context.Context, message string, opts types.GenerationOptions) (string, error)
+	// ChatStream is like Chat but calls onToken with each chunk as it is generated.
+	ChatStream(ctx context.Context, message string, opts types.GenerationOptions, onToken func(token string) error) (string, error)
+	// ChatMessages will take a role-structured conversation and return the assistant's reply.
+	ChatMessages(ctx context.Context, messages []types.LLMMessage, opts types.GenerationOptions) (string, error)
+	// ChatMessagesStream is like ChatMessages but calls onToken with each chunk as it is generated.
+	ChatMessagesStream(ctx context.Context, messages []types.LLMMessage, opts types.GenerationOptions, onToken func(token string) error) (string, error)
 }
 
 // vecClient is an interface for vector storage.
 type vecClient interface {
-	StoreVector(vector []float32, metadata map[string]interface{}) error
-	SearchVector(vector []float32, limit int) ([]types.SearchResult, error)
+	StoreVectors(ctx context.Context, points []types.VectorPoint) error
+	DeleteByDocumentID(ctx context.Context, documentID string) error
+	DeleteStaleChunks(ctx context.Context, documentID string, chunkCount int) error
+	SearchVector(ctx context.Context, vector []float32, limit int, opts types.SearchOptions) ([]types.SearchResult, error)
+}
+
+// documentIndexer is implemented by vector stores that share a database with
+// the documents and can store a document and replace its vectors in one transaction.
+type documentIndexer interface {
+	StoreDocumentWithVectors(ctx context.Context, doc *types.Document, points []types.VectorPoint) error
+	DeleteDocumentWithVectors(ctx context.Context, documentID string) (bool, error)
 }
 
 // docStore is an interface for document storage.
 type docStore interface {
-	StoreDocument(doc *types.Document) error
-	GetDocument(id string) (*types.Document, error)
-	SearchDocuments(query string, limit int) ([]*types.Document, error)
+	StoreDocument(ctx context.Context, doc *types.Document) error
+	GetDocument(ctx context.Context, id string) (*types.Document, error)
+	ListDocuments(ctx context.Context, opts types.DocumentListOptions) ([]*types.Document, int, error)
+	DeleteDocument(ctx context.Context, id string) (bool, error)
+	SearchDocuments(ctx context.Context, query string, limit int) ([]*types.Document, error)
+}
+
+// jobStore is an interface for tracking scrape jobs.
+type jobStore interface {
+	CreateScrapeJob(ctx context.Context, job *types.ScrapeJobRecord) error
+	FinishScrapeJob(ctx context.Context, jobID string, status types.JobStatus, errMsg string, documentIDs []string) error
+	GetScrapeJob(ctx context.Context, id string) (*types.ScrapeJobRecord, error)
+	ListScrapeJobs(ctx context.Context, opts types.ScrapeJobListOptions) ([]*types.ScrapeJobRecord, int, error)
+	RequeueScrapeJob(ctx context.Context, jobID string) (bool, error)
+	DeadLetterScrapeJob(ctx context.Context, jobID string, errMsg string) error
+	CrawlProgress(ctx context.Context, crawlID string) (*types.CrawlProgress, error)
 }
 
 // kafkaProducer is an interface for Kafka messaging.
 type kafkaProducer interface {
-	SendMessage(topic string, message []byte) error
+	SendMessage(ctx context.Context, topic string, message []byte) error
+}
+
+// ChatResult is a generated answer together with the documents it was based on.
+// The answer cites Sources[i] as [i+1].
+type ChatResult struct {
+	Response string
+	Sources  []types.Source
+}
+
+// ChatOptions tunes a single chat request. The zero value uses the configured defaults.
+type ChatOptions struct {
+	Generation types.GenerationOptions
+	// Filter restricts which chunks may be used as context, e.g. to one category.
+	Filter *types.VectorFilter
+	// ScoreThreshold is the minimum similarity a chunk needs to be used as
+	// context. Nil uses the vector store's configured threshold.
+	ScoreThreshold *float32
+}
+
+// contextSearchLimit is the number of documents retrieved as context for an answer.
+const contextSearchLimit = 5
+
+// searchOptions returns the vector search options for retrieving chat context.
+func (o ChatOptions) searchOptions() types.SearchOptions {
+	return types.SearchOptions{Filter: o.Filter, ScoreThreshold: o.ScoreThreshold}
 }
 
 // Chat handles the core logic for a chat interaction with RAG and caching.
-func (s *Service) Chat(message string) (string, error) {
-	ctx := context.Background()
+// The result lists the documents the answer was based on.
+func (s *Service) Chat(ctx context.Context, message string, opts ChatOptions) (*ChatResult, error) {
+	return s.chat(ctx, message, opts, nil)
+}
 
-	// Step 1: Check cache for embedding
-	embeddingCache := s.cache.EmbeddingCache()
-	queryVector, err := embeddingCache.Get(ctx, message)
-	if err != nil {
-		// Cache miss, generate embedding
-		queryVector, err = s.embClient.Embed(message)
-		if err != nil {
-			return "", fmt.Errorf("failed to embed query: %w", err)
-		}
-		// Cache the embedding for future use
-		embeddingCache.Set(ctx, message, queryVector, cache.DefaultTTL)
+// ChatStream runs the same pipeline as Chat but streams the answer through onDelta.
+// Cancelling ctx aborts generation.
+func (s *Service) ChatStream(ctx context.Context, message string, opts ChatOptions, onDelta func(delta string) error) (*ChatResult, error) {
+	return s.chat(ctx, message, opts, onDelta)
+}
+
+// generate sends a prompt to the LLM, streaming through onDelta when it is set.
+func (s *Service) generate(ctx context.Context, prompt string, opts types.GenerationOptions, onDelta func(delta string) error) (string, error) {
+	if onDelta == nil {
+		return s.embClient.Chat(ctx, prompt, opts)
 	}
+	return s.embClient.ChatStream(ctx, prompt, opts, onDelta)
+}
 
-	// Step 2: Search for relevant documents in vector database
-	searchResults, err := s.vecClient.SearchVector(queryVector, 5)
+// chat implements Chat and ChatStream.
+func (s *Service) chat(ctx context.Context, message string, opts ChatOptions, onDelta func(delta string) error) (*ChatResult, error) {
+	// Step 1-3: Find relevant documents by keyword and vector search
+	retrieved, err := s.retrieve(ctx, message, contextSearchLimit, opts.searchOptions())
 	if err != nil {
-		return "", fmt.Errorf("failed to search vectors: %w", err)
-	}
-
-	// Step 3: Retrieve relevant documents from cache/database
-	var contextDocs []string
-	var tutorialData []string
-	var hasRelevantContent bool
-
-	for _, result := range searchResults {
-		if docID, ok := result.Metadata["document_id"].(string); ok {
-			doc, err := s.getDocumentWithCache(ctx, docID)
-			if err == nil && doc != nil {
-				// Check if the content is relevant (score threshold)
-				if result.Score > 0.7 { // Adjust threshold as needed
-					contextDocs = append(contextDocs, fmt.Sprintf("Title: %s\nContent: %s", doc.Title, doc.Content))
-					tutorialData = append(tutorialData, doc.Content)
-					hasRelevantContent = true
-				}
-			}
-		}
+		return nil, err
 	}
 
+	contextDocs, sources := citedContext(retrieved)
+	hasRelevantContent := len(contextDocs) > 0
+
 	// Step 4: Build context-aware prompt for tutorial generation
 	context := ""
 	if hasRelevantContent && len(contextDocs) > 0 {
@@ -111,7 +163,7 @@ func (s *Service) Chat(message string) (string, error) {
 	// Step 5: Generate tutorial response using LLM with context (simplified for speed)
 	var tutorialPrompt string
 	if hasRelevantContent {
-		tutorialPrompt = fmt.Sprintf("%sQuestion: %s\n\nProvide a concise tutorial in Markdown format with:\n\n# %s\n\n## What is it?\n[Brief explanation]\n\n## Key Points:\n- Point 1\n- Point 2\n- Point 3\n\n## Basic Example:\n```\n[Simple example]\n```\n\nKeep it short and practical.", context, message, message)
+		tutorialPrompt = fmt.Sprintf("%sQuestion: %s\n\n"+citationInstruction+"\n\nProvide a concise tutorial in Markdown format with:\n\n# %s\n\n## What is it?\n[Brief explanation]\n\n## Key Points:\n- Point 1\n- Point 2\n- Point 3\n\n## Basic Example:\n```\n[Simple example]\n```\n\nKeep it short and practical.", context, message, message)
 	} else {
 		// No relevant content found, provide simple response
 		tutorialPrompt = fmt.Sprintf(`Question: %s
@@ -133,27 +185,79 @@ Provide a brief tutorial in Markdown format:
 Keep it concise and helpful.`, message, message)
 	}
 
-	response, err := s.embClient.Chat(tutorialPrompt)
+	response, err := s.generate(ctx, tutorialPrompt, opts.Generation, onDelta)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate tutorial response: %w", err)
+		return nil, fmt.Errorf("failed to generate tutorial response: %w", err)
 	}
 
 	// Step 6: Store the response in vector database for future learning
-	go s.storeResponseForLearning(message, response, hasRelevantContent)
+	go s.storeResponseForLearning(ctx, message, response, hasRelevantContent)
 
-	return response, nil
+	return &ChatResult{Response: response, Sources: sources}, nil
 }
 
-// storeResponseForLearning stores the LLM response in the vector database for future learning
-func (s *Service) storeResponseForLearning(userQuery, llmResponse string, wasBasedOnScrapedData bool) {
-	ctx := context.Background()
+// embedQuery returns the embedding of a search query, using the embedding cache.
+func (s *Service) embedQuery(ctx context.Context, query string) ([]float32, error) {
+	embeddingCache := s.cache.EmbeddingCache()
+	if vector, err := embeddingCache.Get(ctx, query); err == nil && vector != nil {
+		return vector, nil
+	}
 
-	// Generate embedding for the response
-	responseVector, err := s.embClient.Embed(llmResponse)
+	// Cache miss, generate embedding
+	vector, err := s.embClient.Embed(ctx, query)
 	if err != nil {
-		log.Printf("Failed to embed response for learning: %v", err)
-		return
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	// Cache the embedding for future use
+	embeddingCache.Set(ctx, query, vector, cache.DefaultTTL)
+	return vector, nil
+}
+
+// citationInstruction asks the model to cite the numbered documents in its context.
+const citationInstruction = "Reference the documentation you rely on with its number in square brackets, for example [1] or [2]."
+
+// citedContext builds prompt context from retrieved documents, numbered to match the sources.
+func citedContext(retrieved []retrievedDoc) ([]string, []types.Source) {
+	contextDocs := make([]string, len(retrieved))
+	sources := make([]types.Source, len(retrieved))
+	for i, r := range retrieved {
+		contextDocs[i] = formatCitedDoc(i+1, r.doc, r.excerpts)
+		sources[i] = newSource(r.doc, r.score, r.rank)
+	}
+	return contextDocs, sources
+}
+
+// formatCitedDoc formats the matching excerpts of a document as numbered prompt context.
+// The number matches the document's position in ChatResult.Sources.
+func formatCitedDoc(n int, doc *types.Document, excerpts []string) string {
+	content := strings.Join(excerpts, "\n\n...\n\n")
+	if url := doc.Metadata["url"]; url != "" {
+		return fmt.Sprintf("[%d] Title: %s\nURL: %s\nContent: %s", n, doc.Title, url, content)
 	}
+	return fmt.Sprintf("[%d] Title: %s\nContent: %s", n, doc.Title, content)
+}
+
+// newSource describes a retrieved document for the caller.
+func newSource(doc *types.Document, score, rank float32) types.Source {
+	return types.Source{
+		DocumentID: doc.ID,
+		Title:      doc.Title,
+		URL:        doc.Metadata["url"],
+		Category:   doc.Category,
+		Score:      score,
+		Rank:       rank,
+	}
+}
+
+// learningTimeout bounds the background work of storeResponseForLearning.
+const learningTimeout = 2 * time.Minute
+
+// storeResponseForLearning stores the LLM response in the vector database for future learning.
+// It runs after the request has been answered, so it keeps ctx's values but not its cancellation.
+func (s *Service) storeResponseForLearning(ctx context.Context, userQuery, llmResponse string, wasBasedOnScrapedData bool) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), learningTimeout)
+	defer cancel()
 
 	// Create a document for the response
 	responseDoc := &types.Document{
@@ -172,19 +276,15 @@ func (s *Service) storeResponseForLearning(userQuery, llmResponse string, wasBas
 		},
 	}
 
-	// Store in database
-	if err := s.docStore.StoreDocument(responseDoc); err != nil {
-		log.Printf("Failed to store response document: %v", err)
+	// Generate embeddings for the response chunks
+	embedded, err := s.embedChunks(ctx, responseDoc)
+	if err != nil {
+		log.Printf("Failed to embed response for learning: %v", err)
 		return
 	}
 
-	// Cache the document
-	docCache := s.cache.DocumentCache()
-	docCache.Set(ctx, responseDoc, cache.DefaultTTL)
-
-	// Store vector with metadata
+	// Store the response and one vector per chunk with metadata
 	metadata := map[string]interface{}{
-		"document_id":   responseDoc.ID,
 		"title":         responseDoc.Title,
 		"category":      responseDoc.Category,
 		"tags":          responseDoc.Tags,
@@ -195,11 +295,15 @@ func (s *Service) storeResponseForLearning(userQuery, llmResponse string, wasBas
 		"learning_data": true,
 	}
 
-	if err := s.vecClient.StoreVector(responseVector, metadata); err != nil {
-		log.Printf("Failed to store response vector: %v", err)
+	if err := s.indexDocument(ctx, responseDoc, embedded, metadata); err != nil {
+		log.Printf("Failed to store response for learning: %v", err)
 		return
 	}
 
+	// Cache the document
+	docCache := s.cache.DocumentCache()
+	docCache.Set(ctx, responseDoc, cache.DefaultTTL)
+
 	log.Printf("Stored AI response for learning: %s", responseDoc.ID)
 }
 
@@ -222,7 +326,7 @@ func (s *Service) getDocumentWithCache(ctx context.Context, id string) (*types.D
 	}
 
 	// Cache miss, get from database
-	doc, err = s.docStore.GetDocument(id)
+	doc, err = s.docStore.GetDocument(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -235,69 +339,392 @@ func (s *Service) getDocumentWithCache(ctx context.Context, id string) (*types.D
 	return doc, nil
 }
 
-// AddDocument adds a new documentation piece to the system with caching.
-func (s *Service) AddDocument(doc *types.Document) error {
-	ctx := context.Background()
+// embeddedChunk is a document chunk together with its embedding.
+type embeddedChunk struct {
+	chunk  chunk.Chunk
+	vector []float32
+}
 
-	// Generate embedding for the document content
+// embedChunks splits a document into chunks and embeds them, using the embedding
+// cache and a single batch request for the chunks that are not cached.
+func (s *Service) embedChunks(ctx context.Context, doc *types.Document) ([]embeddedChunk, error) {
 	embeddingCache := s.cache.EmbeddingCache()
-	vector, err := embeddingCache.Get(ctx, doc.Content)
+
+	chunks := s.splitter.Split(doc.Content)
+	embedded := make([]embeddedChunk, len(chunks))
+
+	// Use cached embeddings where possible and collect the rest
+	var missing []int
+	var texts []string
+	for i, c := range chunks {
+		text := c.EmbeddingText(doc.Title)
+		embedded[i].chunk = c
+
+		if vector, err := embeddingCache.Get(ctx, text); err == nil && vector != nil {
+			embedded[i].vector = vector
+			continue
+		}
+		missing = append(missing, i)
+		texts = append(texts, text)
+	}
+
+	if len(texts) == 0 {
+		return embedded, nil
+	}
+
+	vectors, err := s.embClient.EmbedBatch(ctx, texts)
 	if err != nil {
-		// Cache miss, generate embedding
-		vector, err = s.embClient.Embed(doc.Content)
-		if err != nil {
-			return fmt.Errorf("failed to embed document: %w", err)
+		return nil, fmt.Errorf("failed to embed %d chunks: %w", len(texts), err)
+	}
+
+	for j, i := range missing {
+		embedded[i].vector = vectors[j]
+		embeddingCache.Set(ctx, texts[j], vectors[j], cache.DefaultTTL)
+	}
+
+	return embedded, nil
+}
+
+// indexDocument stores a document and replaces its vectors with one vector per
+// chunk; each payload extends metadata with the document ID. The new chunks
+// overwrite the old ones in place before the chunks past the end of a shorter
+// revision are removed, so searches never see the document without vectors.
+// When the vector store shares a database with the documents, both are written
+// in one transaction.
+func (s *Service) indexDocument(ctx context.Context, doc *types.Document, embedded []embeddedChunk, metadata map[string]interface{}) error {
+	// The ID is needed in the payloads before the document is stored
+	if doc.ID == "" {
+		doc.ID = fmt.Sprintf("doc_%d", time.Now().UnixNano())
+	}
+	metadata["document_id"] = doc.ID
+
+	points := make([]types.VectorPoint, len(embedded))
+	for i, e := range embedded {
+		points[i] = types.VectorPoint{
+			DocumentID: doc.ID,
+			ChunkIndex: e.chunk.Index,
+			Vector:     e.vector,
+			Payload:    e.chunk.Payload(metadata),
 		}
-		// Cache the embedding
-		embeddingCache.Set(ctx, doc.Content, vector, cache.DefaultTTL)
 	}
 
-	// Store document in database
-	if err := s.docStore.StoreDocument(doc); err != nil {
+	if indexer, ok := s.vecClient.(documentIndexer); ok {
+		if err := indexer.StoreDocumentWithVectors(ctx, doc, points); err != nil {
+			return fmt.Errorf("failed to store document with vectors: %w", err)
+		}
+		return nil
+	}
+
+	if err := s.docStore.StoreDocument(ctx, doc); err != nil {
 		return fmt.Errorf("failed to store document: %w", err)
 	}
+	if err := s.vecClient.StoreVectors(ctx, points); err != nil {
+		return fmt.Errorf("failed to store vectors: %w", err)
+	}
+	if err := s.vecClient.DeleteStaleChunks(ctx, doc.ID, len(points)); err != nil {
+		return fmt.Errorf("failed to delete stale vectors: %w", err)
+	}
+	return nil
+}
+
+// AddDocument adds a new documentation piece to the system with caching.
+func (s *Service) AddDocument(ctx context.Context, doc *types.Document) error {
+	// Generate embeddings for the document chunks
+	embedded, err := s.embedChunks(ctx, doc)
+	if err != nil {
+		return fmt.Errorf("failed to embed document: %w", err)
+	}
+
+	// Store the document and one vector per chunk with document metadata
+	metadata := map[string]interface{}{
+		"title":    doc.Title,
+		"category": doc.Category,
+		"tags":     doc.Tags,
+		"author":   doc.Author,
+	}
+
+	if err := s.indexDocument(ctx, doc, embedded, metadata); err != nil {
+		return err
+	}
 
 	// Cache the document
 	docCache := s.cache.DocumentCache()
 	docCache.Set(ctx, doc, cache.DefaultTTL)
 
-	// Store vector with document metadata
-	metadata := map[string]interface{}{
-		"document_id": doc.ID,
-		"title":       doc.Title,
-		"category":    doc.Category,
-		"tags":        doc.Tags,
-		"author":      doc.Author,
+	// Cached search results may include or miss the document
+	s.clearSearchCache(ctx)
+
+	return nil
+}
+
+// ErrDocumentNotFound is returned when a document to read, update or delete does not exist.
+var ErrDocumentNotFound = errors.New("document not found")
+
+// GetDocument returns a document by ID.
+func (s *Service) GetDocument(ctx context.Context, id string) (*types.Document, error) {
+	doc, err := s.getDocumentWithCache(ctx, id)
+	if err != nil {
+		return nil, err
 	}
+	if doc == nil {
+		return nil, ErrDocumentNotFound
+	}
+	return doc, nil
+}
 
-	if err := s.vecClient.StoreVector(vector, metadata); err != nil {
-		return fmt.Errorf("failed to store vector: %w", err)
+// ListDocuments returns one page of documents, newest first.
+func (s *Service) ListDocuments(ctx context.Context, opts types.DocumentListOptions) (*types.DocumentList, error) {
+	docs, total, err := s.docStore.ListDocuments(ctx, opts)
+	if err != nil {
+		return nil, err
 	}
+	return &types.DocumentList{Documents: docs, Total: total, Limit: opts.Limit, Offset: opts.Offset}, nil
+}
 
-	// Invalidate search cache for this category
-	searchCache := s.cache.SearchCache()
-	searchCache.Delete(ctx, doc.Category)
+// UpdateDocument replaces the content and fields of an existing document and
+// re-indexes its vectors. The ID and creation time are kept.
+func (s *Service) UpdateDocument(ctx context.Context, id string, doc *types.Document) (*types.Document, error) {
+	return s.updateDocument(ctx, id, func(existing *types.Document) *types.Document {
+		doc.ID = existing.ID
+		doc.CreatedAt = existing.CreatedAt
+		return doc
+	})
+}
+
+// PatchDocument changes the fields set in patch and re-indexes the document's vectors.
+func (s *Service) PatchDocument(ctx context.Context, id string, patch types.DocumentPatch) (*types.Document, error) {
+	return s.updateDocument(ctx, id, func(existing *types.Document) *types.Document {
+		patch.Apply(existing)
+		return existing
+	})
+}
+
+// updateDocument loads a document from the database, applies update to it and
+// stores and re-embeds the result.
+func (s *Service) updateDocument(ctx context.Context, id string, update func(existing *types.Document) *types.Document) (*types.Document, error) {
+	// Read from the database, not the cache, so the update starts from the stored revision
+	existing, err := s.docStore.GetDocument(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrDocumentNotFound
+	}
+	doc := update(existing)
+
+	embedded, err := s.embedChunks(ctx, doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed document: %w", err)
+	}
+
+	if err := s.indexDocument(ctx, doc, embedded, documentPayload(doc)); err != nil {
+		return nil, err
+	}
+
+	docCache := s.cache.DocumentCache()
+	docCache.Set(ctx, doc, cache.DefaultTTL)
+
+	// Cached search results may include or miss the document
+	s.clearSearchCache(ctx)
+
+	return doc, nil
+}
+
+// documentPayload returns the vector payload fields of a re-indexed document.
+// The source of scraped documents and AI responses is derived from their metadata.
+func documentPayload(doc *types.Document) map[string]interface{} {
+	payload := map[string]interface{}{
+		"title":    doc.Title,
+		"category": doc.Category,
+		"tags":     doc.Tags,
+		"author":   doc.Author,
+	}
+
+	switch url := doc.Metadata["url"]; {
+	case doc.Category == "AI_Response":
+		payload["source"] = "ai-response"
+	case strings.Contains(url, "w3schools.com"):
+		payload["source"] = "w3schools"
+	case url != "":
+		payload["source"] = "universal"
+	}
+
+	return payload
+}
+
+// DeleteDocument deletes a document, its vectors and its cache entry.
+func (s *Service) DeleteDocument(ctx context.Context, id string) error {
+	var deleted bool
+	if indexer, ok := s.vecClient.(documentIndexer); ok {
+		var err error
+		if deleted, err = indexer.DeleteDocumentWithVectors(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete document with vectors: %w", err)
+		}
+	} else {
+		// Vectors go first so that retrying a failed delete still removes them
+		if err := s.vecClient.DeleteByDocumentID(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete vectors: %w", err)
+		}
+		var err error
+		if deleted, err = s.docStore.DeleteDocument(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	docCache := s.cache.DocumentCache()
+	if err := docCache.Delete(ctx, id); err != nil {
+		log.Printf("Failed to delete document %s from cache: %v", id, err)
+	}
+	s.clearSearchCache(ctx)
 
+	if !deleted {
+		return ErrDocumentNotFound
+	}
 	return nil
 }
 
-// ScrapeDocument queues a scraping job via Kafka.
-func (s *Service) ScrapeDocument(url, category string, tags []string) error {
-	job := types.ScrapeJob{
+// clearSearchCache drops the cached search results after a document changes.
+func (s *Service) clearSearchCache(ctx context.Context) {
+	if err := s.cache.SearchCache().Clear(ctx); err != nil {
+		log.Printf("Failed to clear search cache: %v", err)
+	}
+}
+
+// ErrJobNotFound is returned when a scrape job does not exist.
+var ErrJobNotFound = errors.New("scrape job not found")
+
+// ScrapeDocument records a scraping job and queues it via Kafka. It returns
+// the job ID, which GetScrapeJob reports the progress of.
+func (s *Service) ScrapeDocument(ctx context.Context, url, category string, tags []string) (string, error) {
+	return s.queueScrapeJob(ctx, types.ScrapeJob{
 		URL:      url,
 		Category: category,
 		Tags:     tags,
-		JobID:    fmt.Sprintf("job_%d", time.Now().UnixNano()),
-	}
+		Type:     types.JobTypePage,
+	})
+}
+
+// CrawlSite records a crawl job starting from seedURL and queues it via
+// Kafka. Workers scrape every page the crawl reaches as a page job of its
+// own. It returns the crawl's job ID, which GetScrapeJob reports the
+// aggregate progress of.
+func (s *Service) CrawlSite(ctx context.Context, seedURL, category string, tags []string, opts types.CrawlOptions) (string, error) {
+	return s.queueScrapeJob(ctx, types.ScrapeJob{
+		URL:      seedURL,
+		Category: category,
+		Tags:     tags,
+		Type:     types.JobTypeCrawl,
+		Crawl:    &opts,
+	})
+}
+
+// IngestSitemap records a sitemap job for the sitemap or sitemap index at
+// sitemapURL and queues it via Kafka. Workers scrape the pages it lists that
+// pass opts and changed since they were last stored, each as a page job of
+// its own. It returns the sitemap job's ID.
+func (s *Service) IngestSitemap(ctx context.Context, sitemapURL, category string, tags []string, opts types.SitemapOptions) (string, error) {
+	return s.queueScrapeJob(ctx, types.ScrapeJob{
+		URL:      sitemapURL,
+		Category: category,
+		Tags:     tags,
+		Type:     types.JobTypeSitemap,
+		Sitemap:  &opts,
+	})
+}
+
+// queueScrapeJob assigns the job an ID, records it and sends it to Kafka.
+func (s *Service) queueScrapeJob(ctx context.Context, job types.ScrapeJob) (string, error) {
+	job.JobID = fmt.Sprintf("job_%d", time.Now().UnixNano())
 
 	// Serialize the job
 	jobData, err := json.Marshal(job)
 	if err != nil {
-		return fmt.Errorf("failed to marshal scrape job: %w", err)
+		return "", fmt.Errorf("failed to marshal scrape job: %w", err)
+	}
+
+	// Record the job before sending it, so the worker always finds it
+	record := &types.ScrapeJobRecord{
+		JobID:     job.JobID,
+		Type:      job.Type,
+		URL:       job.URL,
+		Category:  job.Category,
+		Tags:      job.Tags,
+		Status:    types.JobQueued,
+		Crawl:     job.Crawl,
+		Sitemap:   job.Sitemap,
+		CreatedAt: time.Now(),
+	}
+	if err := s.jobStore.CreateScrapeJob(ctx, record); err != nil {
+		return "", err
 	}
 
 	// Send to Kafka topic
-	if err := s.kafkaProd.SendMessage("scrape-jobs", jobData); err != nil {
+	if err := s.kafkaProd.SendMessage(ctx, "scrape-jobs", jobData); err != nil {
+		if finishErr := s.jobStore.FinishScrapeJob(ctx, job.JobID, types.JobFailed, err.Error(), nil); finishErr != nil {
+			log.Printf("Failed to mark scrape job %s as failed: %v", job.JobID, finishErr)
+		}
+		return "", fmt.Errorf("failed to send scrape job to Kafka: %w", err)
+	}
+
+	return job.JobID, nil
+}
+
+// GetScrapeJob returns the state of a scrape job, with the progress of its
+// pages for a crawl or sitemap job.
+func (s *Service) GetScrapeJob(ctx context.Context, id string) (*types.ScrapeJobRecord, error) {
+	job, err := s.jobStore.GetScrapeJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+
+	if job.Type.HasPages() {
+		if job.Progress, err = s.jobStore.CrawlProgress(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	return job, nil
+}
+
+// ListScrapeJobs returns one page of scrape jobs, newest first.
+func (s *Service) ListScrapeJobs(ctx context.Context, opts types.ScrapeJobListOptions) (*types.ScrapeJobList, error) {
+	jobs, total, err := s.jobStore.ListScrapeJobs(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &types.ScrapeJobList{Jobs: jobs, Total: total, Limit: opts.Limit, Offset: opts.Offset}, nil
+}
+
+// ErrJobNotDeadLettered is returned when replaying a job that is not in the dead-letter topic.
+var ErrJobNotDeadLettered = errors.New("scrape job is not dead-lettered")
+
+// ReplayDeadLetter queues a dead-lettered job again with a fresh set of attempts.
+func (s *Service) ReplayDeadLetter(ctx context.Context, id string) error {
+	record, err := s.GetScrapeJob(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Resetting only dead-lettered jobs makes concurrent replays of one job send it once
+	requeued, err := s.jobStore.RequeueScrapeJob(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !requeued {
+		return ErrJobNotDeadLettered
+	}
+
+	jobData, err := json.Marshal(record.Job())
+	if err != nil {
+		return fmt.Errorf("failed to marshal scrape job: %w", err)
+	}
+
+	if err := s.kafkaProd.SendMessage(ctx, "scrape-jobs", jobData); err != nil {
+		if deadLetterErr := s.jobStore.DeadLetterScrapeJob(ctx, id, err.Error()); deadLetterErr != nil {
+			log.Printf("Failed to mark scrape job %s as dead-lettered: %v", id, deadLetterErr)
+		}
 		return fmt.Errorf("failed to send scrape job to Kafka: %w", err)
 	}
 
@@ -305,8 +732,17 @@ func (s *Service) ScrapeDocument(url, category string, tags []string) error {
 }
 
 // SearchDocuments searches for documents by text query with caching.
-func (s *Service) SearchDocuments(query string, limit int) ([]*types.Document, error) {
-	ctx := context.Background()
+// With a non-empty filter it runs a semantic search over the document chunks
+// instead, restricted to chunks whose payload matches the filter.
+func (s *Service) SearchDocuments(ctx context.Context, query string, limit int, filter *types.VectorFilter) ([]*types.Document, error) {
+	if !filter.IsEmpty() {
+		return s.searchDocumentsByVector(ctx, query, limit, filter)
+	}
+	return s.searchDocumentsByKeyword(ctx, query, limit)
+}
+
+// searchDocumentsByKeyword runs a cached full-text search over the documents.
+func (s *Service) searchDocumentsByKeyword(ctx context.Context, query string, limit int) ([]*types.Document, error) {
 	searchCache := s.cache.SearchCache()
 
 	// Try cache first
@@ -316,7 +752,7 @@ func (s *Service) SearchDocuments(query string, limit int) ([]*types.Document, e
 	}
 
 	// Cache miss, search database
-	docs, err = s.docStore.SearchDocuments(query, limit)
+	docs, err = s.docStore.SearchDocuments(ctx, query, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -329,9 +765,50 @@ func (s *Service) SearchDocuments(query string, limit int) ([]*types.Document, e
 	return docs, nil
 }
 
+// chunksPerDocument is how many chunk matches are fetched per requested
+// document, so that several chunks of one document do not crowd out others.
+const chunksPerDocument = 3
+
+// searchDocumentsByVector returns the documents of the chunks closest to query, best match first.
+func (s *Service) searchDocumentsByVector(ctx context.Context, query string, limit int, filter *types.VectorFilter) ([]*types.Document, error) {
+	queryVector, err := s.embedQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.vecClient.SearchVector(ctx, queryVector, limit*chunksPerDocument, types.SearchOptions{Filter: filter})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search vectors: %w", err)
+	}
+
+	docs := []*types.Document{}
+	seen := make(map[string]bool)
+	for _, result := range results {
+		docID, ok := result.Metadata["document_id"].(string)
+		if !ok || seen[docID] {
+			continue
+		}
+		seen[docID] = true
+
+		doc, err := s.getDocumentWithCache(ctx, docID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get document %s: %w", docID, err)
+		}
+		if doc == nil {
+			continue
+		}
+
+		docs = append(docs, doc)
+		if len(docs) == limit {
+			break
+		}
+	}
+
+	return docs, nil
+}
+
 // GetChatSession retrieves or creates a chat session
-func (s *Service) GetChatSession(sessionID string) (*types.ChatSession, error) {
-	ctx := context.Background()
+func (s *Service) GetChatSession(ctx context.Context, sessionID string) (*types.ChatSession, error) {
 	chatCache := s.cache.ChatCache()
 
 	session, err := chatCache.GetSession(ctx, sessionID)
@@ -354,8 +831,7 @@ func (s *Service) GetChatSession(sessionID string) (*types.ChatSession, error) {
 }
 
 // AddChatMessage adds a message to a chat session
-func (s *Service) AddChatMessage(sessionID string, role, content string) error {
-	ctx := context.Background()
+func (s *Service) AddChatMessage(ctx context.Context, sessionID string, role, content string) error {
 	chatCache := s.cache.ChatCache()
 
 	message := &types.ChatMessage{
@@ -369,37 +845,26 @@ func (s *Service) AddChatMessage(sessionID string, role, content string) error {
 }
 
 // GetChatHistory retrieves chat history for a session
-func (s *Service) GetChatHistory(sessionID string, limit int) ([]*types.ChatMessage, error) {
-	ctx := context.Background()
+func (s *Service) GetChatHistory(ctx context.Context, sessionID string, limit int) ([]*types.ChatMessage, error) {
 	chatCache := s.cache.ChatCache()
 
 	return chatCache.GetHistory(ctx, sessionID, limit)
 }
 
 // GenerateTutorialFromScrapedData generates a tutorial from scraped content
-func (s *Service) GenerateTutorialFromScrapedData(url, topic string) (string, error) {
+func (s *Service) GenerateTutorialFromScrapedData(ctx context.Context, url, topic string) (string, error) {
 	// Search for existing documents related to this topic
-	docs, err := s.docStore.SearchDocuments(topic, 10)
+	retrieved, err := s.retrieve(ctx, topic, 10, types.SearchOptions{})
 	if err != nil {
 		return "", fmt.Errorf("failed to search for existing documents: %w", err)
 	}
 
-	var tutorialContent []string
-	var contextDocs []string
-
-	// Collect content from existing documents
-	for _, doc := range docs {
-		if strings.Contains(strings.ToLower(doc.Title), strings.ToLower(topic)) ||
-			strings.Contains(strings.ToLower(doc.Category), strings.ToLower(topic)) {
-			tutorialContent = append(tutorialContent, doc.Content)
-			contextDocs = append(contextDocs, fmt.Sprintf("Title: %s\nContent: %s", doc.Title, doc.Content))
-		}
-	}
+	contextDocs := tutorialContext(retrieved)
 
 	// If no existing content, trigger scraping
-	if len(tutorialContent) == 0 {
+	if len(contextDocs) == 0 {
 		// Queue scraping job
-		if err := s.ScrapeDocument(url, topic, []string{"tutorial", "documentation"}); err != nil {
+		if _, err := s.ScrapeDocument(ctx, url, topic, []string{"tutorial", "documentation"}); err != nil {
 			return "", fmt.Errorf("failed to queue scraping job: %w", err)
 		}
 
@@ -414,7 +879,7 @@ func (s *Service) GenerateTutorialFromScrapedData(url, topic string) (string, er
 
 	tutorialPrompt := fmt.Sprintf("%sGenerate a comprehensive tutorial for: %s\n\nPlease create a well-structured tutorial in Markdown format based on the scraped documentation above. Structure your response as follows:\n\n# Complete Tutorial: %s\n\n## Overview\n[Provide a clear, concise overview of the topic]\n\n## Prerequisites\n[List any prerequisites or basic knowledge needed]\n\n## Step-by-Step Guide\n\n### Step 1: [First Step]\n[Detailed explanation with examples]\n\n### Step 2: [Second Step]\n[Detailed explanation with examples]\n\n### Step 3: [Third Step]\n[Detailed explanation with examples]\n\n## Code Examples\n\n### Basic Example\n```[language]\n[Provide practical code examples]\n```\n\n### Advanced Example\n```[language]\n[More complex examples]\n```\n\n## Best Practices\n- [Best practice 1]\n- [Best practice 2]\n- [Best practice 3]\n\n## Common Pitfalls to Avoid\n- [Pitfall 1]\n- [Pitfall 2]\n\n## Summary\n[Brief summary of what was covered]\n\n## Next Steps\n[Suggest what to learn next]\n\nMake the tutorial comprehensive yet easy to follow, with practical examples and clear explanations. Use proper Markdown formatting.", context, topic, topic)
 
-	response, err := s.embClient.Chat(tutorialPrompt)
+	response, err := s.embClient.Chat(ctx, tutorialPrompt, types.GenerationOptions{})
 	if err != nil {
 		return "", fmt.Errorf("failed to generate tutorial: %w", err)
 	}
@@ -422,33 +887,32 @@ func (s *Service) GenerateTutorialFromScrapedData(url, topic string) (string, er
 	return response, nil
 }
 
+// tutorialContext formats the matching excerpts of retrieved documents as tutorial prompt context.
+func tutorialContext(retrieved []retrievedDoc) []string {
+	contextDocs := make([]string, len(retrieved))
+	for i, r := range retrieved {
+		contextDocs[i] = fmt.Sprintf("Title: %s\nContent: %s", r.doc.Title, strings.Join(r.excerpts, "\n\n...\n\n"))
+	}
+	return contextDocs
+}
+
 // ScrapeAndGenerateTutorial scrapes content and immediately generates a tutorial
-func (s *Service) ScrapeAndGenerateTutorial(url, topic string) (string, error) {
+func (s *Service) ScrapeAndGenerateTutorial(ctx context.Context, url, topic string) (string, error) {
 	// First, try to get existing content
-	docs, err := s.docStore.SearchDocuments(topic, 5)
+	retrieved, err := s.retrieve(ctx, topic, 5, types.SearchOptions{})
 	if err != nil {
 		return "", fmt.Errorf("failed to search documents: %w", err)
 	}
 
-	var tutorialContent []string
-	var contextDocs []string
-
-	// Collect relevant content
-	for _, doc := range docs {
-		if strings.Contains(strings.ToLower(doc.Title), strings.ToLower(topic)) ||
-			strings.Contains(strings.ToLower(doc.Category), strings.ToLower(topic)) {
-			tutorialContent = append(tutorialContent, doc.Content)
-			contextDocs = append(contextDocs, fmt.Sprintf("Title: %s\nContent: %s", doc.Title, doc.Content))
-		}
-	}
+	contextDocs := tutorialContext(retrieved)
 
 	// If we have content, generate tutorial immediately
-	if len(tutorialContent) > 0 {
+	if len(contextDocs) > 0 {
 		context := "Based on the following scraped documentation:\n\n" + strings.Join(contextDocs, "\n\n") + "\n\n"
 
 		tutorialPrompt := fmt.Sprintf("%sGenerate a quick tutorial for: %s\n\nPlease create a concise tutorial in Markdown format based on the scraped documentation above. Structure your response as follows:\n\n# Quick Tutorial: %s\n\n## What is %s?\n[Brief explanation]\n\n## Key Concepts:\n- [Concept 1]\n- [Concept 2]\n- [Concept 3]\n\n## Basic Example:\n```[language]\n[Simple, practical example]\n```\n\n## Common Use Cases:\n- [Use case 1]\n- [Use case 2]\n\n## Tips:\n- [Tip 1]\n- [Tip 2]\n\nKeep it concise and practical for beginners. Use proper Markdown formatting.", context, topic, topic, topic)
 
-		response, err := s.embClient.Chat(tutorialPrompt)
+		response, err := s.embClient.Chat(ctx, tutorialPrompt, types.GenerationOptions{})
 		if err != nil {
 			return "", fmt.Errorf("failed to generate tutorial: %w", err)
 		}
@@ -457,7 +921,7 @@ func (s *Service) ScrapeAndGenerateTutorial(url, topic string) (string, error) {
 	}
 
 	// If no content exists, queue scraping and return a message
-	if err := s.ScrapeDocument(url, topic, []string{"tutorial", "documentation"}); err != nil {
+	if _, err := s.ScrapeDocument(ctx, url, topic, []string{"tutorial", "documentation"}); err != nil {
 		return "", fmt.Errorf("failed to queue scraping job: %w", err)
 	}
 
@@ -465,100 +929,108 @@ func (s *Service) ScrapeAndGenerateTutorial(url, topic string) (string, error) {
 }
 
 // ChatWithHistory handles chat with conversation history
-func (s *Service) ChatWithHistory(sessionID, message string) (string, error) {
-	ctx := context.Background()
+func (s *Service) ChatWithHistory(ctx context.Context, sessionID, message string, opts ChatOptions) (*ChatResult, error) {
+	return s.chatWithHistory(ctx, sessionID, message, opts, nil)
+}
+
+// ChatWithHistoryStream runs the same pipeline as ChatWithHistory but streams the answer through onDelta.
+// Cancelling ctx aborts generation.
+func (s *Service) ChatWithHistoryStream(ctx context.Context, sessionID, message string, opts ChatOptions, onDelta func(delta string) error) (*ChatResult, error) {
+	return s.chatWithHistory(ctx, sessionID, message, opts, onDelta)
+}
 
+// chatWithHistory implements ChatWithHistory and ChatWithHistoryStream.
+func (s *Service) chatWithHistory(ctx context.Context, sessionID, message string, opts ChatOptions, onDelta func(delta string) error) (*ChatResult, error) {
 	// Get or create chat session
-	_, err := s.GetChatSession(sessionID)
+	_, err := s.GetChatSession(ctx, sessionID)
 	if err != nil {
-		return "", fmt.Errorf("failed to get chat session: %w", err)
+		return nil, fmt.Errorf("failed to get chat session: %w", err)
 	}
 
 	// Get conversation history
-	history, err := s.GetChatHistory(sessionID, 10) // Last 10 messages
+	history, err := s.GetChatHistory(ctx, sessionID, 10) // Last 10 messages
 	if err != nil {
-		return "", fmt.Errorf("failed to get chat history: %w", err)
-	}
-
-	// Build conversation context
-	var conversationContext strings.Builder
-	if len(history) > 0 {
-		conversationContext.WriteString("Previous conversation:\n")
-		for _, msg := range history {
-			conversationContext.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
-		}
-		conversationContext.WriteString("\n")
+		return nil, fmt.Errorf("failed to get chat history: %w", err)
 	}
 
-	// Search for relevant content in vector database
-	queryVector, err := s.embClient.Embed(message)
+	// Find relevant documents by keyword and vector search
+	retrieved, err := s.retrieve(ctx, message, contextSearchLimit, opts.searchOptions())
 	if err != nil {
-		return "", fmt.Errorf("failed to embed query: %w", err)
-	}
-
-	searchResults, err := s.vecClient.SearchVector(queryVector, 5)
-	if err != nil {
-		return "", fmt.Errorf("failed to search vectors: %w", err)
-	}
-
-	// Retrieve relevant documents
-	var contextDocs []string
-	var hasRelevantContent bool
-
-	for _, result := range searchResults {
-		if docID, ok := result.Metadata["document_id"].(string); ok {
-			doc, err := s.getDocumentWithCache(ctx, docID)
-			if err == nil && doc != nil {
-				if result.Score > 0.7 {
-					contextDocs = append(contextDocs, fmt.Sprintf("Title: %s\nContent: %s", doc.Title, doc.Content))
-					hasRelevantContent = true
-				}
-			}
-		}
-	}
-
-	// Build comprehensive prompt with history and context
-	var prompt strings.Builder
-	prompt.WriteString(conversationContext.String())
-
-	if hasRelevantContent && len(contextDocs) > 0 {
-		prompt.WriteString("Based on the following relevant documentation:\n\n")
-		prompt.WriteString(strings.Join(contextDocs, "\n\n"))
-		prompt.WriteString("\n\n")
+		return nil, err
 	}
 
-	prompt.WriteString(fmt.Sprintf("Current user question: %s\n\n", message))
+	contextDocs, sources := citedContext(retrieved)
+	hasRelevantContent := len(contextDocs) > 0
 
-	if hasRelevantContent {
-		prompt.WriteString("Please generate a short, focused tutorial in Markdown format based on the documentation above. Consider the conversation history for context. Structure your response as follows:\n\n# Quick Tutorial: [Topic Name]\n\n## What is [Topic]?\n[Brief 1-2 sentence explanation]\n\n## Key Concepts:\n- [Concept 1]\n- [Concept 2]\n- [Concept 3]\n\n## Basic Example:\n```[language]\n[Provide a simple, practical example]\n```\n\n## Common Use Cases:\n- [Use case 1]\n- [Use case 2]\n\n## Tips:\n- [Tip 1]\n- [Tip 2]\n\nKeep the tutorial concise, practical, and beginner-friendly. Use proper Markdown formatting.")
-	} else {
-		prompt.WriteString("Please provide a helpful and informative tutorial in Markdown format about this topic. Consider the conversation history for context. If you don't have specific information, provide general guidance and suggest where they might find more detailed information.\n\nStructure your response as a well-formatted Markdown tutorial with proper headers, code blocks, and bullet points.")
-	}
+	// Send the retrieved context as the system prompt, followed by the conversation so far
+	messages := []types.LLMMessage{{Role: types.RoleSystem, Content: historySystemPrompt(contextDocs)}}
+	messages = append(messages, historyMessages(history)...)
+	messages = append(messages, types.LLMMessage{Role: types.RoleUser, Content: message})
 
-	response, err := s.embClient.Chat(prompt.String())
+	response, err := s.generateMessages(ctx, messages, opts.Generation, onDelta)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate response: %w", err)
+		return nil, fmt.Errorf("failed to generate response: %w", err)
 	}
 
 	// Store user message in history
-	if err := s.AddChatMessage(sessionID, "user", message); err != nil {
+	if err := s.AddChatMessage(ctx, sessionID, types.RoleUser, message); err != nil {
 		log.Printf("Failed to store user message: %v", err)
 	}
 
 	// Store AI response in history
-	if err := s.AddChatMessage(sessionID, "assistant", response); err != nil {
+	if err := s.AddChatMessage(ctx, sessionID, types.RoleAssistant, response); err != nil {
 		log.Printf("Failed to store AI response: %v", err)
 	}
 
 	// Store the response in vector database for learning
-	go s.storeResponseForLearning(message, response, hasRelevantContent)
+	go s.storeResponseForLearning(ctx, message, response, hasRelevantContent)
 
-	return response, nil
+	return &ChatResult{Response: response, Sources: sources}, nil
+}
+
+// generateMessages sends a conversation to the LLM, streaming through onDelta when it is set.
+func (s *Service) generateMessages(ctx context.Context, messages []types.LLMMessage, opts types.GenerationOptions, onDelta func(delta string) error) (string, error) {
+	if onDelta == nil {
+		return s.embClient.ChatMessages(ctx, messages, opts)
+	}
+	return s.embClient.ChatMessagesStream(ctx, messages, opts, onDelta)
+}
+
+// historySystemPrompt builds the system prompt for a conversation, holding the
+// numbered documentation retrieved for the latest question.
+func historySystemPrompt(contextDocs []string) string {
+	var prompt strings.Builder
+	prompt.WriteString("You are a technical documentation assistant. Answer the user's latest question, using the earlier conversation for context.\n\n")
+
+	if len(contextDocs) > 0 {
+		prompt.WriteString("Relevant documentation:\n\n")
+		prompt.WriteString(strings.Join(contextDocs, "\n\n"))
+		prompt.WriteString("\n\n")
+		prompt.WriteString(citationInstruction + "\n\n")
+		prompt.WriteString("Reply with a short, focused tutorial in Markdown format based on the documentation above. Structure your response as follows:\n\n# Quick Tutorial: [Topic Name]\n\n## What is [Topic]?\n[Brief 1-2 sentence explanation]\n\n## Key Concepts:\n- [Concept 1]\n- [Concept 2]\n- [Concept 3]\n\n## Basic Example:\n```[language]\n[Provide a simple, practical example]\n```\n\n## Common Use Cases:\n- [Use case 1]\n- [Use case 2]\n\n## Tips:\n- [Tip 1]\n- [Tip 2]\n\nKeep the tutorial concise, practical, and beginner-friendly. Use proper Markdown formatting.")
+	} else {
+		prompt.WriteString("Reply with a helpful and informative tutorial in Markdown format about the topic. If you don't have specific information, provide general guidance and suggest where more detailed information can be found.\n\nStructure your response as a well-formatted Markdown tutorial with proper headers, code blocks, and bullet points.")
+	}
+
+	return prompt.String()
+}
+
+// historyMessages converts stored chat history into LLM messages, dropping
+// anything that is not a user or assistant turn.
+func historyMessages(history []*types.ChatMessage) []types.LLMMessage {
+	messages := make([]types.LLMMessage, 0, len(history))
+	for _, msg := range history {
+		if msg.Role != types.RoleUser && msg.Role != types.RoleAssistant {
+			continue
+		}
+		messages = append(messages, types.LLMMessage{Role: msg.Role, Content: msg.Content})
+	}
+	return messages
 }
 
 // GetConversationInsights analyzes conversation history for insights
-func (s *Service) GetConversationInsights(sessionID string) (map[string]interface{}, error) {
-	history, err := s.GetChatHistory(sessionID, 50) // Get more history for analysis
+func (s *Service) GetConversationInsights(ctx context.Context, sessionID string) (map[string]interface{}, error) {
+	history, err := s.GetChatHistory(ctx, sessionID, 50) // Get more history for analysis
 	if err != nil {
 		return nil, fmt.Errorf("failed to get conversation history: %w", err)
 	}
@@ -0,0 +1,601 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"tech-docs-ai/internal/cache"
+	"tech-docs-ai/internal/chunk"
+	"tech-docs-ai/internal/types"
+	"tech-docs-ai/internal/vec"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryMessages(t *testing.T) {
+	history := []*types.ChatMessage{
+		{Role: "user", Content: "What is Go?"},
+		{Role: "assistant", Content: "A programming language."},
+		{Role: "system", Content: "ignored"},
+		{Role: "user", Content: "Who made it?"},
+	}
+
+	messages := historyMessages(history)
+
+	assert.Equal(t, []types.LLMMessage{
+		{Role: types.RoleUser, Content: "What is Go?"},
+		{Role: types.RoleAssistant, Content: "A programming language."},
+		{Role: types.RoleUser, Content: "Who made it?"},
+	}, messages)
+}
+
+func TestHistorySystemPrompt_WithContext(t *testing.T) {
+	prompt := historySystemPrompt([]string{"[1] Title: Goroutines\nContent: Lightweight threads."})
+
+	assert.Contains(t, prompt, "[1] Title: Goroutines")
+	assert.Contains(t, prompt, citationInstruction)
+}
+
+func TestHistorySystemPrompt_WithoutContext(t *testing.T) {
+	prompt := historySystemPrompt(nil)
+
+	assert.NotContains(t, prompt, "Relevant documentation")
+	assert.NotContains(t, prompt, citationInstruction)
+}
+
+type mockDocStore struct {
+	mock.Mock
+}
+
+func (m *mockDocStore) StoreDocument(ctx context.Context, doc *types.Document) error {
+	args := m.Called(ctx, doc)
+	return args.Error(0)
+}
+
+func (m *mockDocStore) GetDocument(ctx context.Context, id string) (*types.Document, error) {
+	args := m.Called(ctx, id)
+	doc, _ := args.Get(0).(*types.Document)
+	return doc, args.Error(1)
+}
+
+func (m *mockDocStore) ListDocuments(ctx context.Context, opts types.DocumentListOptions) ([]*types.Document, int, error) {
+	args := m.Called(ctx, opts)
+	docs, _ := args.Get(0).([]*types.Document)
+	return docs, args.Int(1), args.Error(2)
+}
+
+func (m *mockDocStore) DeleteDocument(ctx context.Context, id string) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockDocStore) SearchDocuments(ctx context.Context, query string, limit int) ([]*types.Document, error) {
+	args := m.Called(ctx, query, limit)
+	docs, _ := args.Get(0).([]*types.Document)
+	return docs, args.Error(1)
+}
+
+// indexingVectorStore is a memory store that also stores documents together with their vectors.
+type indexingVectorStore struct {
+	*vec.MemoryStore
+	docs map[string]*types.Document
+}
+
+func (s *indexingVectorStore) StoreDocumentWithVectors(ctx context.Context, doc *types.Document, points []types.VectorPoint) error {
+	s.docs[doc.ID] = doc
+	if err := s.DeleteByDocumentID(ctx, doc.ID); err != nil {
+		return err
+	}
+	return s.StoreVectors(ctx, points)
+}
+
+func (s *indexingVectorStore) DeleteDocumentWithVectors(ctx context.Context, documentID string) (bool, error) {
+	_, ok := s.docs[documentID]
+	delete(s.docs, documentID)
+	return ok, s.DeleteByDocumentID(ctx, documentID)
+}
+
+func testEmbeddedChunks() []embeddedChunk {
+	return []embeddedChunk{
+		{chunk: chunk.Chunk{Index: 0, Content: "Goroutines are lightweight threads."}, vector: []float32{1, 0}},
+		{chunk: chunk.Chunk{Index: 1, Content: "Channels connect goroutines."}, vector: []float32{0, 1}},
+	}
+}
+
+func TestService_IndexDocument(t *testing.T) {
+	ctx := context.Background()
+	docStore := new(mockDocStore)
+	docStore.On("StoreDocument", mock.Anything, mock.Anything).Return(nil)
+	store := vec.NewMemoryStore(vec.DistanceCosine)
+
+	s := &Service{docStore: docStore, vecClient: store}
+	doc := &types.Document{Title: "Goroutines", Category: "Go"}
+
+	require.NoError(t, s.indexDocument(ctx, doc, testEmbeddedChunks(), map[string]interface{}{"category": "Go"}))
+	require.NotEmpty(t, doc.ID)
+	docStore.AssertCalled(t, "StoreDocument", mock.Anything, doc)
+
+	// Re-indexing a shorter revision leaves no stale chunks
+	require.NoError(t, s.indexDocument(ctx, doc, testEmbeddedChunks()[:1], map[string]interface{}{"category": "Go"}))
+
+	results, err := store.SearchVector(ctx, []float32{1, 1}, 10, types.SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, vec.PointID(doc.ID, 0), results[0].ID)
+	assert.Equal(t, doc.ID, results[0].Metadata["document_id"])
+	assert.Equal(t, "Go", results[0].Metadata["category"])
+}
+
+func TestService_IndexDocument_Transactional(t *testing.T) {
+	ctx := context.Background()
+	docStore := new(mockDocStore)
+	store := &indexingVectorStore{MemoryStore: vec.NewMemoryStore(vec.DistanceCosine), docs: map[string]*types.Document{}}
+
+	s := &Service{docStore: docStore, vecClient: store}
+	doc := &types.Document{ID: "doc_1", Title: "Goroutines"}
+
+	require.NoError(t, s.indexDocument(ctx, doc, testEmbeddedChunks(), map[string]interface{}{}))
+
+	// The document went through the vector store, not the document store
+	assert.Same(t, doc, store.docs["doc_1"])
+	docStore.AssertNotCalled(t, "StoreDocument", mock.Anything, mock.Anything)
+
+	results, err := store.SearchVector(ctx, []float32{1, 1}, 10, types.SearchOptions{})
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+}
+
+func TestDocumentPatch_Apply(t *testing.T) {
+	doc := &types.Document{ID: "doc_1", Title: "Goroutines", Content: "Old.", Category: "Go", Tags: []string{"a"}, Metadata: map[string]string{"url": "x"}}
+	title, tags := "Channels", []string{"concurrency"}
+
+	types.DocumentPatch{Title: &title, Tags: &tags}.Apply(doc)
+
+	assert.Equal(t, &types.Document{ID: "doc_1", Title: "Channels", Content: "Old.", Category: "Go", Tags: tags, Metadata: map[string]string{"url": "x"}}, doc)
+}
+
+func TestDocumentPayload(t *testing.T) {
+	doc := &types.Document{Title: "HTML Intro", Category: "HTML", Tags: []string{"basics"}, Author: "W3Schools", Metadata: map[string]string{"url": "https://www.w3schools.com/html/"}}
+	assert.Equal(t, map[string]interface{}{
+		"title":    "HTML Intro",
+		"category": "HTML",
+		"tags":     []string{"basics"},
+		"author":   "W3Schools",
+		"source":   "w3schools",
+	}, documentPayload(doc))
+
+	assert.Equal(t, "ai-response", documentPayload(&types.Document{Category: "AI_Response"})["source"])
+	assert.Equal(t, "universal", documentPayload(&types.Document{Metadata: map[string]string{"url": "https://go.dev/doc"}})["source"])
+	assert.NotContains(t, documentPayload(&types.Document{Title: "Manual"}), "source")
+}
+
+type mockJobStore struct {
+	mock.Mock
+}
+
+func (m *mockJobStore) CreateScrapeJob(ctx context.Context, job *types.ScrapeJobRecord) error {
+	args := m.Called(ctx, job)
+	return args.Error(0)
+}
+
+func (m *mockJobStore) FinishScrapeJob(ctx context.Context, jobID string, status types.JobStatus, errMsg string, documentIDs []string) error {
+	args := m.Called(ctx, jobID, status, errMsg, documentIDs)
+	return args.Error(0)
+}
+
+func (m *mockJobStore) GetScrapeJob(ctx context.Context, id string) (*types.ScrapeJobRecord, error) {
+	args := m.Called(ctx, id)
+	job, _ := args.Get(0).(*types.ScrapeJobRecord)
+	return job, args.Error(1)
+}
+
+func (m *mockJobStore) ListScrapeJobs(ctx context.Context, opts types.ScrapeJobListOptions) ([]*types.ScrapeJobRecord, int, error) {
+	args := m.Called(ctx, opts)
+	jobs, _ := args.Get(0).([]*types.ScrapeJobRecord)
+	return jobs, args.Int(1), args.Error(2)
+}
+
+func (m *mockJobStore) RequeueScrapeJob(ctx context.Context, jobID string) (bool, error) {
+	args := m.Called(ctx, jobID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockJobStore) DeadLetterScrapeJob(ctx context.Context, jobID string, errMsg string) error {
+	args := m.Called(ctx, jobID, errMsg)
+	return args.Error(0)
+}
+
+func (m *mockJobStore) CrawlProgress(ctx context.Context, crawlID string) (*types.CrawlProgress, error) {
+	args := m.Called(ctx, crawlID)
+	progress, _ := args.Get(0).(*types.CrawlProgress)
+	return progress, args.Error(1)
+}
+
+type mockKafkaProducer struct {
+	mock.Mock
+}
+
+func (m *mockKafkaProducer) SendMessage(ctx context.Context, topic string, message []byte) error {
+	args := m.Called(ctx, topic, message)
+	return args.Error(0)
+}
+
+func TestService_ScrapeDocument(t *testing.T) {
+	ctx := context.Background()
+	jobs := new(mockJobStore)
+	producer := new(mockKafkaProducer)
+	s := &Service{jobStore: jobs, kafkaProd: producer}
+
+	var recorded *types.ScrapeJobRecord
+	jobs.On("CreateScrapeJob", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		recorded = args.Get(1).(*types.ScrapeJobRecord)
+	}).Return(nil)
+	producer.On("SendMessage", mock.Anything, "scrape-jobs", mock.Anything).Return(nil)
+
+	jobID, err := s.ScrapeDocument(ctx, "https://example.com", "Go", []string{"go"})
+	require.NoError(t, err)
+	require.NotNil(t, recorded)
+	assert.Equal(t, jobID, recorded.JobID)
+	assert.Equal(t, types.JobQueued, recorded.Status)
+	jobs.AssertNotCalled(t, "FinishScrapeJob", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_ScrapeDocument_SendFailure(t *testing.T) {
+	ctx := context.Background()
+	jobs := new(mockJobStore)
+	producer := new(mockKafkaProducer)
+	s := &Service{jobStore: jobs, kafkaProd: producer}
+
+	jobs.On("CreateScrapeJob", mock.Anything, mock.Anything).Return(nil)
+	producer.On("SendMessage", mock.Anything, "scrape-jobs", mock.Anything).Return(assert.AnError)
+	jobs.On("FinishScrapeJob", mock.Anything, mock.Anything, types.JobFailed, assert.AnError.Error(), []string(nil)).Return(nil)
+
+	_, err := s.ScrapeDocument(ctx, "https://example.com", "Go", nil)
+	require.Error(t, err)
+	jobs.AssertExpectations(t)
+}
+
+func TestService_GetScrapeJob_NotFound(t *testing.T) {
+	jobs := new(mockJobStore)
+	jobs.On("GetScrapeJob", mock.Anything, "missing").Return(nil, nil)
+	s := &Service{jobStore: jobs}
+
+	_, err := s.GetScrapeJob(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestService_ReplayDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	deadLetteredAt := time.Now()
+	jobs := new(mockJobStore)
+	producer := new(mockKafkaProducer)
+	s := &Service{jobStore: jobs, kafkaProd: producer}
+
+	jobs.On("GetScrapeJob", mock.Anything, "job_1").Return(&types.ScrapeJobRecord{
+		JobID:          "job_1",
+		URL:            "https://example.com",
+		Category:       "Go",
+		Status:         types.JobFailed,
+		DeadLetteredAt: &deadLetteredAt,
+	}, nil)
+	jobs.On("RequeueScrapeJob", mock.Anything, "job_1").Return(true, nil).Once()
+	producer.On("SendMessage", mock.Anything, "scrape-jobs", mock.Anything).Return(nil)
+
+	require.NoError(t, s.ReplayDeadLetter(ctx, "job_1"))
+
+	var sent types.ScrapeJob
+	require.NoError(t, json.Unmarshal(producer.Calls[0].Arguments.Get(2).([]byte), &sent))
+	assert.Equal(t, types.ScrapeJob{URL: "https://example.com", Category: "Go", JobID: "job_1"}, sent)
+
+	// A second replay finds the job already requeued
+	jobs.On("RequeueScrapeJob", mock.Anything, "job_1").Return(false, nil)
+	assert.ErrorIs(t, s.ReplayDeadLetter(ctx, "job_1"), ErrJobNotDeadLettered)
+	producer.AssertNumberOfCalls(t, "SendMessage", 1)
+}
+
+func TestService_ReplayDeadLetter_SendFailure(t *testing.T) {
+	ctx := context.Background()
+	jobs := new(mockJobStore)
+	producer := new(mockKafkaProducer)
+	s := &Service{jobStore: jobs, kafkaProd: producer}
+
+	jobs.On("GetScrapeJob", mock.Anything, "job_1").Return(&types.ScrapeJobRecord{JobID: "job_1"}, nil)
+	jobs.On("RequeueScrapeJob", mock.Anything, "job_1").Return(true, nil)
+	producer.On("SendMessage", mock.Anything, "scrape-jobs", mock.Anything).Return(assert.AnError)
+	jobs.On("DeadLetterScrapeJob", mock.Anything, "job_1", assert.AnError.Error()).Return(nil)
+
+	require.Error(t, s.ReplayDeadLetter(ctx, "job_1"))
+	jobs.AssertExpectations(t)
+}
+
+func TestService_CrawlSite(t *testing.T) {
+	ctx := context.Background()
+	jobs := new(mockJobStore)
+	producer := new(mockKafkaProducer)
+	s := &Service{jobStore: jobs, kafkaProd: producer}
+
+	jobs.On("CreateScrapeJob", mock.Anything, mock.Anything).Return(nil)
+	producer.On("SendMessage", mock.Anything, "scrape-jobs", mock.Anything).Return(nil)
+
+	opts := types.CrawlOptions{MaxDepth: 2, MaxPages: 10, SameDomain: true}
+	jobID, err := s.CrawlSite(ctx, "https://go.dev/doc/", "Go", nil, opts)
+	require.NoError(t, err)
+
+	record := jobs.Calls[0].Arguments.Get(1).(*types.ScrapeJobRecord)
+	assert.Equal(t, types.JobTypeCrawl, record.Type)
+	assert.Equal(t, &opts, record.Crawl)
+
+	var sent types.ScrapeJob
+	require.NoError(t, json.Unmarshal(producer.Calls[0].Arguments.Get(2).([]byte), &sent))
+	assert.Equal(t, jobID, sent.JobID)
+	assert.Equal(t, types.JobTypeCrawl, sent.Type)
+	assert.Equal(t, &opts, sent.Crawl)
+}
+
+func TestService_IngestSitemap(t *testing.T) {
+	ctx := context.Background()
+	jobs := new(mockJobStore)
+	producer := new(mockKafkaProducer)
+	s := &Service{jobStore: jobs, kafkaProd: producer}
+
+	jobs.On("CreateScrapeJob", mock.Anything, mock.Anything).Return(nil)
+	producer.On("SendMessage", mock.Anything, "scrape-jobs", mock.Anything).Return(nil)
+
+	opts := types.SitemapOptions{Include: []string{"^/doc/"}, MaxPages: 20}
+	jobID, err := s.IngestSitemap(ctx, "https://go.dev/sitemap.xml", "Go", nil, opts)
+	require.NoError(t, err)
+
+	record := jobs.Calls[0].Arguments.Get(1).(*types.ScrapeJobRecord)
+	assert.Equal(t, types.JobTypeSitemap, record.Type)
+	assert.Equal(t, &opts, record.Sitemap)
+
+	var sent types.ScrapeJob
+	require.NoError(t, json.Unmarshal(producer.Calls[0].Arguments.Get(2).([]byte), &sent))
+	assert.Equal(t, jobID, sent.JobID)
+	assert.Equal(t, types.JobTypeSitemap, sent.Type)
+	assert.Equal(t, &opts, sent.Sitemap)
+}
+
+func TestService_GetScrapeJob_CrawlProgress(t *testing.T) {
+	jobs := new(mockJobStore)
+	progress := &types.CrawlProgress{Pages: 3, Succeeded: 2, Running: 1}
+	jobs.On("GetScrapeJob", mock.Anything, "job_1").Return(&types.ScrapeJobRecord{JobID: "job_1", Type: types.JobTypeCrawl}, nil)
+	jobs.On("CrawlProgress", mock.Anything, "job_1").Return(progress, nil)
+	s := &Service{jobStore: jobs}
+
+	job, err := s.GetScrapeJob(context.Background(), "job_1")
+	require.NoError(t, err)
+	assert.Equal(t, progress, job.Progress)
+}
+
+type mockEmbClient struct {
+	mock.Mock
+}
+
+func (m *mockEmbClient) Embed(ctx context.Context, text string) ([]float32, error) {
+	args := m.Called(ctx, text)
+	vector, _ := args.Get(0).([]float32)
+	return vector, args.Error(1)
+}
+
+func (m *mockEmbClient) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	args := m.Called(ctx, texts)
+	vectors, _ := args.Get(0).([][]float32)
+	return vectors, args.Error(1)
+}
+
+func (m *mockEmbClient) Chat(ctx context.Context, message string, opts types.GenerationOptions) (string, error) {
+	args := m.Called(ctx, message, opts)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockEmbClient) ChatStream(ctx context.Context, message string, opts types.GenerationOptions, onToken func(token string) error) (string, error) {
+	args := m.Called(ctx, message, opts, onToken)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockEmbClient) ChatMessages(ctx context.Context, messages []types.LLMMessage, opts types.GenerationOptions) (string, error) {
+	args := m.Called(ctx, messages, opts)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockEmbClient) ChatMessagesStream(ctx context.Context, messages []types.LLMMessage, opts types.GenerationOptions, onToken func(token string) error) (string, error) {
+	args := m.Called(ctx, messages, opts, onToken)
+	return args.String(0), args.Error(1)
+}
+
+type mockVecClient struct {
+	mock.Mock
+}
+
+func (m *mockVecClient) StoreVectors(ctx context.Context, points []types.VectorPoint) error {
+	args := m.Called(ctx, points)
+	return args.Error(0)
+}
+
+func (m *mockVecClient) DeleteByDocumentID(ctx context.Context, documentID string) error {
+	args := m.Called(ctx, documentID)
+	return args.Error(0)
+}
+
+func (m *mockVecClient) DeleteStaleChunks(ctx context.Context, documentID string, chunkCount int) error {
+	args := m.Called(ctx, documentID, chunkCount)
+	return args.Error(0)
+}
+
+func (m *mockVecClient) SearchVector(ctx context.Context, vector []float32, limit int, opts types.SearchOptions) ([]types.SearchResult, error) {
+	args := m.Called(ctx, vector, limit, opts)
+	results, _ := args.Get(0).([]types.SearchResult)
+	return results, args.Error(1)
+}
+
+// newTestService returns a Service whose Redis cache cannot be reached, so
+// every cache lookup misses and every write is dropped.
+func newTestService(embClient embClient, vecClient vecClient, docStore docStore) *Service {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	return &Service{
+		embClient: embClient,
+		vecClient: vecClient,
+		docStore:  docStore,
+		cache:     cache.NewRedisCacheWithClient(client),
+		splitter:  chunk.NewSplitter(),
+		retrieval: retrievalConfig{vectorWeight: 1, k: 60},
+	}
+}
+
+func TestService_Chat(t *testing.T) {
+	embedding := []float32{0.1, 0.2, 0.3}
+	doc := &types.Document{ID: "doc_1", Title: "Test Doc", Content: "Test content"}
+
+	tests := []struct {
+		name           string
+		embeddingError error
+		searchError    error
+		chatError      error
+		expectedError  bool
+	}{
+		{name: "successful chat"},
+		{name: "embedding error", embeddingError: assert.AnError, expectedError: true},
+		{name: "search error", searchError: assert.AnError, expectedError: true},
+		{name: "generation error", chatError: assert.AnError, expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			embClient := new(mockEmbClient)
+			vecClient := new(mockVecClient)
+			docStore := new(mockDocStore)
+
+			embClient.On("Embed", mock.Anything, "Hello").Return(embedding, tt.embeddingError)
+			vecClient.On("SearchVector", mock.Anything, embedding, contextSearchLimit, types.SearchOptions{}).
+				Return([]types.SearchResult{{ID: "doc_1_0", Score: 0.9, Metadata: map[string]interface{}{"document_id": "doc_1"}}}, tt.searchError).Maybe()
+			docStore.On("GetDocument", mock.Anything, "doc_1").Return(doc, nil).Maybe()
+			var prompt string
+			embClient.On("Chat", mock.Anything, mock.Anything, types.GenerationOptions{}).Run(func(args mock.Arguments) {
+				prompt = args.String(1)
+			}).Return("Response based on: Test Doc", tt.chatError).Maybe()
+
+			// Answers are stored for learning in the background
+			embClient.On("EmbedBatch", mock.Anything, mock.Anything).Return([][]float32{embedding}, nil).Maybe()
+			docStore.On("StoreDocument", mock.Anything, mock.Anything).Return(nil).Maybe()
+			vecClient.On("StoreVectors", mock.Anything, mock.Anything).Return(nil).Maybe()
+			vecClient.On("DeleteStaleChunks", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			s := newTestService(embClient, vecClient, docStore)
+			result, err := s.Chat(context.Background(), "Hello", ChatOptions{})
+
+			if tt.expectedError {
+				assert.ErrorIs(t, err, assert.AnError)
+				assert.Nil(t, result)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "Response based on: Test Doc", result.Response)
+			require.Len(t, result.Sources, 1)
+			assert.Equal(t, "doc_1", result.Sources[0].DocumentID)
+			assert.Contains(t, prompt, "[1] Title: Test Doc\nContent: Test content")
+		})
+	}
+}
+
+func TestService_AddDocument(t *testing.T) {
+	embedding := []float32{0.1, 0.2, 0.3}
+
+	tests := []struct {
+		name           string
+		embeddingError error
+		storeError     error
+		vectorError    error
+		expectedError  bool
+	}{
+		{name: "successful add"},
+		{name: "embedding error", embeddingError: assert.AnError, expectedError: true},
+		{name: "store error", storeError: assert.AnError, expectedError: true},
+		{name: "vector error", vectorError: assert.AnError, expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			embClient := new(mockEmbClient)
+			vecClient := new(mockVecClient)
+			docStore := new(mockDocStore)
+			doc := &types.Document{Title: "Test Doc", Content: "Test content"}
+
+			embClient.On("EmbedBatch", mock.Anything, []string{"Test Doc\n\nTest content"}).Return([][]float32{embedding}, tt.embeddingError)
+			docStore.On("StoreDocument", mock.Anything, doc).Return(tt.storeError).Maybe()
+			vecClient.On("StoreVectors", mock.Anything, mock.Anything).Return(tt.vectorError).Maybe()
+			vecClient.On("DeleteStaleChunks", mock.Anything, mock.Anything, 1).Return(nil).Maybe()
+
+			s := newTestService(embClient, vecClient, docStore)
+			err := s.AddDocument(context.Background(), doc)
+
+			if tt.expectedError {
+				assert.ErrorIs(t, err, assert.AnError)
+				return
+			}
+			require.NoError(t, err)
+			require.NotEmpty(t, doc.ID)
+			points := vecClient.Calls[0].Arguments.Get(1).([]types.VectorPoint)
+			require.Len(t, points, 1)
+			assert.Equal(t, embedding, points[0].Vector)
+			assert.Equal(t, doc.ID, points[0].Payload["document_id"])
+			vecClient.AssertCalled(t, "DeleteStaleChunks", mock.Anything, doc.ID, 1)
+		})
+	}
+}
+
+func TestService_SearchDocuments(t *testing.T) {
+	embedding := []float32{0.1, 0.2, 0.3}
+	docs := []*types.Document{{ID: "doc_1", Title: "Test Doc", Content: "Test content"}}
+	filter := &types.VectorFilter{Must: []types.FieldCondition{{Key: "category", Values: []string{"Go"}}}}
+
+	tests := []struct {
+		name           string
+		filter         *types.VectorFilter
+		embeddingError error
+		searchError    error
+		expectedError  bool
+	}{
+		{name: "keyword search"},
+		{name: "keyword search error", searchError: assert.AnError, expectedError: true},
+		{name: "vector search", filter: filter},
+		{name: "embedding error", filter: filter, embeddingError: assert.AnError, expectedError: true},
+		{name: "vector search error", filter: filter, searchError: assert.AnError, expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			embClient := new(mockEmbClient)
+			vecClient := new(mockVecClient)
+			docStore := new(mockDocStore)
+
+			if tt.filter == nil {
+				docStore.On("SearchDocuments", mock.Anything, "test", 5).Return(docs, tt.searchError)
+			} else {
+				embClient.On("Embed", mock.Anything, "test").Return(embedding, tt.embeddingError)
+				vecClient.On("SearchVector", mock.Anything, embedding, 5*chunksPerDocument, types.SearchOptions{Filter: tt.filter}).
+					Return([]types.SearchResult{{ID: "doc_1_0", Metadata: map[string]interface{}{"document_id": "doc_1"}}}, tt.searchError).Maybe()
+				docStore.On("GetDocument", mock.Anything, "doc_1").Return(docs[0], nil).Maybe()
+			}
+
+			s := newTestService(embClient, vecClient, docStore)
+			result, err := s.SearchDocuments(context.Background(), "test", 5, tt.filter)
+
+			if tt.expectedError {
+				assert.ErrorIs(t, err, assert.AnError)
+				assert.Nil(t, result)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, docs, result)
+			}
+
+			embClient.AssertExpectations(t)
+			docStore.AssertExpectations(t)
+		})
+	}
+}
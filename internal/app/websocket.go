@@ -1,10 +1,13 @@
 package app
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"sync"
 
+	"tech-docs-ai/internal/types"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -34,7 +37,13 @@ type WebSocketMessage struct {
 	SessionID string `json:"session_id,omitempty"`
 	Message   string `json:"message,omitempty"`
 	Response  string `json:"response,omitempty"`
+	Delta     string `json:"delta,omitempty"`
 	Error     string `json:"error,omitempty"`
+	// Sources lists the documents a chat answer was based on; the answer cites Sources[i] as [i+1].
+	Sources []types.Source `json:"sources,omitempty"`
+	// Stream asks the server to send the answer as chat_delta frames
+	// followed by a chat_done frame instead of a single chat_response.
+	Stream bool `json:"stream,omitempty"`
 }
 
 // HandleWebSocket handles WebSocket connections
@@ -48,6 +57,10 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 
 	log.Printf("WebSocket connection established from %s", r.RemoteAddr)
 
+	// Cancelled when the read loop exits so streaming generation stops with the connection
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
 	// Create a mutex to protect WebSocket writes
 	var writeMutex sync.Mutex
 
@@ -78,18 +91,31 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 		case "chat":
 			// Send typing indicator
 			h.sendTypingIndicatorSafe(conn, &writeMutex)
+
+			if msg.Stream {
+				go func(connection *websocket.Conn, message string, mutex *sync.Mutex) {
+					result, err := h.service.ChatStream(ctx, message, ChatOptions{}, h.deltaSender(connection, mutex))
+					if err != nil {
+						log.Printf("Chat streaming failed: %v", err)
+						h.sendErrorSafe(connection, mutex, "Failed to process chat message", err)
+						return
+					}
+					h.sendChatResultSafe(connection, mutex, "chat_done", result)
+				}(conn, msg.Message, &writeMutex)
+				continue
+			}
 			
 			// Process chat in a goroutine to avoid blocking the WebSocket connection
 			go func(connection *websocket.Conn, message string, mutex *sync.Mutex) {
 				log.Printf("Starting chat processing for message: %s", message)
-				response, err := h.service.Chat(message)
+				result, err := h.service.Chat(ctx, message, ChatOptions{})
 				if err != nil {
 					log.Printf("Chat processing failed: %v", err)
 					h.sendErrorSafe(connection, mutex, "Failed to process chat message", err)
 					return
 				}
 				log.Printf("Chat processing completed, sending response")
-				h.sendResponseSafe(connection, mutex, "chat_response", response)
+				h.sendChatResultSafe(connection, mutex, "chat_response", result)
 				log.Printf("Response sent successfully")
 			}(conn, msg.Message, &writeMutex)
 
@@ -101,15 +127,27 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 			
 			// Send typing indicator
 			h.sendTypingIndicatorSafe(conn, &writeMutex)
+
+			if msg.Stream {
+				go func(connection *websocket.Conn, sessionID, message string, mutex *sync.Mutex) {
+					result, err := h.service.ChatWithHistoryStream(ctx, sessionID, message, ChatOptions{}, h.deltaSender(connection, mutex))
+					if err != nil {
+						h.sendErrorSafe(connection, mutex, "Failed to process chat with history", err)
+						return
+					}
+					h.sendChatResultSafe(connection, mutex, "chat_done", result)
+				}(conn, msg.SessionID, msg.Message, &writeMutex)
+				continue
+			}
 			
 			// Process chat with history in a goroutine to avoid blocking
 			go func(connection *websocket.Conn, sessionID, message string, mutex *sync.Mutex) {
-				response, err := h.service.ChatWithHistory(sessionID, message)
+				result, err := h.service.ChatWithHistory(ctx, sessionID, message, ChatOptions{})
 				if err != nil {
 					h.sendErrorSafe(connection, mutex, "Failed to process chat with history", err)
 					return
 				}
-				h.sendResponseSafe(connection, mutex, "chat_response", response)
+				h.sendChatResultSafe(connection, mutex, "chat_response", result)
 			}(conn, msg.SessionID, msg.Message, &writeMutex)
 
 		default:
@@ -170,6 +208,20 @@ func (h *WebSocketHandler) sendResponseSafe(conn *websocket.Conn, mutex *sync.Mu
 	}
 }
 
+// sendChatResultSafe sends a chat answer and its sources over WebSocket with mutex protection
+func (h *WebSocketHandler) sendChatResultSafe(conn *websocket.Conn, mutex *sync.Mutex, msgType string, result *ChatResult) {
+	msg := WebSocketMessage{
+		Type:     msgType,
+		Response: result.Response,
+		Sources:  result.Sources,
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if err := conn.WriteJSON(msg); err != nil {
+		log.Printf("WebSocket write error: %v", err)
+	}
+}
+
 // sendErrorSafe sends an error response over WebSocket with mutex protection
 func (h *WebSocketHandler) sendErrorSafe(conn *websocket.Conn, mutex *sync.Mutex, message string, err error) {
 	errorMsg := message
@@ -198,4 +250,17 @@ func (h *WebSocketHandler) sendTypingIndicatorSafe(conn *websocket.Conn, mutex *
 	if err := conn.WriteJSON(msg); err != nil {
 		log.Printf("WebSocket write error: %v", err)
 	}
-}
\ No newline at end of file
+}
+// deltaSender returns a callback that forwards each streamed chunk as a chat_delta frame.
+// A failed write aborts generation so a closed socket stops the upstream request.
+func (h *WebSocketHandler) deltaSender(conn *websocket.Conn, mutex *sync.Mutex) func(delta string) error {
+	return func(delta string) error {
+		msg := WebSocketMessage{
+			Type:  "chat_delta",
+			Delta: delta,
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		return conn.WriteJSON(msg)
+	}
+}
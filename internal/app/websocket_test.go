@@ -0,0 +1,87 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tech-docs-ai/internal/types"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func dialTestWebSocket(t *testing.T, svc ServiceInterface) *websocket.Conn {
+	wsHandler := NewWebSocketHandler(svc)
+	server := httptest.NewServer(http.HandlerFunc(wsHandler.HandleWebSocket))
+	t.Cleanup(server.Close)
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	var welcome WebSocketMessage
+	require.NoError(t, conn.ReadJSON(&welcome))
+	assert.Equal(t, "connection", welcome.Type)
+
+	return conn
+}
+
+func TestWebSocketHandler_ChatStream(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	mockService.On("ChatStream", mock.Anything, "Hello", ChatOptions{}, mock.Anything).
+		Run(func(args mock.Arguments) {
+			onDelta := args.Get(3).(func(string) error)
+			onDelta("Hi ")
+			onDelta("there!")
+		}).
+		Return(&ChatResult{Response: "Hi there!"}, nil)
+
+	conn := dialTestWebSocket(t, mockService)
+	require.NoError(t, conn.WriteJSON(WebSocketMessage{Type: "chat", Message: "Hello", Stream: true}))
+
+	var frames []WebSocketMessage
+	for {
+		var msg WebSocketMessage
+		require.NoError(t, conn.ReadJSON(&msg))
+		frames = append(frames, msg)
+		if msg.Type == "chat_done" || msg.Type == "error" {
+			break
+		}
+	}
+
+	require.Len(t, frames, 4)
+	assert.Equal(t, "typing", frames[0].Type)
+	assert.Equal(t, WebSocketMessage{Type: "chat_delta", Delta: "Hi "}, frames[1])
+	assert.Equal(t, WebSocketMessage{Type: "chat_delta", Delta: "there!"}, frames[2])
+	assert.Equal(t, WebSocketMessage{Type: "chat_done", Response: "Hi there!"}, frames[3])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestWebSocketHandler_ChatWithoutStream(t *testing.T) {
+	mockService := new(MockServiceForTesting)
+	mockService.On("Chat", mock.Anything, "Hello", ChatOptions{}).Return(&ChatResult{
+		Response: "Hi there! [1]",
+		Sources:  []types.Source{{DocumentID: "doc_1", Title: "Greetings", Score: 0.9}},
+	}, nil)
+
+	conn := dialTestWebSocket(t, mockService)
+	require.NoError(t, conn.WriteJSON(WebSocketMessage{Type: "chat", Message: "Hello"}))
+
+	var typing, response WebSocketMessage
+	require.NoError(t, conn.ReadJSON(&typing))
+	require.NoError(t, conn.ReadJSON(&response))
+
+	assert.Equal(t, "typing", typing.Type)
+	assert.Equal(t, "chat_response", response.Type)
+	assert.Equal(t, "Hi there! [1]", response.Response)
+	require.Len(t, response.Sources, 1)
+	assert.Equal(t, "doc_1", response.Sources[0].DocumentID)
+
+	mockService.AssertExpectations(t)
+}
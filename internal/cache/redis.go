@@ -82,6 +82,12 @@ func NewRedisCache() (*RedisCache, error) {
 	return &RedisCache{client: client}, nil
 }
 
+// NewRedisCacheWithClient wraps an existing client without checking the
+// connection or configuring the server.
+func NewRedisCacheWithClient(client *redis.Client) *RedisCache {
+	return &RedisCache{client: client}
+}
+
 // validateKey checks if the key size is within limits
 func (c *RedisCache) validateKey(key string) error {
 	if len(key) > MaxKeySize {
@@ -201,6 +207,8 @@ type SearchCache interface {
 	Get(ctx context.Context, query string, limit int) ([]*types.Document, error)
 	Set(ctx context.Context, query string, limit int, docs []*types.Document, ttl time.Duration) error
 	Delete(ctx context.Context, query string) error
+	// Clear removes every cached search result, for when documents change.
+	Clear(ctx context.Context) error
 }
 
 // ChatCache interface for caching chat sessions
@@ -366,6 +374,19 @@ func (c *redisSearchCache) Delete(ctx context.Context, query string) error {
 	return c.cache.client.Del(ctx, key).Err()
 }
 
+func (c *redisSearchCache) Clear(ctx context.Context) error {
+	iter := c.cache.client.Scan(ctx, 0, SearchResultKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		if err := c.cache.client.Del(ctx, iter.Val()).Err(); err != nil {
+			return fmt.Errorf("failed to delete search result: %w", err)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to scan search results: %w", err)
+	}
+	return nil
+}
+
 // redisChatCache implements ChatCache
 type redisChatCache struct {
 	cache *RedisCache
@@ -0,0 +1,326 @@
+package chunk
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Default chunking parameters, in characters.
+const (
+	DefaultSize    = 1000
+	DefaultOverlap = 150
+)
+
+// Options controls how documents are split into chunks.
+type Options struct {
+	// Size is the target maximum chunk length in characters. Code blocks are
+	// never split, so a chunk holding a large code block may exceed it.
+	Size int
+	// Overlap is how many trailing characters of a chunk are repeated at the
+	// start of the next chunk of the same section.
+	Overlap int
+}
+
+// Chunk is a piece of a document that is embedded and stored as one vector.
+type Chunk struct {
+	Index       int      `json:"chunk_index"`
+	Content     string   `json:"content"`
+	HeadingPath []string `json:"heading_path"`
+}
+
+// Splitter splits Markdown into heading-aware chunks.
+type Splitter struct {
+	opts Options
+}
+
+// NewSplitter creates a splitter configured from CHUNK_SIZE and CHUNK_OVERLAP.
+func NewSplitter() *Splitter {
+	opts := Options{
+		Size:    DefaultSize,
+		Overlap: DefaultOverlap,
+	}
+
+	if size, err := strconv.Atoi(os.Getenv("CHUNK_SIZE")); err == nil && size > 0 {
+		opts.Size = size
+	}
+	if overlap, err := strconv.Atoi(os.Getenv("CHUNK_OVERLAP")); err == nil && overlap >= 0 {
+		opts.Overlap = overlap
+	}
+
+	return NewSplitterWithOptions(opts)
+}
+
+// NewSplitterWithOptions creates a splitter with explicit options.
+func NewSplitterWithOptions(opts Options) *Splitter {
+	if opts.Size <= 0 {
+		opts.Size = DefaultSize
+	}
+	if opts.Overlap < 0 || opts.Overlap >= opts.Size {
+		opts.Overlap = 0
+	}
+	return &Splitter{opts: opts}
+}
+
+// Split breaks Markdown content into chunks. A new chunk always starts at a
+// heading, sections longer than the configured size are split between
+// paragraphs, and fenced code blocks are kept whole.
+func (s *Splitter) Split(markdown string) []Chunk {
+	var chunks []Chunk
+	for _, sec := range parseSections(markdown) {
+		for _, content := range s.splitSection(sec) {
+			chunks = append(chunks, Chunk{
+				Index:       len(chunks),
+				Content:     content,
+				HeadingPath: sec.headingPath,
+			})
+		}
+	}
+	return chunks
+}
+
+// EmbeddingText returns the text to embed for the chunk: the document title
+// and heading path followed by the chunk content.
+func (c Chunk) EmbeddingText(title string) string {
+	path := make([]string, 0, len(c.HeadingPath)+1)
+	if title != "" && (len(c.HeadingPath) == 0 || c.HeadingPath[0] != title) {
+		path = append(path, title)
+	}
+	path = append(path, c.HeadingPath...)
+
+	if len(path) == 0 {
+		return c.Content
+	}
+	return strings.Join(path, " > ") + "\n\n" + c.Content
+}
+
+// Payload returns a copy of base extended with the chunk's index, heading path and content.
+func (c Chunk) Payload(base map[string]interface{}) map[string]interface{} {
+	payload := make(map[string]interface{}, len(base)+3)
+	for k, v := range base {
+		payload[k] = v
+	}
+
+	headingPath := c.HeadingPath
+	if headingPath == nil {
+		headingPath = []string{}
+	}
+
+	payload["chunk_index"] = c.Index
+	payload["heading_path"] = headingPath
+	payload["content"] = c.Content
+	return payload
+}
+
+var headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.+?)(?:\s+#+)?\s*$`)
+
+// block is a paragraph or fenced code block within a section.
+type block struct {
+	text string
+	code bool
+}
+
+// section is the content under one heading.
+type section struct {
+	headingPath []string
+	heading     string
+	blocks      []block
+}
+
+// parseSections groups Markdown lines into sections of blocks.
+func parseSections(markdown string) []section {
+	type heading struct {
+		level int
+		text  string
+	}
+
+	var sections []section
+	var stack []heading
+	var current section
+	var paragraph, code []string
+	fence := ""
+
+	flushParagraph := func() {
+		if text := strings.TrimSpace(strings.Join(paragraph, "\n")); text != "" {
+			current.blocks = append(current.blocks, block{text: text})
+		}
+		paragraph = nil
+	}
+	flushSection := func() {
+		flushParagraph()
+		if len(current.blocks) > 0 {
+			sections = append(sections, current)
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if fence != "" {
+			code = append(code, line)
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				current.blocks = append(current.blocks, block{text: strings.Join(code, "\n"), code: true})
+				code = nil
+				fence = ""
+			}
+			continue
+		}
+
+		if f := openingFence(trimmed); f != "" {
+			flushParagraph()
+			fence = f
+			code = []string{line}
+			continue
+		}
+
+		if m := headingPattern.FindStringSubmatch(trimmed); m != nil {
+			flushSection()
+			level := len(m[1])
+			for len(stack) > 0 && stack[len(stack)-1].level >= level {
+				stack = stack[:len(stack)-1]
+			}
+			stack = append(stack, heading{level: level, text: m[2]})
+
+			path := make([]string, len(stack))
+			for i, h := range stack {
+				path[i] = h.text
+			}
+			current = section{headingPath: path, heading: trimmed}
+			continue
+		}
+
+		if trimmed == "" {
+			flushParagraph()
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+
+	// An unterminated fence still counts as code
+	if len(code) > 0 {
+		current.blocks = append(current.blocks, block{text: strings.Join(code, "\n"), code: true})
+	}
+	flushSection()
+
+	return sections
+}
+
+// openingFence returns the fence marker if the line opens a fenced code block.
+func openingFence(line string) string {
+	for _, marker := range []string{"```", "~~~"} {
+		if strings.HasPrefix(line, marker) {
+			return line[:len(line)-len(strings.TrimLeft(line, marker[:1]))]
+		}
+	}
+	return ""
+}
+
+// splitSection packs a section's blocks into chunks of at most the configured size.
+func (s *Splitter) splitSection(sec section) []string {
+	var pieces []block
+	for _, b := range sec.blocks {
+		if b.code || runeLen(b.text) <= s.opts.Size {
+			pieces = append(pieces, b)
+			continue
+		}
+		for _, text := range splitText(b.text, s.opts.Size) {
+			pieces = append(pieces, block{text: text})
+		}
+	}
+
+	var chunks []string
+	var parts []string
+	length := 0
+	hasBody := false
+
+	if sec.heading != "" {
+		parts = append(parts, sec.heading)
+		length = runeLen(sec.heading)
+	}
+
+	for i, piece := range pieces {
+		n := runeLen(piece.text)
+		if hasBody && length+n+2 > s.opts.Size {
+			chunks = append(chunks, strings.Join(parts, "\n\n"))
+			parts = nil
+			length = 0
+
+			// Repeat the end of the previous prose block, never part of a code block
+			if previous := pieces[i-1]; !previous.code {
+				if overlap := tail(previous.text, s.opts.Overlap); overlap != "" {
+					parts = append(parts, overlap)
+					length = runeLen(overlap)
+				}
+			}
+		}
+
+		parts = append(parts, piece.text)
+		length += n + 2
+		hasBody = true
+	}
+
+	if hasBody {
+		chunks = append(chunks, strings.Join(parts, "\n\n"))
+	}
+
+	return chunks
+}
+
+// splitText splits a long prose block at line and then word boundaries.
+func splitText(text string, size int) []string {
+	var pieces []string
+	var current strings.Builder
+
+	add := func(word, sep string) {
+		if current.Len() > 0 && runeLen(current.String())+len(sep)+runeLen(word) > size {
+			pieces = append(pieces, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteString(sep)
+		}
+		current.WriteString(word)
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if runeLen(line) <= size {
+			add(line, "\n")
+			continue
+		}
+		for j, word := range strings.Fields(line) {
+			sep := " "
+			if j == 0 {
+				sep = "\n"
+			}
+			add(word, sep)
+		}
+	}
+
+	if current.Len() > 0 {
+		pieces = append(pieces, current.String())
+	}
+	return pieces
+}
+
+// tail returns roughly the last n characters of text, starting at a word boundary.
+func tail(text string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if runeLen(text) <= n {
+		return text
+	}
+
+	runes := []rune(text)
+	suffix := string(runes[len(runes)-n:])
+	if i := strings.IndexAny(suffix, " \n"); i >= 0 {
+		suffix = suffix[i+1:]
+	}
+	return strings.TrimSpace(suffix)
+}
+
+func runeLen(s string) int {
+	return utf8.RuneCountInString(s)
+}
@@ -0,0 +1,121 @@
+package chunk
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitter_SplitsAtHeadings(t *testing.T) {
+	markdown := `Intro paragraph before any heading.
+
+# Go Basics
+
+Go is a statically typed language.
+
+## Variables
+
+Use var or := to declare variables.
+
+### Constants
+
+Constants are declared with const.
+
+## Functions
+
+Functions are declared with func.`
+
+	chunks := NewSplitterWithOptions(Options{Size: 500, Overlap: 50}).Split(markdown)
+
+	require.Len(t, chunks, 5)
+
+	assert.Nil(t, chunks[0].HeadingPath)
+	assert.Equal(t, "Intro paragraph before any heading.", chunks[0].Content)
+
+	assert.Equal(t, []string{"Go Basics"}, chunks[1].HeadingPath)
+	assert.Equal(t, "# Go Basics\n\nGo is a statically typed language.", chunks[1].Content)
+
+	assert.Equal(t, []string{"Go Basics", "Variables"}, chunks[2].HeadingPath)
+	assert.Equal(t, []string{"Go Basics", "Variables", "Constants"}, chunks[3].HeadingPath)
+	assert.Equal(t, []string{"Go Basics", "Functions"}, chunks[4].HeadingPath)
+
+	for i, c := range chunks {
+		assert.Equal(t, i, c.Index)
+	}
+}
+
+func TestSplitter_KeepsCodeBlocksIntact(t *testing.T) {
+	code := "```go\n# not a heading\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n```"
+	markdown := "## Example\n\n" + strings.Repeat("word ", 20) + "\n\n" + code + "\n\nAfter the code."
+
+	chunks := NewSplitterWithOptions(Options{Size: 60, Overlap: 10}).Split(markdown)
+
+	var found bool
+	for _, c := range chunks {
+		assert.Equal(t, []string{"Example"}, c.HeadingPath)
+		if strings.Contains(c.Content, "```go") {
+			assert.Contains(t, c.Content, code)
+			found = true
+		}
+	}
+	assert.True(t, found, "code block should be present in one chunk")
+}
+
+func TestSplitter_SizeAndOverlap(t *testing.T) {
+	var paragraphs []string
+	for i := 0; i < 10; i++ {
+		paragraphs = append(paragraphs, strings.Repeat("alpha beta gamma ", 5))
+	}
+	markdown := "# Long\n\n" + strings.Join(paragraphs, "\n\n")
+
+	chunks := NewSplitterWithOptions(Options{Size: 200, Overlap: 30}).Split(markdown)
+
+	require.Greater(t, len(chunks), 1)
+	for _, c := range chunks {
+		assert.LessOrEqual(t, runeLen(c.Content), 200+30)
+	}
+
+	// Each continuation chunk starts with the tail of the previous one
+	for i := 1; i < len(chunks); i++ {
+		prefix := strings.SplitN(chunks[i].Content, "\n\n", 2)[0]
+		assert.True(t, strings.HasSuffix(chunks[i-1].Content, prefix), "chunk %d should overlap chunk %d", i, i-1)
+	}
+}
+
+func TestSplitter_SplitsOversizedParagraph(t *testing.T) {
+	markdown := strings.Repeat("lorem ipsum ", 100)
+
+	chunks := NewSplitterWithOptions(Options{Size: 100, Overlap: 0}).Split(markdown)
+
+	require.Greater(t, len(chunks), 1)
+	for _, c := range chunks {
+		assert.LessOrEqual(t, runeLen(c.Content), 100)
+	}
+}
+
+func TestSplitter_EmptyContent(t *testing.T) {
+	assert.Empty(t, NewSplitter().Split(""))
+	assert.Empty(t, NewSplitter().Split("# Only a heading\n\n"))
+}
+
+func TestChunk_EmbeddingText(t *testing.T) {
+	c := Chunk{Content: "Use var.", HeadingPath: []string{"Go Basics", "Variables"}}
+	assert.Equal(t, "Go Tutorial > Go Basics > Variables\n\nUse var.", c.EmbeddingText("Go Tutorial"))
+	assert.Equal(t, "Go Basics > Variables\n\nUse var.", c.EmbeddingText("Go Basics"))
+	assert.Equal(t, "Use var.", Chunk{Content: "Use var."}.EmbeddingText(""))
+}
+
+func TestChunk_Payload(t *testing.T) {
+	base := map[string]interface{}{"document_id": "doc_1"}
+	c := Chunk{Index: 2, Content: "text"}
+
+	payload := c.Payload(base)
+
+	assert.Equal(t, "doc_1", payload["document_id"])
+	assert.Equal(t, 2, payload["chunk_index"])
+	assert.Equal(t, []string{}, payload["heading_path"])
+	assert.Equal(t, "text", payload["content"])
+	assert.NotContains(t, base, "chunk_index")
+}
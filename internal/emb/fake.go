@@ -2,7 +2,11 @@
 package emb
 
 import (
+	"context"
 	"errors"
+	"strings"
+
+	"tech-docs-ai/internal/types"
 )
 
 // FakeClient is a fake implementation of an embedding client for testing.
@@ -14,15 +18,61 @@ func NewFakeClient() *FakeClient {
 }
 
 // Embed takes a text and returns a fake vector embedding.
-func (c *FakeClient) Embed(text string) ([]float32, error) {
+func (c *FakeClient) Embed(ctx context.Context, text string) ([]float32, error) {
 	// Return a static, fake embedding for demonstration.
 	return []float32{0.1, 0.2, 0.3}, nil
 }
 
+// EmbedBatch returns a fake vector embedding for each text.
+func (c *FakeClient) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = c.Embed(ctx, text)
+	}
+	return vectors, nil
+}
+
 // Chat returns a hardcoded chat response.
-func (c *FakeClient) Chat(message string) (string, error) {
+func (c *FakeClient) Chat(ctx context.Context, message string, opts types.GenerationOptions) (string, error) {
 	if message == "" {
 		return "", errors.New("empty message")
 	}
 	return "Fake LLM response for: " + message, nil
 }
+
+// ChatStream returns the hardcoded chat response one word at a time.
+func (c *FakeClient) ChatStream(ctx context.Context, message string, opts types.GenerationOptions, onToken func(token string) error) (string, error) {
+	response, err := c.Chat(ctx, message, opts)
+	if err != nil {
+		return "", err
+	}
+	for _, word := range strings.SplitAfter(response, " ") {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if err := onToken(word); err != nil {
+			return "", err
+		}
+	}
+	return response, nil
+}
+
+// ChatMessages returns a hardcoded response to the last user message.
+func (c *FakeClient) ChatMessages(ctx context.Context, messages []types.LLMMessage, opts types.GenerationOptions) (string, error) {
+	return c.Chat(ctx, lastUserMessage(messages), opts)
+}
+
+// ChatMessagesStream returns the hardcoded response to the last user message one word at a time.
+func (c *FakeClient) ChatMessagesStream(ctx context.Context, messages []types.LLMMessage, opts types.GenerationOptions, onToken func(token string) error) (string, error) {
+	return c.ChatStream(ctx, lastUserMessage(messages), opts, onToken)
+}
+
+// lastUserMessage returns the content of the most recent user message.
+func lastUserMessage(messages []types.LLMMessage) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == types.RoleUser {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
@@ -2,11 +2,15 @@ package emb
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"time"
+
+	"tech-docs-ai/internal/types"
 )
 
 // OllamaClient is a client for interacting with Ollama API
@@ -14,6 +18,7 @@ type OllamaClient struct {
 	apiURL     string
 	model      string
 	chatModel  string
+	options    types.GenerationOptions
 	httpClient *http.Client
 }
 
@@ -38,6 +43,7 @@ func NewOllamaClient() *OllamaClient {
 		apiURL:    apiURL,
 		model:     model,
 		chatModel: chatModel,
+		options:   DefaultGenerationOptions(),
 		httpClient: &http.Client{
 			Timeout: 300 * time.Second, // Increased to 5 minutes for LLM responses
 		},
@@ -55,11 +61,23 @@ type EmbedResponse struct {
 	Embedding []float32 `json:"embedding"`
 }
 
+// EmbedBatchRequest represents a request to the /api/embed endpoint
+type EmbedBatchRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// EmbedBatchResponse represents a response from the /api/embed endpoint
+type EmbedBatchResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
 // ChatRequest represents a request to the chat API
 type ChatRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
+	Model   string                   `json:"model"`
+	Prompt  string                   `json:"prompt"`
+	Stream  bool                     `json:"stream"`
+	Options *types.GenerationOptions `json:"options,omitempty"`
 }
 
 // ChatResponse represents a response from the chat API
@@ -68,8 +86,22 @@ type ChatResponse struct {
 	Done     bool   `json:"done"`
 }
 
+// ChatMessagesRequest represents a request to the /api/chat endpoint
+type ChatMessagesRequest struct {
+	Model    string                   `json:"model"`
+	Messages []types.LLMMessage       `json:"messages"`
+	Stream   bool                     `json:"stream"`
+	Options  *types.GenerationOptions `json:"options,omitempty"`
+}
+
+// ChatMessagesResponse represents a response, or a streamed chunk of one, from the /api/chat endpoint
+type ChatMessagesResponse struct {
+	Message types.LLMMessage `json:"message"`
+	Done    bool             `json:"done"`
+}
+
 // Embed generates embeddings for the given text
-func (c *OllamaClient) Embed(text string) ([]float32, error) {
+func (c *OllamaClient) Embed(ctx context.Context, text string) ([]float32, error) {
 	request := EmbedRequest{
 		Model:  c.model,
 		Prompt: text,
@@ -80,7 +112,13 @@ func (c *OllamaClient) Embed(text string) ([]float32, error) {
 		return nil, fmt.Errorf("failed to marshal embed request: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(c.apiURL+"/api/embeddings", "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.apiURL+"/api/embeddings", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embed request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make embed request: %w", err)
 	}
@@ -98,12 +136,53 @@ func (c *OllamaClient) Embed(text string) ([]float32, error) {
 	return embedResponse.Embedding, nil
 }
 
-// Chat generates a chat response for the given message
-func (c *OllamaClient) Chat(message string) (string, error) {
+// EmbedBatch generates embeddings for many texts using the /api/embed endpoint,
+// sending at most MaxEmbedBatchSize texts per request. The result is in input order.
+func (c *OllamaClient) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return embedInBatches(texts, func(batch []string) ([][]float32, error) {
+		request := EmbedBatchRequest{
+			Model: c.model,
+			Input: batch,
+		}
+
+		jsonData, err := json.Marshal(request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal embed request: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", c.apiURL+"/api/embed", bytes.NewBuffer(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create embed request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to make embed request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("embed request failed with status: %d", resp.StatusCode)
+		}
+
+		var embedResponse EmbedBatchResponse
+		if err := json.NewDecoder(resp.Body).Decode(&embedResponse); err != nil {
+			return nil, fmt.Errorf("failed to decode embed response: %w", err)
+		}
+
+		return embedResponse.Embeddings, nil
+	})
+}
+
+// Chat generates a chat response for the given message. Unset options fall
+// back to the client's configured defaults.
+func (c *OllamaClient) Chat(ctx context.Context, message string, opts types.GenerationOptions) (string, error) {
 	request := ChatRequest{
-		Model:  c.chatModel,
-		Prompt: message,
-		Stream: false,
+		Model:   c.chatModel,
+		Prompt:  message,
+		Stream:  false,
+		Options: c.requestOptions(opts),
 	}
 
 	jsonData, err := json.Marshal(request)
@@ -111,7 +190,13 @@ func (c *OllamaClient) Chat(message string) (string, error) {
 		return "", fmt.Errorf("failed to marshal chat request: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(c.apiURL+"/api/generate", "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.apiURL+"/api/generate", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create chat request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to make chat request: %w", err)
 	}
@@ -128,3 +213,164 @@ func (c *OllamaClient) Chat(message string) (string, error) {
 
 	return chatResponse.Response, nil
 }
+
+// ChatStream generates a chat response for the given message, calling onToken
+// with each chunk as Ollama streams it back. It returns the full response.
+// Cancelling ctx aborts the upstream request.
+func (c *OllamaClient) ChatStream(ctx context.Context, message string, opts types.GenerationOptions, onToken func(token string) error) (string, error) {
+	request := ChatRequest{
+		Model:   c.chatModel,
+		Prompt:  message,
+		Stream:  true,
+		Options: c.requestOptions(opts),
+	}
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal chat request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.apiURL+"/api/generate", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create chat request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to make chat request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("chat request failed with status: %d", resp.StatusCode)
+	}
+
+	// Ollama streams one JSON object per line until an object with done=true
+	var full bytes.Buffer
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var chunk ChatResponse
+		if err := decoder.Decode(&chunk); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return full.String(), fmt.Errorf("failed to decode chat stream: %w", err)
+		}
+
+		if chunk.Response != "" {
+			full.WriteString(chunk.Response)
+			if err := onToken(chunk.Response); err != nil {
+				return full.String(), fmt.Errorf("stream consumer aborted: %w", err)
+			}
+		}
+
+		if chunk.Done {
+			break
+		}
+	}
+
+	return full.String(), nil
+}
+
+// ChatMessages generates the assistant's reply to a role-structured conversation
+// using the /api/chat endpoint.
+func (c *OllamaClient) ChatMessages(ctx context.Context, messages []types.LLMMessage, opts types.GenerationOptions) (string, error) {
+	request := ChatMessagesRequest{
+		Model:    c.chatModel,
+		Messages: messages,
+		Stream:   false,
+		Options:  c.requestOptions(opts),
+	}
+
+	resp, err := c.postChat(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var chatResponse ChatMessagesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResponse); err != nil {
+		return "", fmt.Errorf("failed to decode chat response: %w", err)
+	}
+
+	return chatResponse.Message.Content, nil
+}
+
+// ChatMessagesStream is like ChatMessages but calls onToken with each chunk as
+// Ollama streams it back. It returns the full response.
+func (c *OllamaClient) ChatMessagesStream(ctx context.Context, messages []types.LLMMessage, opts types.GenerationOptions, onToken func(token string) error) (string, error) {
+	request := ChatMessagesRequest{
+		Model:    c.chatModel,
+		Messages: messages,
+		Stream:   true,
+		Options:  c.requestOptions(opts),
+	}
+
+	resp, err := c.postChat(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var full bytes.Buffer
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var chunk ChatMessagesResponse
+		if err := decoder.Decode(&chunk); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return full.String(), fmt.Errorf("failed to decode chat stream: %w", err)
+		}
+
+		if chunk.Message.Content != "" {
+			full.WriteString(chunk.Message.Content)
+			if err := onToken(chunk.Message.Content); err != nil {
+				return full.String(), fmt.Errorf("stream consumer aborted: %w", err)
+			}
+		}
+
+		if chunk.Done {
+			break
+		}
+	}
+
+	return full.String(), nil
+}
+
+// requestOptions fills unset options from the configured defaults. It returns
+// nil when nothing is set so Ollama applies the model's own defaults.
+func (c *OllamaClient) requestOptions(opts types.GenerationOptions) *types.GenerationOptions {
+	opts = opts.WithDefaults(c.options)
+	if opts.IsZero() {
+		return nil
+	}
+	return &opts
+}
+
+// postChat sends a request to the /api/chat endpoint and checks the response status.
+func (c *OllamaClient) postChat(ctx context.Context, request ChatMessagesRequest) (*http.Response, error) {
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal chat request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.apiURL+"/api/chat", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make chat request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("chat request failed with status: %d", resp.StatusCode)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,296 @@
+package emb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"tech-docs-ai/internal/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestOllamaClient(serverURL string) *OllamaClient {
+	client := NewOllamaClient()
+	client.apiURL = serverURL
+	return client
+}
+
+func TestOllamaClient_ChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/generate", r.URL.Path)
+
+		var req ChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for _, token := range []string{"Hello", ", ", "world"} {
+			fmt.Fprintf(w, "{\"response\":%q,\"done\":false}\n", token)
+		}
+		fmt.Fprintln(w, `{"response":"","done":true}`)
+	}))
+	defer server.Close()
+
+	client := newTestOllamaClient(server.URL)
+
+	var tokens []string
+	response, err := client.ChatStream(context.Background(), "hi", types.GenerationOptions{}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, world", response)
+	assert.Equal(t, []string{"Hello", ", ", "world"}, tokens)
+}
+
+func TestOllamaClient_ChatStream_ConsumerAbort(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"response":"one","done":false}`)
+		fmt.Fprintln(w, `{"response":"two","done":false}`)
+		fmt.Fprintln(w, `{"response":"","done":true}`)
+	}))
+	defer server.Close()
+
+	client := newTestOllamaClient(server.URL)
+
+	calls := 0
+	_, err := client.ChatStream(context.Background(), "hi", types.GenerationOptions{}, func(token string) error {
+		calls++
+		return fmt.Errorf("client went away")
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestOllamaClient_ChatStream_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := newTestOllamaClient(server.URL)
+
+	_, err := client.ChatStream(context.Background(), "hi", types.GenerationOptions{}, func(token string) error { return nil })
+	assert.Error(t, err)
+}
+
+func TestOllamaClient_ChatStream_ContextCancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Hold the request open until the client goes away
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := newTestOllamaClient(server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.ChatStream(ctx, "hi", types.GenerationOptions{}, func(token string) error { return nil })
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestOllamaClient_Embed_DeadlineExceeded(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := newTestOllamaClient(server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.Embed(ctx, "hello")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestOllamaClient_Chat_ContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request should not reach the server")
+	}))
+	defer server.Close()
+
+	client := newTestOllamaClient(server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.Chat(ctx, "hi", types.GenerationOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestOllamaClient_ChatMessages(t *testing.T) {
+	messages := []types.LLMMessage{
+		{Role: types.RoleSystem, Content: "Be brief."},
+		{Role: types.RoleUser, Content: "What is Go?"},
+		{Role: types.RoleAssistant, Content: "A programming language."},
+		{Role: types.RoleUser, Content: "Who made it?"},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/chat", r.URL.Path)
+
+		var req ChatMessagesRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.False(t, req.Stream)
+		assert.Equal(t, messages, req.Messages)
+
+		fmt.Fprint(w, `{"message":{"role":"assistant","content":"Google."},"done":true}`)
+	}))
+	defer server.Close()
+
+	client := newTestOllamaClient(server.URL)
+
+	response, err := client.ChatMessages(context.Background(), messages, types.GenerationOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Google.", response)
+}
+
+func TestOllamaClient_ChatMessagesStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/chat", r.URL.Path)
+
+		var req ChatMessagesRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+
+		for _, token := range []string{"Hello", ", ", "world"} {
+			fmt.Fprintf(w, "{\"message\":{\"role\":\"assistant\",\"content\":%q},\"done\":false}\n", token)
+		}
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true}`)
+	}))
+	defer server.Close()
+
+	client := newTestOllamaClient(server.URL)
+
+	var tokens []string
+	response, err := client.ChatMessagesStream(context.Background(), []types.LLMMessage{{Role: types.RoleUser, Content: "hi"}}, types.GenerationOptions{}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, world", response)
+	assert.Equal(t, []string{"Hello", ", ", "world"}, tokens)
+}
+
+func TestOllamaClient_ChatMessages_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := newTestOllamaClient(server.URL)
+
+	_, err := client.ChatMessages(context.Background(), []types.LLMMessage{{Role: types.RoleUser, Content: "hi"}}, types.GenerationOptions{})
+	assert.Error(t, err)
+}
+
+func TestOllamaClient_Chat_Options(t *testing.T) {
+	t.Setenv("LLM_TEMPERATURE", "0.7")
+	t.Setenv("LLM_NUM_CTX", "4096")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.NotNil(t, req.Options)
+
+		// Per-call options override the defaults; unset ones keep them
+		assert.Equal(t, 0.1, *req.Options.Temperature)
+		assert.Equal(t, 4096, *req.Options.NumCtx)
+		assert.Equal(t, 7, *req.Options.Seed)
+		assert.Nil(t, req.Options.TopP)
+
+		fmt.Fprint(w, `{"response":"ok","done":true}`)
+	}))
+	defer server.Close()
+
+	client := newTestOllamaClient(server.URL)
+
+	temperature := 0.1
+	seed := 7
+	_, err := client.Chat(context.Background(), "hi", types.GenerationOptions{Temperature: &temperature, Seed: &seed})
+	require.NoError(t, err)
+}
+
+func TestOllamaClient_Chat_NoOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.NotContains(t, body, "options")
+
+		fmt.Fprint(w, `{"response":"ok","done":true}`)
+	}))
+	defer server.Close()
+
+	client := newTestOllamaClient(server.URL)
+	client.options = types.GenerationOptions{}
+
+	_, err := client.Chat(context.Background(), "hi", types.GenerationOptions{})
+	require.NoError(t, err)
+}
+
+func TestOllamaClient_EmbedBatch(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/embed", r.URL.Path)
+		requests++
+
+		var req EmbedBatchRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.LessOrEqual(t, len(req.Input), MaxEmbedBatchSize)
+
+		resp := EmbedBatchResponse{}
+		for i := range req.Input {
+			resp.Embeddings = append(resp.Embeddings, []float32{float32(len(req.Input[i]))})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := newTestOllamaClient(server.URL)
+
+	texts := make([]string, MaxEmbedBatchSize+1)
+	for i := range texts {
+		texts[i] = strings.Repeat("a", i+1)
+	}
+
+	vectors, err := client.EmbedBatch(context.Background(), texts)
+	require.NoError(t, err)
+	require.Len(t, vectors, len(texts))
+	assert.Equal(t, 2, requests)
+	for i, vector := range vectors {
+		assert.Equal(t, []float32{float32(i + 1)}, vector)
+	}
+}
+
+func TestOllamaClient_EmbedBatch_CountMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"embeddings":[[0.1]]}`)
+	}))
+	defer server.Close()
+
+	client := newTestOllamaClient(server.URL)
+
+	_, err := client.EmbedBatch(context.Background(), []string{"a", "b"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,277 @@
+package emb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"tech-docs-ai/internal/types"
+)
+
+// OpenAIClient is a client for servers that speak the OpenAI-compatible
+// /v1/chat/completions and /v1/embeddings API, such as llama.cpp server, vLLM or LocalAI.
+type OpenAIClient struct {
+	apiURL     string
+	apiKey     string
+	model      string
+	chatModel  string
+	options    types.GenerationOptions
+	httpClient *http.Client
+}
+
+// NewOpenAIClient creates a new OpenAI-compatible client
+func NewOpenAIClient() *OpenAIClient {
+	apiURL := os.Getenv("OPENAI_API_URL")
+	if apiURL == "" {
+		apiURL = "http://localhost:8000"
+	}
+
+	model := os.Getenv("OPENAI_EMBED_MODEL")
+	if model == "" {
+		model = "nomic-embed-text"
+	}
+
+	chatModel := os.Getenv("OPENAI_CHAT_MODEL")
+	if chatModel == "" {
+		chatModel = "tinyllama"
+	}
+
+	return &OpenAIClient{
+		apiURL:    strings.TrimRight(apiURL, "/"),
+		apiKey:    os.Getenv("OPENAI_API_KEY"),
+		model:     model,
+		chatModel: chatModel,
+		options:   DefaultGenerationOptions(),
+		httpClient: &http.Client{
+			Timeout: 300 * time.Second,
+		},
+	}
+}
+
+// OpenAIEmbeddingRequest represents a request to the /v1/embeddings endpoint
+type OpenAIEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// OpenAIEmbeddingResponse represents a response from the /v1/embeddings endpoint
+type OpenAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// OpenAIChatRequest represents a request to the /v1/chat/completions endpoint
+type OpenAIChatRequest struct {
+	Model       string             `json:"model"`
+	Messages    []types.LLMMessage `json:"messages"`
+	Stream      bool               `json:"stream"`
+	Temperature *float64           `json:"temperature,omitempty"`
+	TopP        *float64           `json:"top_p,omitempty"`
+	MaxTokens   *int               `json:"max_tokens,omitempty"`
+	Stop        []string           `json:"stop,omitempty"`
+	Seed        *int               `json:"seed,omitempty"`
+}
+
+// OpenAIChatResponse represents a response, or a streamed chunk of one,
+// from the /v1/chat/completions endpoint
+type OpenAIChatResponse struct {
+	Choices []struct {
+		Message      types.LLMMessage `json:"message"`
+		Delta        types.LLMMessage `json:"delta"`
+		FinishReason *string          `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// Embed generates embeddings for the given text
+func (c *OpenAIClient) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := c.embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// EmbedBatch generates embeddings for many texts, sending at most
+// MaxEmbedBatchSize texts per request. The result is in input order.
+func (c *OpenAIClient) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return embedInBatches(texts, func(batch []string) ([][]float32, error) {
+		return c.embed(ctx, batch)
+	})
+}
+
+// embed sends a single /v1/embeddings request and orders the result by input index.
+func (c *OpenAIClient) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	request := OpenAIEmbeddingRequest{
+		Model: c.model,
+		Input: texts,
+	}
+
+	resp, err := c.post(ctx, "/v1/embeddings", request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make embed request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embed request failed with status: %d", resp.StatusCode)
+	}
+
+	var embedResponse OpenAIEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embedResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode embed response: %w", err)
+	}
+
+	if len(embedResponse.Data) != len(texts) {
+		return nil, fmt.Errorf("embed response contained %d embeddings for %d inputs", len(embedResponse.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range embedResponse.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embed response has out of range index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+
+	return vectors, nil
+}
+
+// Chat generates a chat response for the given message
+func (c *OpenAIClient) Chat(ctx context.Context, message string, opts types.GenerationOptions) (string, error) {
+	return c.ChatMessages(ctx, userMessage(message), opts)
+}
+
+// ChatStream generates a chat response for the given message, calling onToken
+// with each chunk as the server streams it back. It returns the full response.
+// Cancelling ctx aborts the upstream request.
+func (c *OpenAIClient) ChatStream(ctx context.Context, message string, opts types.GenerationOptions, onToken func(token string) error) (string, error) {
+	return c.ChatMessagesStream(ctx, userMessage(message), opts, onToken)
+}
+
+// ChatMessages generates the assistant's reply to a role-structured conversation.
+// Unset options fall back to the client's configured defaults.
+func (c *OpenAIClient) ChatMessages(ctx context.Context, messages []types.LLMMessage, opts types.GenerationOptions) (string, error) {
+	request := c.newChatRequest(messages, false, opts)
+
+	resp, err := c.post(ctx, "/v1/chat/completions", request)
+	if err != nil {
+		return "", fmt.Errorf("failed to make chat request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("chat request failed with status: %d", resp.StatusCode)
+	}
+
+	var chatResponse OpenAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResponse); err != nil {
+		return "", fmt.Errorf("failed to decode chat response: %w", err)
+	}
+
+	if len(chatResponse.Choices) == 0 {
+		return "", fmt.Errorf("chat response contained no choices")
+	}
+
+	return chatResponse.Choices[0].Message.Content, nil
+}
+
+// ChatMessagesStream is like ChatMessages but calls onToken with each chunk as
+// the server streams it back. It returns the full response.
+func (c *OpenAIClient) ChatMessagesStream(ctx context.Context, messages []types.LLMMessage, opts types.GenerationOptions, onToken func(token string) error) (string, error) {
+	request := c.newChatRequest(messages, true, opts)
+
+	resp, err := c.post(ctx, "/v1/chat/completions", request)
+	if err != nil {
+		return "", fmt.Errorf("failed to make chat request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("chat request failed with status: %d", resp.StatusCode)
+	}
+
+	// The stream is a sequence of "data: {...}" server-sent events ending with "data: [DONE]"
+	var full bytes.Buffer
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk OpenAIChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return full.String(), fmt.Errorf("failed to decode chat stream: %w", err)
+		}
+
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			full.WriteString(choice.Delta.Content)
+			if err := onToken(choice.Delta.Content); err != nil {
+				return full.String(), fmt.Errorf("stream consumer aborted: %w", err)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return full.String(), fmt.Errorf("failed to read chat stream: %w", err)
+	}
+
+	return full.String(), nil
+}
+
+// newChatRequest builds a chat completion request. OpenAI-compatible servers
+// take the context size from their own configuration, so NumCtx is not sent.
+func (c *OpenAIClient) newChatRequest(messages []types.LLMMessage, stream bool, opts types.GenerationOptions) OpenAIChatRequest {
+	opts = opts.WithDefaults(c.options)
+	return OpenAIChatRequest{
+		Model:       c.chatModel,
+		Messages:    messages,
+		Stream:      stream,
+		Temperature: opts.Temperature,
+		TopP:        opts.TopP,
+		MaxTokens:   opts.NumPredict,
+		Stop:        opts.Stop,
+		Seed:        opts.Seed,
+	}
+}
+
+// userMessage wraps a single prompt as a one-message conversation.
+func userMessage(message string) []types.LLMMessage {
+	return []types.LLMMessage{{Role: types.RoleUser, Content: message}}
+}
+
+// post sends a JSON request to the given API path.
+func (c *OpenAIClient) post(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.apiURL+path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	return c.httpClient.Do(req)
+}
@@ -0,0 +1,190 @@
+package emb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tech-docs-ai/internal/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestOpenAIClient(serverURL string) *OpenAIClient {
+	client := NewOpenAIClient()
+	client.apiURL = serverURL
+	client.apiKey = "test-key"
+	return client
+}
+
+func TestOpenAIClient_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+		var req OpenAIEmbeddingRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, []string{"hello"}, req.Input)
+
+		fmt.Fprint(w, `{"data":[{"index":0,"embedding":[0.1,0.2,0.3]}]}`)
+	}))
+	defer server.Close()
+
+	client := newTestOpenAIClient(server.URL)
+
+	vector, err := client.Embed(context.Background(), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, []float32{0.1, 0.2, 0.3}, vector)
+}
+
+func TestOpenAIClient_Embed_NoData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":[]}`)
+	}))
+	defer server.Close()
+
+	client := newTestOpenAIClient(server.URL)
+
+	_, err := client.Embed(context.Background(), "hello")
+	assert.Error(t, err)
+}
+
+func TestOpenAIClient_EmbedBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenAIEmbeddingRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, []string{"first", "second"}, req.Input)
+
+		// Servers may return the embeddings out of order
+		fmt.Fprint(w, `{"data":[{"index":1,"embedding":[0.2]},{"index":0,"embedding":[0.1]}]}`)
+	}))
+	defer server.Close()
+
+	client := newTestOpenAIClient(server.URL)
+
+	vectors, err := client.EmbedBatch(context.Background(), []string{"first", "second"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.1}, {0.2}}, vectors)
+}
+
+func TestOpenAIClient_Chat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+
+		var req OpenAIChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.False(t, req.Stream)
+		require.Len(t, req.Messages, 1)
+		assert.Equal(t, "user", req.Messages[0].Role)
+		assert.Equal(t, "hi", req.Messages[0].Content)
+
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Hello!"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	client := newTestOpenAIClient(server.URL)
+
+	response, err := client.Chat(context.Background(), "hi", types.GenerationOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello!", response)
+}
+
+func TestOpenAIClient_Chat_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := newTestOpenAIClient(server.URL)
+
+	_, err := client.Chat(context.Background(), "hi", types.GenerationOptions{})
+	assert.Error(t, err)
+}
+
+func TestOpenAIClient_ChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenAIChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+		for _, token := range []string{"Hello", ", ", "world"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", token)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := newTestOpenAIClient(server.URL)
+
+	var tokens []string
+	response, err := client.ChatStream(context.Background(), "hi", types.GenerationOptions{}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, world", response)
+	assert.Equal(t, []string{"Hello", ", ", "world"}, tokens)
+}
+
+func TestOpenAIClient_ChatStream_ConsumerAbort(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hello\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\" world\"}}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := newTestOpenAIClient(server.URL)
+
+	errStop := errors.New("stop")
+	response, err := client.ChatStream(context.Background(), "hi", types.GenerationOptions{}, func(token string) error {
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+	assert.Equal(t, "Hello", response)
+}
+
+func TestOpenAIClient_Chat_Options(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenAIChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.NotNil(t, req.MaxTokens)
+		require.NotNil(t, req.Seed)
+		assert.Equal(t, 256, *req.MaxTokens)
+		assert.Equal(t, 42, *req.Seed)
+		assert.Equal(t, []string{"###"}, req.Stop)
+
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer server.Close()
+
+	client := newTestOpenAIClient(server.URL)
+
+	numPredict := 256
+	seed := 42
+	_, err := client.Chat(context.Background(), "hi", types.GenerationOptions{NumPredict: &numPredict, Seed: &seed, Stop: []string{"###"}})
+	require.NoError(t, err)
+}
+
+func TestNewProvider(t *testing.T) {
+	t.Setenv("LLM_PROVIDER", "")
+	provider, err := NewProvider()
+	require.NoError(t, err)
+	assert.IsType(t, &OllamaClient{}, provider)
+
+	t.Setenv("LLM_PROVIDER", "OpenAI")
+	provider, err = NewProvider()
+	require.NoError(t, err)
+	assert.IsType(t, &OpenAIClient{}, provider)
+
+	t.Setenv("LLM_PROVIDER", "unknown")
+	_, err = NewProvider()
+	assert.Error(t, err)
+}
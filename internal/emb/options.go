@@ -0,0 +1,40 @@
+package emb
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"tech-docs-ai/internal/types"
+)
+
+// DefaultGenerationOptions reads the default generation options from the
+// LLM_TEMPERATURE, LLM_TOP_P, LLM_NUM_CTX, LLM_NUM_PREDICT, LLM_STOP (comma
+// separated) and LLM_SEED environment variables. Options that are not
+// configured are left to the model's own defaults.
+func DefaultGenerationOptions() types.GenerationOptions {
+	var opts types.GenerationOptions
+
+	if v, err := strconv.ParseFloat(os.Getenv("LLM_TEMPERATURE"), 64); err == nil {
+		opts.Temperature = &v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("LLM_TOP_P"), 64); err == nil {
+		opts.TopP = &v
+	}
+	if v, err := strconv.Atoi(os.Getenv("LLM_NUM_CTX")); err == nil {
+		opts.NumCtx = &v
+	}
+	if v, err := strconv.Atoi(os.Getenv("LLM_NUM_PREDICT")); err == nil {
+		opts.NumPredict = &v
+	}
+	if v, err := strconv.Atoi(os.Getenv("LLM_SEED")); err == nil {
+		opts.Seed = &v
+	}
+	for _, stop := range strings.Split(os.Getenv("LLM_STOP"), ",") {
+		if stop = strings.TrimSpace(stop); stop != "" {
+			opts.Stop = append(opts.Stop, stop)
+		}
+	}
+
+	return opts
+}
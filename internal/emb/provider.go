@@ -0,0 +1,72 @@
+package emb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"tech-docs-ai/internal/types"
+)
+
+// Supported values for the LLM_PROVIDER environment variable.
+const (
+	ProviderOllama = "ollama"
+	ProviderOpenAI = "openai"
+)
+
+// Provider is an LLM backend that can embed text and generate chat responses.
+type Provider interface {
+	// Embed returns the vector representation of text.
+	Embed(ctx context.Context, text string) ([]float32, error)
+	// EmbedBatch returns the vector representation of each text, in input order.
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+	// Chat returns the model's response to message. Unset options fall back to the configured defaults.
+	Chat(ctx context.Context, message string, opts types.GenerationOptions) (string, error)
+	// ChatStream is like Chat but calls onToken with each chunk as it is generated.
+	ChatStream(ctx context.Context, message string, opts types.GenerationOptions, onToken func(token string) error) (string, error)
+	// ChatMessages returns the assistant's reply to a role-structured conversation.
+	ChatMessages(ctx context.Context, messages []types.LLMMessage, opts types.GenerationOptions) (string, error)
+	// ChatMessagesStream is like ChatMessages but calls onToken with each chunk as it is generated.
+	ChatMessagesStream(ctx context.Context, messages []types.LLMMessage, opts types.GenerationOptions, onToken func(token string) error) (string, error)
+}
+
+// NewProvider creates the LLM provider selected by the LLM_PROVIDER environment
+// variable. It defaults to Ollama.
+func NewProvider() (Provider, error) {
+	name := strings.ToLower(strings.TrimSpace(os.Getenv("LLM_PROVIDER")))
+
+	switch name {
+	case "", ProviderOllama:
+		return NewOllamaClient(), nil
+	case ProviderOpenAI:
+		return NewOpenAIClient(), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", name)
+	}
+}
+
+// MaxEmbedBatchSize is the largest number of texts sent in one embedding request.
+const MaxEmbedBatchSize = 64
+
+// embedInBatches splits texts into batches of at most MaxEmbedBatchSize, embeds
+// each with embed and checks that every text got exactly one vector.
+func embedInBatches(texts []string, embed func(batch []string) ([][]float32, error)) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += MaxEmbedBatchSize {
+		end := start + MaxEmbedBatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		batch, err := embed(texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) != end-start {
+			return nil, fmt.Errorf("embed returned %d vectors for %d texts", len(batch), end-start)
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
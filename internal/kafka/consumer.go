@@ -2,15 +2,21 @@ package kafka
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
+	"tech-docs-ai/internal/cache"
+	"tech-docs-ai/internal/chunk"
 	"tech-docs-ai/internal/emb"
-	"tech-docs-ai/internal/repo"
 	"tech-docs-ai/internal/scraper"
 	"tech-docs-ai/internal/types"
 	"tech-docs-ai/internal/vec"
@@ -18,19 +24,54 @@ import (
 	"github.com/segmentio/kafka-go"
 )
 
+// jobTimeout bounds how long a single scrape job may spend scraping, embedding and storing.
+const jobTimeout = 10 * time.Minute
+
+// commitTimeout bounds committing the offset of a finished job.
+const commitTimeout = 10 * time.Second
+
+// jobStore is an interface for storing documents and tracking scrape jobs and scraped pages.
+type jobStore interface {
+	StoreDocument(ctx context.Context, doc *types.Document) error
+	FindDocumentByURL(ctx context.Context, url string) (*types.Document, error)
+	StartScrapeJob(ctx context.Context, job types.ScrapeJob, attempt int) error
+	FinishScrapeJob(ctx context.Context, jobID string, status types.JobStatus, errMsg string, documentIDs []string) error
+	DeadLetterScrapeJob(ctx context.Context, jobID string, errMsg string) error
+	CreateCrawlPage(ctx context.Context, page *types.ScrapeJobRecord, maxPages int) (bool, error)
+	FinishCrawl(ctx context.Context, crawlID string) (bool, error)
+	GetScrapedPage(ctx context.Context, url string) (*types.ScrapedPage, error)
+	SaveScrapedPage(ctx context.Context, page *types.ScrapedPage) error
+}
+
+// messageProducer is an interface for sending messages to Kafka topics.
+type messageProducer interface {
+	Send(ctx context.Context, m kafka.Message) error
+	Close() error
+}
+
 // Consumer is a Kafka consumer for processing scraping jobs.
 type Consumer struct {
 	reader          *kafka.Reader
+	retryReader     *kafka.Reader
+	offsets         *offsetTracker
+	retryOffsets    *offsetTracker
+	producer        messageProducer
+	retry           retryPolicy
 	w3schoolsScraper *scraper.W3SchoolsScraper
 	universalScraper *scraper.UniversalScraper
-	docStore        *repo.PostgresStore
-	embClient       *emb.OllamaClient
-	vecClient       *vec.QdrantClient
+	sitemapScraper   *scraper.SitemapScraper
+	docStore        jobStore
+	embClient       emb.Provider
+	vecClient       vec.Store
+	docCache        cache.DocumentCache
+	searchCache     cache.SearchCache
+	splitter        *chunk.Splitter
 	workerPool      *WorkerPool
 }
 
-// NewConsumer creates a new Kafka consumer.
-func NewConsumer(docStore *repo.PostgresStore, embClient *emb.OllamaClient, vecClient *vec.QdrantClient) *Consumer {
+// NewConsumer creates a new Kafka consumer. Documents it stores are evicted
+// from the server's cache in redisCache.
+func NewConsumer(docStore jobStore, embClient emb.Provider, vecClient vec.Store, redisCache *cache.RedisCache) *Consumer {
 	kafkaURL := os.Getenv("KAFKA_URL")
 	if kafkaURL == "" {
 		kafkaURL = "localhost:9092"
@@ -38,123 +79,417 @@ func NewConsumer(docStore *repo.PostgresStore, embClient *emb.OllamaClient, vecC
 
 	reader := kafka.NewReader(kafka.ReaderConfig{
 		Brokers:  []string{kafkaURL},
-		Topic:    "scrape-jobs",
-		GroupID:  "scraper-workers",
+		Topic:    ScrapeJobsTopic,
+		GroupID:  ScrapeJobsGroup,
+		MinBytes: 10e3, // 10KB
+		MaxBytes: 10e6, // 10MB
+	})
+
+	retryReader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:  []string{kafkaURL},
+		Topic:    RetryTopic,
+		GroupID:  RetryGroup,
 		MinBytes: 10e3, // 10KB
 		MaxBytes: 10e6, // 10MB
 	})
 
+	fetcher := scraper.NewFetcher()
+
 	return &Consumer{
 		reader:           reader,
-		w3schoolsScraper: scraper.NewW3SchoolsScraper(),
-		universalScraper: scraper.NewUniversalScraper(),
+		retryReader:      retryReader,
+		offsets:          newOffsetTracker(reader.CommitMessages),
+		retryOffsets:     newOffsetTracker(retryReader.CommitMessages),
+		producer:         NewProducer(),
+		retry:            loadRetryPolicy(),
+		w3schoolsScraper: scraper.NewW3SchoolsScraper(fetcher),
+		universalScraper: scraper.NewUniversalScraper(fetcher),
+		sitemapScraper:   scraper.NewSitemapScraper(fetcher),
 		docStore:         docStore,
 		embClient:        embClient,
 		vecClient:        vecClient,
+		docCache:         redisCache.DocumentCache(),
+		searchCache:      redisCache.SearchCache(),
+		splitter:         chunk.NewSplitter(),
 		workerPool:       NewWorkerPool(5), // 5 workers
 	}
 }
 
-// Start starts consuming messages from Kafka.
+// Start starts consuming messages from Kafka until ctx is cancelled. A
+// message's offset is committed only after its job has finished, so jobs
+// that are still in flight when the worker stops are delivered again.
 func (c *Consumer) Start(ctx context.Context) error {
 	log.Println("Starting Kafka consumer...")
 
+	go c.consumeRetries(ctx)
+
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
-			m, err := c.reader.ReadMessage(ctx)
+			m, err := c.reader.FetchMessage(ctx)
 			if err != nil {
 				log.Printf("Error reading message: %v", err)
 				continue
 			}
 
 			// Process message in worker pool
-			c.workerPool.Submit(func() {
-				c.processMessage(m)
-			})
+			c.dispatch(c.offsets, m)
 		}
 	}
 }
 
-// processMessage processes a single Kafka message.
-func (c *Consumer) processMessage(m kafka.Message) {
+// consumeRetries processes the messages of the retry topic, waiting for each
+// one's retry time first. Messages are waited for in order, so a retry can
+// run later than its backoff when an earlier message has a longer one.
+func (c *Consumer) consumeRetries(ctx context.Context) {
+	for {
+		m, err := c.retryReader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Error reading retry message: %v", err)
+			continue
+		}
+
+		if wait := time.Until(messageRetryAt(m)); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		c.dispatch(c.retryOffsets, m)
+	}
+}
+
+// dispatch processes a message in the worker pool and commits its offset
+// through offsets once the job has finished. Jobs are not cancelled when the
+// consumer stops, so that Shutdown can let them finish.
+func (c *Consumer) dispatch(offsets *offsetTracker, m kafka.Message) {
+	offsets.track(m)
+
+	c.workerPool.Submit(func() {
+		jobCtx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+		defer cancel()
+		c.processMessage(jobCtx, m)
+
+		commitCtx, cancelCommit := context.WithTimeout(context.Background(), commitTimeout)
+		defer cancelCommit()
+		if err := offsets.done(commitCtx, m); err != nil {
+			log.Printf("Failed to commit offset %d of partition %d: %v", m.Offset, m.Partition, err)
+		}
+	})
+}
+
+// jobStatusTimeout bounds recording a job's outcome, which happens even after the job timed out.
+const jobStatusTimeout = 10 * time.Second
+
+// processMessage processes a single Kafka message and records the job's
+// progress in the jobs table. Failed jobs are retried through the retry topic
+// until they run out of attempts and are dead-lettered. Storage and embedding
+// calls are abandoned once ctx is cancelled or its deadline passes.
+func (c *Consumer) processMessage(ctx context.Context, m kafka.Message) {
 	log.Printf("Processing message: %s", string(m.Value))
 
+	statusCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobStatusTimeout)
+	defer cancel()
+
+	attempt := messageAttempt(m)
+
 	var job types.ScrapeJob
 	if err := json.Unmarshal(m.Value, &job); err != nil {
+		// A malformed message fails the same way every time, so it is not retried
 		log.Printf("Failed to unmarshal job: %v", err)
+		c.deadLetter(statusCtx, m, job, attempt, fmt.Sprintf("failed to unmarshal job: %v", err))
 		return
 	}
 
-	// Check if content already exists for this URL/topic
-	existingDocs, err := c.docStore.SearchDocuments(job.URL, 1)
-	if err == nil && len(existingDocs) > 0 {
-		log.Printf("Content already exists for URL: %s, skipping scrape", job.URL)
-		return
+	if err := c.docStore.StartScrapeJob(ctx, job, attempt); err != nil {
+		log.Printf("Failed to mark job %s as running: %v", job.JobID, err)
 	}
 
-	// Choose appropriate scraper based on URL
-	var content *scraper.ScrapedContent
-	var doc *types.Document
-
-	if strings.Contains(job.URL, "w3schools.com") {
-		// Use W3Schools-specific scraper
-		var err error
-		content, err = c.w3schoolsScraper.ScrapePage(job.URL)
-		if err != nil {
-			log.Printf("Failed to scrape %s with W3Schools scraper: %v", job.URL, err)
-			return
+	result := c.runJob(ctx, job)
+
+	switch result.status {
+	case types.JobFailed:
+		c.handleFailure(statusCtx, m, job, attempt, result.message)
+	case types.JobRunning:
+		// A started crawl or sitemap job finishes with its last page, which may already have run
+		c.finishCrawl(statusCtx, job.JobID)
+	default:
+		if err := c.docStore.FinishScrapeJob(statusCtx, job.JobID, result.status, result.message, result.documentIDs); err != nil {
+			log.Printf("Failed to record outcome of job %s: %v", job.JobID, err)
 		}
-		doc = c.w3schoolsScraper.ConvertToDocument(content)
-	} else {
-		// Use universal scraper for all other URLs
-		var err error
-		content, err = c.universalScraper.ScrapePage(job.URL)
-		if err != nil {
-			log.Printf("Failed to scrape %s with universal scraper: %v", job.URL, err)
+	}
+
+	if job.ParentID != "" {
+		c.finishCrawl(statusCtx, job.ParentID)
+	}
+}
+
+// handleFailure schedules a failed attempt of a job for retry with
+// exponential backoff, or dead-letters the job after its last attempt.
+func (c *Consumer) handleFailure(ctx context.Context, m kafka.Message, job types.ScrapeJob, attempt int, cause string) {
+	if attempt < c.retry.maxAttempts {
+		delay := c.retry.backoff(attempt)
+		err := c.producer.Send(ctx, retryMessage(m, attempt+1, time.Now().Add(delay), cause))
+		if err == nil {
+			log.Printf("Job %s failed attempt %d of %d, retrying in %s", job.JobID, attempt, c.retry.maxAttempts, delay)
+			if err := c.docStore.FinishScrapeJob(ctx, job.JobID, types.JobRetrying, cause, nil); err != nil {
+				log.Printf("Failed to record outcome of job %s: %v", job.JobID, err)
+			}
 			return
 		}
-		doc = c.universalScraper.ConvertToDocument(content)
+		log.Printf("Failed to schedule retry of job %s: %v", job.JobID, err)
+	}
+
+	c.deadLetter(ctx, m, job, attempt, cause)
+}
+
+// deadLetter sends a message that failed for good to the dead-letter topic
+// and marks its job as dead-lettered, so it can be inspected and replayed.
+func (c *Consumer) deadLetter(ctx context.Context, m kafka.Message, job types.ScrapeJob, attempt int, cause string) {
+	if err := c.producer.Send(ctx, deadLetterMessage(m, attempt, cause)); err != nil {
+		log.Printf("Failed to dead-letter message: %v", err)
+	}
+
+	if job.JobID == "" {
+		return
+	}
+
+	log.Printf("Job %s failed after %d attempts and was dead-lettered: %s", job.JobID, attempt, cause)
+	if err := c.docStore.DeadLetterScrapeJob(ctx, job.JobID, cause); err != nil {
+		log.Printf("Failed to record outcome of job %s: %v", job.JobID, err)
+	}
+}
+
+// jobResult is the outcome of a scrape job.
+type jobResult struct {
+	status      types.JobStatus
+	message     string
+	documentIDs []string
+}
+
+// jobFailed logs a job failure and returns it as a result. URLs that
+// robots.txt disallows end the job as disallowed, since retrying cannot help.
+func jobFailed(job types.ScrapeJob, err error) jobResult {
+	if errors.Is(err, scraper.ErrDisallowed) {
+		log.Printf("Job %s for URL %s is disallowed: %v", job.JobID, job.URL, err)
+		return jobResult{status: types.JobDisallowed, message: err.Error()}
+	}
+	log.Printf("Job %s for URL %s failed: %v", job.JobID, job.URL, err)
+	return jobResult{status: types.JobFailed, message: err.Error()}
+}
+
+// runJob scrapes the job's URL and stores the page as a document. Pages of
+// a crawl also queue the pages they link to; crawl and sitemap jobs are run
+// by runCrawl and runSitemap.
+func (c *Consumer) runJob(ctx context.Context, job types.ScrapeJob) jobResult {
+	switch job.Type {
+	case types.JobTypeCrawl:
+		return c.runCrawl(ctx, job)
+	case types.JobTypeSitemap:
+		return c.runSitemap(ctx, job)
+	}
+
+	// Pages whose links are followed need their body even when unchanged
+	followLinks := job.Crawl != nil && job.Depth < job.Crawl.MaxDepth
+
+	// A page fetched before is re-fetched conditionally on having changed.
+	// New category or tag overrides may change the document of an unchanged
+	// page, so they are compared through the content hash instead.
+	stored, err := c.storedPage(ctx, job.URL)
+	if err != nil {
+		return jobFailed(job, err)
+	}
+	overrides := overridesHash(job)
+	var validators scraper.Validators
+	if stored != nil && !followLinks && !job.Refresh && stored.OverridesHash == overrides {
+		validators = scraper.Validators{ETag: stored.ETag, LastModified: stored.LastModified}
+	}
+
+	content, doc, err := c.scrapePage(job.URL, validators)
+	if errors.Is(err, scraper.ErrNotModified) && stored != nil {
+		stored.FetchedAt = time.Now()
+		c.saveScrapedPage(ctx, stored)
+		log.Printf("Page not modified: %s, skipping", job.URL)
+		return jobResult{status: types.JobSkipped, message: "content not modified", documentIDs: []string{stored.DocumentID}}
+	}
+	if err != nil {
+		return jobFailed(job, err)
+	}
+
+	if followLinks {
+		c.followLinks(ctx, job, content.Links)
 	}
 
 	// Override category and tags if provided in job
 	if job.Category != "" {
 		doc.Category = job.Category
 	}
-	if len(job.Tags) > 0 {
-		doc.Tags = append(doc.Tags, job.Tags...)
+	doc.Tags = mergeTags(doc.Tags, job.Tags)
+
+	now := time.Now()
+	page := &types.ScrapedPage{
+		URL:           job.URL,
+		ETag:          content.Validators.ETag,
+		LastModified:  content.Validators.LastModified,
+		ContentHash:   contentHash(doc),
+		OverridesHash: overrides,
+		FetchedAt:     now,
+		ChangedAt:     now,
+	}
+	if stored != nil {
+		if stored.ContentHash == page.ContentHash {
+			page.DocumentID = stored.DocumentID
+			page.ChangedAt = stored.ChangedAt
+			c.saveScrapedPage(ctx, page)
+			log.Printf("Content unchanged for URL: %s, not storing it again", job.URL)
+			return jobResult{status: types.JobSkipped, message: "content unchanged", documentIDs: []string{stored.DocumentID}}
+		}
+
+		// Update the stored document in place; storing it replaces its vectors
+		doc.ID = stored.DocumentID
 	}
 
 	// Store document and vector
-	if err := c.storeDocumentWithVector(doc, job.URL); err != nil {
-		log.Printf("Failed to store document: %v", err)
-		return
+	if err := c.storeDocumentWithVector(ctx, doc, job.URL); err != nil {
+		return jobFailed(job, fmt.Errorf("failed to store document: %w", err))
 	}
+	c.invalidateCache(ctx, doc.ID)
+
+	page.DocumentID = doc.ID
+	c.saveScrapedPage(ctx, page)
 
 	log.Printf("Successfully processed job %s for URL: %s", job.JobID, job.URL)
+	return jobResult{status: types.JobSucceeded, documentIDs: []string{doc.ID}}
 }
 
-// storeDocumentWithVector stores a document in both database and vector store.
-func (c *Consumer) storeDocumentWithVector(doc *types.Document, url string) error {
-	// Generate embedding for the document content
-	vector, err := c.embClient.Embed(doc.Content)
+// scrapePage scrapes url with the scraper suited to its site and converts
+// it to a document. With validators of an earlier fetch it returns an error
+// wrapping scraper.ErrNotModified if the page has not changed since.
+func (c *Consumer) scrapePage(url string, validators scraper.Validators) (*scraper.ScrapedContent, *types.Document, error) {
+	if strings.Contains(url, "w3schools.com") {
+		// Use W3Schools-specific scraper
+		content, err := c.w3schoolsScraper.ScrapePageIfModified(url, validators)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scrape with W3Schools scraper: %w", err)
+		}
+		return content, c.w3schoolsScraper.ConvertToDocument(content), nil
+	}
+
+	// Use universal scraper for all other URLs
+	content, err := c.universalScraper.ScrapePageIfModified(url, validators)
 	if err != nil {
-		return fmt.Errorf("failed to embed document: %w", err)
+		return nil, nil, fmt.Errorf("failed to scrape with universal scraper: %w", err)
 	}
+	return content, c.universalScraper.ConvertToDocument(content), nil
+}
 
-	// Store document in database
-	if err := c.docStore.StoreDocument(doc); err != nil {
-		return fmt.Errorf("failed to store document: %w", err)
+// storedPage returns the record of the page last scraped from url, or nil
+// if it was never stored. Pages stored before records were kept get one
+// built from their document, without validators.
+func (c *Consumer) storedPage(ctx context.Context, url string) (*types.ScrapedPage, error) {
+	page, err := c.docStore.GetScrapedPage(ctx, url)
+	if err != nil || page != nil {
+		return page, err
+	}
+
+	doc, err := c.docStore.FindDocumentByURL(ctx, url)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	return &types.ScrapedPage{
+		URL:         url,
+		DocumentID:  doc.ID,
+		ContentHash: contentHash(doc),
+		FetchedAt:   doc.UpdatedAt,
+		ChangedAt:   doc.UpdatedAt,
+	}, nil
+}
+
+// saveScrapedPage records a fetched page. Failures are only logged, as the
+// document is stored either way and the next scrape falls back to it.
+func (c *Consumer) saveScrapedPage(ctx context.Context, page *types.ScrapedPage) {
+	if err := c.docStore.SaveScrapedPage(ctx, page); err != nil {
+		log.Printf("Failed to record scraped page %s: %v", page.URL, err)
+	}
+}
+
+// contentHash fingerprints the stored fields of a document, to tell whether
+// a page or the category and tags of its job changed since it was stored.
+func contentHash(doc *types.Document) string {
+	fields, _ := json.Marshal([]interface{}{doc.Title, doc.Content, doc.Category, doc.Tags})
+	sum := sha256.Sum256(fields)
+	return hex.EncodeToString(sum[:])
+}
+
+// invalidateCache evicts a stored document from the server's cache, along with
+// the cached search results, which may hold its previous revision.
+func (c *Consumer) invalidateCache(ctx context.Context, docID string) {
+	if err := c.docCache.Delete(ctx, docID); err != nil {
+		log.Printf("Failed to delete document %s from cache: %v", docID, err)
+	}
+	if err := c.searchCache.Clear(ctx); err != nil {
+		log.Printf("Failed to clear search cache: %v", err)
 	}
+}
+
+// overridesHash fingerprints the category and tags a job sets on its page,
+// or returns "" for a job that sets neither.
+func overridesHash(job types.ScrapeJob) string {
+	if job.Category == "" && len(job.Tags) == 0 {
+		return ""
+	}
+	fields, _ := json.Marshal([]interface{}{job.Category, mergeTags(nil, job.Tags)})
+	sum := sha256.Sum256(fields)
+	return hex.EncodeToString(sum[:])
+}
 
-	// Store vector with document metadata
+// mergeTags returns the tags of both lists once each, sorted, so a document's
+// tags and content hash do not depend on which list or order a tag came in.
+func mergeTags(tags, extra []string) []string {
+	seen := make(map[string]bool)
+	merged := []string{}
+	for _, tag := range append(append([]string{}, tags...), extra...) {
+		if !seen[tag] {
+			seen[tag] = true
+			merged = append(merged, tag)
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// storeDocumentWithVector stores a document in the database and one vector per chunk in the vector store.
+func (c *Consumer) storeDocumentWithVector(ctx context.Context, doc *types.Document, url string) error {
+	// Generate embeddings for all chunks of the document content in one batch
+	chunks := c.splitter.Split(doc.Content)
+	texts := make([]string, len(chunks))
+	for i, ch := range chunks {
+		texts[i] = ch.EmbeddingText(doc.Title)
+	}
+
+	vectors, err := c.embClient.EmbedBatch(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed %d chunks: %w", len(chunks), err)
+	}
+
+	// Store vectors with document metadata
 	source := "universal"
 	if strings.Contains(url, "w3schools.com") {
 		source = "w3schools"
 	}
-	
+
+	// The ID is needed in the payloads before the document is stored
+	if doc.ID == "" {
+		doc.ID = fmt.Sprintf("doc_%d", time.Now().UnixNano())
+	}
+
 	metadata := map[string]interface{}{
 		"document_id": doc.ID,
 		"title":       doc.Title,
@@ -164,15 +499,70 @@ func (c *Consumer) storeDocumentWithVector(doc *types.Document, url string) erro
 		"source":      source,
 	}
 
-	if err := c.vecClient.StoreVector(vector, metadata); err != nil {
-		return fmt.Errorf("failed to store vector: %w", err)
+	points := make([]types.VectorPoint, len(chunks))
+	for i, ch := range chunks {
+		points[i] = types.VectorPoint{
+			DocumentID: doc.ID,
+			ChunkIndex: ch.Index,
+			Vector:     vectors[i],
+			Payload:    ch.Payload(metadata),
+		}
+	}
+
+	// Stores that share the documents' database write both in one transaction
+	if indexer, ok := c.vecClient.(vec.DocumentIndexer); ok {
+		if err := indexer.StoreDocumentWithVectors(ctx, doc, points); err != nil {
+			return fmt.Errorf("failed to store document with vectors: %w", err)
+		}
+		log.Printf("Indexed document %s as %d chunks", doc.ID, len(chunks))
+		return nil
+	}
+
+	// Store document in database
+	if err := c.docStore.StoreDocument(ctx, doc); err != nil {
+		return fmt.Errorf("failed to store document: %w", err)
+	}
+
+	// Overwrite the previous revision's chunks in place, then drop those past its new end
+	if err := c.vecClient.StoreVectors(ctx, points); err != nil {
+		return fmt.Errorf("failed to store chunk vectors: %w", err)
+	}
+
+	if err := c.vecClient.DeleteStaleChunks(ctx, doc.ID, len(points)); err != nil {
+		return fmt.Errorf("failed to delete stale vectors: %w", err)
 	}
 
+	log.Printf("Indexed document %s as %d chunks", doc.ID, len(chunks))
+
 	return nil
 }
 
-// Close closes the Kafka consumer.
+// Shutdown stops the worker pool from starting new jobs and waits until the
+// jobs in flight have finished and committed their offsets, or until ctx is done.
+func (c *Consumer) Shutdown(ctx context.Context) error {
+	drained := make(chan struct{})
+	go func() {
+		c.workerPool.Shutdown()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close closes the Kafka consumer. Call it after Shutdown, as finishing jobs
+// commit their offsets through the readers.
 func (c *Consumer) Close() error {
+	if err := c.retryReader.Close(); err != nil {
+		log.Printf("Failed to close retry reader: %v", err)
+	}
+	if err := c.producer.Close(); err != nil {
+		log.Printf("Failed to close producer: %v", err)
+	}
 	return c.reader.Close()
 }
 
@@ -0,0 +1,446 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"tech-docs-ai/internal/cache"
+	"tech-docs-ai/internal/chunk"
+	"tech-docs-ai/internal/emb"
+	"tech-docs-ai/internal/scraper"
+	"tech-docs-ai/internal/types"
+	"tech-docs-ai/internal/vec"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJob is a job's record in fakeJobStore.
+type fakeJob struct {
+	status       types.JobStatus
+	message      string
+	documentIDs  []string
+	attempt      int
+	deadLettered bool
+}
+
+// fakeJobStore keeps documents, jobs and scraped pages in memory.
+type fakeJobStore struct {
+	docs  map[string]*types.Document
+	jobs  map[string]*fakeJob
+	pages map[string]*types.ScrapedPage
+}
+
+func newFakeJobStore() *fakeJobStore {
+	return &fakeJobStore{
+		docs:  make(map[string]*types.Document),
+		jobs:  make(map[string]*fakeJob),
+		pages: make(map[string]*types.ScrapedPage),
+	}
+}
+
+func (s *fakeJobStore) StoreDocument(ctx context.Context, doc *types.Document) error {
+	stored := *doc
+	s.docs[doc.ID] = &stored
+	return nil
+}
+
+func (s *fakeJobStore) FindDocumentByURL(ctx context.Context, url string) (*types.Document, error) {
+	for _, doc := range s.docs {
+		if doc.Metadata["url"] == url {
+			return doc, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeJobStore) StartScrapeJob(ctx context.Context, job types.ScrapeJob, attempt int) error {
+	s.jobs[job.JobID] = &fakeJob{status: types.JobRunning, attempt: attempt}
+	return nil
+}
+
+func (s *fakeJobStore) FinishScrapeJob(ctx context.Context, jobID string, status types.JobStatus, errMsg string, documentIDs []string) error {
+	job := s.jobs[jobID]
+	job.status, job.message, job.documentIDs = status, errMsg, documentIDs
+	return nil
+}
+
+func (s *fakeJobStore) DeadLetterScrapeJob(ctx context.Context, jobID string, errMsg string) error {
+	job := s.jobs[jobID]
+	job.status, job.message, job.deadLettered = types.JobFailed, errMsg, true
+	return nil
+}
+
+func (s *fakeJobStore) CreateCrawlPage(ctx context.Context, page *types.ScrapeJobRecord, maxPages int) (bool, error) {
+	return false, nil
+}
+
+func (s *fakeJobStore) FinishCrawl(ctx context.Context, crawlID string) (bool, error) {
+	return false, nil
+}
+
+func (s *fakeJobStore) GetScrapedPage(ctx context.Context, url string) (*types.ScrapedPage, error) {
+	if page, ok := s.pages[url]; ok {
+		stored := *page
+		return &stored, nil
+	}
+	return nil, nil
+}
+
+func (s *fakeJobStore) SaveScrapedPage(ctx context.Context, page *types.ScrapedPage) error {
+	stored := *page
+	s.pages[page.URL] = &stored
+	return nil
+}
+
+// fakeProducer records sent messages. Sends to failTopic fail.
+type fakeProducer struct {
+	sent      []kafka.Message
+	failTopic string
+}
+
+func (p *fakeProducer) Send(ctx context.Context, m kafka.Message) error {
+	if m.Topic == p.failTopic {
+		return errors.New("broker unavailable")
+	}
+	p.sent = append(p.sent, m)
+	return nil
+}
+
+func (p *fakeProducer) Close() error { return nil }
+
+func (p *fakeProducer) topics() []string {
+	topics := []string{}
+	for _, m := range p.sent {
+		topics = append(topics, m.Topic)
+	}
+	return topics
+}
+
+// fakeEmbedder embeds every text as the same vector.
+type fakeEmbedder struct {
+	emb.Provider
+}
+
+func (fakeEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = []float32{1, 0}
+	}
+	return vectors, nil
+}
+
+// fakeDocumentCache records the documents evicted from it.
+type fakeDocumentCache struct {
+	cache.DocumentCache
+	deleted []string
+}
+
+func (c *fakeDocumentCache) Delete(ctx context.Context, id string) error {
+	c.deleted = append(c.deleted, id)
+	return nil
+}
+
+// fakeSearchCache counts how often it was cleared.
+type fakeSearchCache struct {
+	cache.SearchCache
+	cleared int
+}
+
+func (c *fakeSearchCache) Clear(ctx context.Context) error {
+	c.cleared++
+	return nil
+}
+
+// testSite serves one documentation page with an ETag, answering requests
+// for the current revision with 304 Not Modified.
+type testSite struct {
+	title    string
+	etag     string
+	status   int
+	disallow bool
+}
+
+func (site *testSite) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/robots.txt" {
+		if site.disallow {
+			w.Write([]byte("User-agent: *\nDisallow: /\n"))
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+	if site.status != 0 {
+		w.WriteHeader(site.status)
+		return
+	}
+	if r.Header.Get("If-None-Match") == site.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", site.etag)
+	fmt.Fprintf(w, "<html><head><title>%[1]s</title></head><body><main><h1>%[1]s</h1><p>Goroutines are lightweight threads managed by the Go runtime.</p></main></body></html>", site.title)
+}
+
+// consumerFixture is a consumer whose dependencies are fakes.
+type consumerFixture struct {
+	*Consumer
+	store       *fakeJobStore
+	producer    *fakeProducer
+	docCache    *fakeDocumentCache
+	searchCache *fakeSearchCache
+	site        *testSite
+	url         string
+}
+
+func newConsumerFixture(t *testing.T) *consumerFixture {
+	t.Setenv("SCRAPE_HOST_DELAY", "0")
+
+	site := &testSite{title: "Goroutines", etag: `"v1"`}
+	server := httptest.NewServer(site)
+	t.Cleanup(server.Close)
+
+	f := &consumerFixture{
+		store:       newFakeJobStore(),
+		producer:    &fakeProducer{},
+		docCache:    &fakeDocumentCache{},
+		searchCache: &fakeSearchCache{},
+		site:        site,
+		url:         server.URL + "/docs/goroutines",
+	}
+	fetcher := scraper.NewFetcher()
+	f.Consumer = &Consumer{
+		producer:         f.producer,
+		retry:            retryPolicy{maxAttempts: 3, baseDelay: time.Second, maxDelay: time.Minute},
+		w3schoolsScraper: scraper.NewW3SchoolsScraper(fetcher),
+		universalScraper: scraper.NewUniversalScraper(fetcher),
+		sitemapScraper:   scraper.NewSitemapScraper(fetcher),
+		docStore:         f.store,
+		embClient:        fakeEmbedder{},
+		vecClient:        vec.NewMemoryStore(vec.DistanceCosine),
+		docCache:         f.docCache,
+		searchCache:      f.searchCache,
+		splitter:         chunk.NewSplitter(),
+	}
+	return f
+}
+
+// message returns the Kafka message of job for the given attempt.
+func (f *consumerFixture) message(t *testing.T, job types.ScrapeJob, attempt int) kafka.Message {
+	value, err := json.Marshal(job)
+	require.NoError(t, err)
+	m := kafka.Message{Topic: ScrapeJobsTopic, Key: []byte(job.JobID), Value: value}
+	if attempt > 1 {
+		m.Headers = []kafka.Header{{Key: attemptHeader, Value: []byte(strconv.Itoa(attempt))}}
+	}
+	return m
+}
+
+func TestConsumer_ProcessMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		disallow  bool
+		attempt   int
+		failTopic string
+		malformed bool
+
+		wantStatus       types.JobStatus
+		wantTopics       []string
+		wantDeadLettered bool
+		wantStored       bool
+	}{
+		{name: "succeeded", attempt: 1, wantStatus: types.JobSucceeded, wantTopics: []string{}, wantStored: true},
+		{name: "retried", status: http.StatusInternalServerError, attempt: 1, wantStatus: types.JobRetrying, wantTopics: []string{RetryTopic}},
+		{name: "dead-lettered after the last attempt", status: http.StatusInternalServerError, attempt: 3,
+			wantStatus: types.JobFailed, wantTopics: []string{DeadLetterTopic}, wantDeadLettered: true},
+		{name: "dead-lettered when the retry cannot be sent", status: http.StatusInternalServerError, attempt: 1, failTopic: RetryTopic,
+			wantStatus: types.JobFailed, wantTopics: []string{DeadLetterTopic}, wantDeadLettered: true},
+		{name: "disallowed by robots.txt", disallow: true, attempt: 1, wantStatus: types.JobDisallowed, wantTopics: []string{}},
+		{name: "malformed message", malformed: true, attempt: 1, wantTopics: []string{DeadLetterTopic}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newConsumerFixture(t)
+			f.site.status, f.site.disallow = tt.status, tt.disallow
+			f.producer.failTopic = tt.failTopic
+
+			m := f.message(t, types.ScrapeJob{URL: f.url, JobID: "job_1"}, tt.attempt)
+			if tt.malformed {
+				m.Value = []byte("{")
+			}
+			f.processMessage(context.Background(), m)
+
+			assert.Equal(t, tt.wantTopics, f.producer.topics())
+			if tt.malformed {
+				assert.Empty(t, f.store.jobs)
+				return
+			}
+
+			job := f.store.jobs["job_1"]
+			require.NotNil(t, job)
+			assert.Equal(t, tt.attempt, job.attempt)
+			assert.Equal(t, tt.wantStatus, job.status)
+			assert.Equal(t, tt.wantDeadLettered, job.deadLettered)
+
+			if tt.wantStatus == types.JobRetrying {
+				assert.Equal(t, 2, messageAttempt(f.producer.sent[0]))
+				assert.True(t, messageRetryAt(f.producer.sent[0]).After(time.Now()))
+			}
+
+			if !tt.wantStored {
+				assert.Empty(t, f.store.docs)
+				assert.Empty(t, f.docCache.deleted)
+				return
+			}
+			require.Len(t, job.documentIDs, 1)
+			docID := job.documentIDs[0]
+			assert.Contains(t, f.store.docs, docID)
+			assert.Equal(t, []string{docID}, f.docCache.deleted)
+			assert.Equal(t, 1, f.searchCache.cleared)
+			assert.Equal(t, `"v1"`, f.store.pages[f.url].ETag)
+		})
+	}
+}
+
+func TestConsumer_RunJob_Revisit(t *testing.T) {
+	tests := []struct {
+		name string
+		// tags are set on both runs of the job
+		tags   []string
+		change func(site *testSite, job *types.ScrapeJob)
+
+		wantStatus  types.JobStatus
+		wantMessage string
+		wantTitle   string
+	}{
+		{name: "not modified", change: func(site *testSite, job *types.ScrapeJob) {},
+			wantStatus: types.JobSkipped, wantMessage: "content not modified", wantTitle: "Goroutines"},
+		{name: "new validators, same content", change: func(site *testSite, job *types.ScrapeJob) { site.etag = `"v2"` },
+			wantStatus: types.JobSkipped, wantMessage: "content unchanged", wantTitle: "Goroutines"},
+		{name: "changed content", change: func(site *testSite, job *types.ScrapeJob) { site.etag, site.title = `"v2"`, "Goroutines and Channels" },
+			wantStatus: types.JobSucceeded, wantTitle: "Goroutines and Channels"},
+		{name: "changed category", change: func(site *testSite, job *types.ScrapeJob) { job.Category = "Concurrency" },
+			wantStatus: types.JobSucceeded, wantTitle: "Goroutines"},
+		{name: "same tags, not modified", tags: []string{"concurrency", "runtime"}, change: func(site *testSite, job *types.ScrapeJob) {},
+			wantStatus: types.JobSkipped, wantMessage: "content not modified", wantTitle: "Goroutines"},
+		{name: "same tags in another order", tags: []string{"concurrency", "runtime"},
+			change:     func(site *testSite, job *types.ScrapeJob) { job.Tags = []string{"runtime", "concurrency"} },
+			wantStatus: types.JobSkipped, wantMessage: "content not modified", wantTitle: "Goroutines"},
+		{name: "changed tags", tags: []string{"concurrency"},
+			change:     func(site *testSite, job *types.ScrapeJob) { job.Tags = []string{"concurrency", "runtime"} },
+			wantStatus: types.JobSucceeded, wantTitle: "Goroutines"},
+		{name: "refresh", change: func(site *testSite, job *types.ScrapeJob) { job.Refresh = true },
+			wantStatus: types.JobSkipped, wantMessage: "content unchanged", wantTitle: "Goroutines"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newConsumerFixture(t)
+			job := types.ScrapeJob{URL: f.url, JobID: "job_1", Tags: tt.tags}
+
+			first := f.runJob(ctx, job)
+			require.Equal(t, types.JobSucceeded, first.status, first.message)
+			docID := first.documentIDs[0]
+
+			tt.change(f.site, &job)
+			result := f.runJob(ctx, job)
+
+			assert.Equal(t, tt.wantStatus, result.status)
+			assert.Equal(t, tt.wantMessage, result.message)
+			assert.Equal(t, []string{docID}, result.documentIDs, "the page keeps its document")
+
+			require.Len(t, f.store.docs, 1)
+			assert.Equal(t, tt.wantTitle, f.store.docs[docID].Title)
+			assert.Equal(t, docID, f.store.pages[f.url].DocumentID)
+
+			if tt.wantStatus == types.JobSucceeded {
+				// The updated document is evicted from the server's cache
+				assert.Equal(t, []string{docID, docID}, f.docCache.deleted)
+				assert.Equal(t, f.site.etag, f.store.pages[f.url].ETag)
+			} else {
+				assert.Equal(t, []string{docID}, f.docCache.deleted)
+			}
+		})
+	}
+}
+
+func TestConsumer_Dispatch_CommitsAfterFinishing(t *testing.T) {
+	f := newConsumerFixture(t)
+	f.workerPool = NewWorkerPool(1)
+	defer f.workerPool.Shutdown()
+
+	committed := make(chan kafka.Message, 1)
+	offsets := newOffsetTracker(func(ctx context.Context, msgs ...kafka.Message) error {
+		for _, m := range msgs {
+			committed <- m
+		}
+		return nil
+	})
+
+	m := f.message(t, types.ScrapeJob{URL: f.url, JobID: "job_1"}, 1)
+	m.Offset = 7
+	f.dispatch(offsets, m)
+
+	select {
+	case c := <-committed:
+		assert.Equal(t, int64(7), c.Offset)
+		// The job's outcome is recorded before its offset is committed
+		assert.Equal(t, types.JobSucceeded, f.store.jobs["job_1"].status)
+	case <-time.After(10 * time.Second):
+		t.Fatal("offset was not committed")
+	}
+}
+
+func TestConsumer_RunJob_SameCategoryNotModified(t *testing.T) {
+	ctx := context.Background()
+	f := newConsumerFixture(t)
+	job := types.ScrapeJob{URL: f.url, JobID: "job_1", Category: "Concurrency"}
+
+	first := f.runJob(ctx, job)
+	require.Equal(t, types.JobSucceeded, first.status, first.message)
+
+	// The stored override matches, so the re-fetch is conditional
+	result := f.runJob(ctx, job)
+	assert.Equal(t, types.JobSkipped, result.status)
+	assert.Equal(t, "content not modified", result.message)
+	assert.Equal(t, "Concurrency", f.store.docs[first.documentIDs[0]].Category)
+}
+
+func TestMergeTags(t *testing.T) {
+	assert.Equal(t, []string{"api", "go", "tutorial"}, mergeTags([]string{"go", "tutorial", "go"}, []string{"tutorial", "api"}))
+	assert.Equal(t, []string{}, mergeTags(nil, nil))
+}
+
+func TestOverridesHash(t *testing.T) {
+	assert.Empty(t, overridesHash(types.ScrapeJob{URL: "https://example.com"}))
+
+	hash := overridesHash(types.ScrapeJob{Category: "Go", Tags: []string{"a", "b"}})
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, overridesHash(types.ScrapeJob{Category: "Go", Tags: []string{"b", "a", "a"}}))
+	assert.NotEqual(t, hash, overridesHash(types.ScrapeJob{Category: "Go", Tags: []string{"a"}}))
+	assert.NotEqual(t, hash, overridesHash(types.ScrapeJob{Category: "Rust", Tags: []string{"a", "b"}}))
+}
+
+func TestContentHash(t *testing.T) {
+	doc := &types.Document{Title: "Intro", Content: "Go is a language.", Category: "Go", Tags: []string{"basics"}}
+	hash := contentHash(doc)
+
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, contentHash(&types.Document{Title: "Intro", Content: "Go is a language.", Category: "Go", Tags: []string{"basics"}, Author: "Someone"}),
+		"the author does not count")
+	assert.NotEqual(t, hash, contentHash(&types.Document{Title: "Intro", Content: "Go is a programming language.", Category: "Go", Tags: []string{"basics"}}))
+	assert.NotEqual(t, hash, contentHash(&types.Document{Title: "Intro", Content: "Go is a language.", Category: "Golang", Tags: []string{"basics"}}))
+	assert.NotEqual(t, hash, contentHash(&types.Document{Title: "Intro", Content: "Go is a language.", Category: "Go", Tags: []string{"basics", "tour"}}))
+	assert.NotEqual(t, contentHash(&types.Document{Title: "a", Content: "b"}), contentHash(&types.Document{Title: "ab"}))
+}
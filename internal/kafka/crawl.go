@@ -0,0 +1,229 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"tech-docs-ai/internal/scraper"
+	"tech-docs-ai/internal/types"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// skippedExtensions are file types a crawl does not follow links to, as they are not pages.
+var skippedExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".ico": true,
+	".css": true, ".js": true, ".json": true, ".xml": true, ".pdf": true, ".zip": true, ".gz": true,
+	".tar": true, ".mp3": true, ".mp4": true, ".webm": true, ".woff": true, ".woff2": true, ".ttf": true,
+}
+
+// crawlScope decides which linked pages a crawl visits.
+type crawlScope struct {
+	host    string
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// newCrawlScope compiles the scope of a crawl from its seed URL and options.
+func newCrawlScope(seedURL string, opts *types.CrawlOptions) (*crawlScope, error) {
+	scope := &crawlScope{}
+
+	if opts.SameDomain {
+		seed, err := url.Parse(seedURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid seed URL: %w", err)
+		}
+		scope.host = canonicalHost(seed.Hostname())
+	}
+
+	var err error
+	if scope.include, err = compilePatterns(opts.Include); err != nil {
+		return nil, err
+	}
+	if scope.exclude, err = compilePatterns(opts.Exclude); err != nil {
+		return nil, err
+	}
+
+	return scope, nil
+}
+
+// compilePatterns compiles URL patterns.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// canonicalHost lowercases a host name and drops a leading "www.", so that
+// example.com and www.example.com count as the same domain.
+func canonicalHost(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
+
+// allows reports whether the crawl visits the page at link.
+func (s *crawlScope) allows(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	if s.host != "" && canonicalHost(u.Hostname()) != s.host {
+		return false
+	}
+	if skippedExtensions[strings.ToLower(path.Ext(u.Path))] {
+		return false
+	}
+
+	if len(s.include) > 0 {
+		included := false
+		for _, re := range s.include {
+			if re.MatchString(link) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, re := range s.exclude {
+		if re.MatchString(link) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// runCrawl starts a crawl by queuing its seed URL as the first page. The
+// crawl stays running until all of its pages have finished.
+func (c *Consumer) runCrawl(ctx context.Context, job types.ScrapeJob) jobResult {
+	if job.Crawl == nil {
+		return jobFailed(job, fmt.Errorf("crawl job has no crawl options"))
+	}
+
+	seedURL, ok := scraper.NormalizeURL(job.URL)
+	if !ok {
+		return jobFailed(job, fmt.Errorf("invalid seed URL %q", job.URL))
+	}
+
+	page := types.ScrapeJob{
+		URL:      seedURL,
+		Category: job.Category,
+		Tags:     job.Tags,
+		ParentID: job.JobID,
+		Crawl:    job.Crawl,
+	}
+	if _, err := c.enqueuePage(ctx, page, job.Crawl.MaxPages); err != nil {
+		return jobFailed(job, fmt.Errorf("failed to queue seed page: %w", err))
+	}
+
+	log.Printf("Started crawl %s from %s", job.JobID, seedURL)
+	return jobResult{status: types.JobRunning}
+}
+
+// followLinks queues the in-scope links of a crawled page as pages one level
+// deeper, unless the page is already at the crawl's maximum depth.
+func (c *Consumer) followLinks(ctx context.Context, job types.ScrapeJob, links []string) {
+	if job.Crawl == nil || job.Depth >= job.Crawl.MaxDepth {
+		return
+	}
+
+	scope, err := newCrawlScope(job.URL, job.Crawl)
+	if err != nil {
+		log.Printf("Not following links of job %s: %v", job.JobID, err)
+		return
+	}
+
+	queued := 0
+	for _, link := range links {
+		if !scope.allows(link) {
+			continue
+		}
+
+		page := types.ScrapeJob{
+			URL:      link,
+			Category: job.Category,
+			Tags:     job.Tags,
+			ParentID: job.ParentID,
+			Depth:    job.Depth + 1,
+			Crawl:    job.Crawl,
+		}
+		created, err := c.enqueuePage(ctx, page, job.Crawl.MaxPages)
+		if err != nil {
+			log.Printf("Failed to queue %s for crawl %s: %v", link, job.ParentID, err)
+			continue
+		}
+		if created {
+			queued++
+		}
+	}
+
+	log.Printf("Queued %d of %d links from %s for crawl %s", queued, len(links), job.URL, job.ParentID)
+}
+
+// enqueuePage records a page of a crawl or sitemap job and sends it to the
+// scrape jobs topic. Pages the parent job already visits, or that would take
+// it past maxPages pages, are left out; enqueuePage reports whether the page
+// was queued.
+func (c *Consumer) enqueuePage(ctx context.Context, page types.ScrapeJob, maxPages int) (bool, error) {
+	page.Type = types.JobTypePage
+	page.JobID = fmt.Sprintf("job_%d", time.Now().UnixNano())
+
+	record := &types.ScrapeJobRecord{
+		JobID:     page.JobID,
+		Type:      page.Type,
+		URL:       page.URL,
+		Category:  page.Category,
+		Tags:      page.Tags,
+		Status:    types.JobQueued,
+		ParentID:  page.ParentID,
+		Depth:     page.Depth,
+		Crawl:     page.Crawl,
+		Refresh:   page.Refresh,
+		CreatedAt: time.Now(),
+	}
+	created, err := c.docStore.CreateCrawlPage(ctx, record, maxPages)
+	if err != nil || !created {
+		return false, err
+	}
+
+	jobData, err := json.Marshal(page)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal scrape job: %w", err)
+	}
+
+	if err := c.producer.Send(ctx, kafka.Message{Topic: ScrapeJobsTopic, Key: []byte(page.JobID), Value: jobData}); err != nil {
+		// A page that is never sent must not keep the crawl running
+		if finishErr := c.docStore.FinishScrapeJob(ctx, page.JobID, types.JobFailed, err.Error(), nil); finishErr != nil {
+			log.Printf("Failed to mark job %s as failed: %v", page.JobID, finishErr)
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// finishCrawl records the outcome of a crawl or sitemap job if none of its pages is left to run.
+func (c *Consumer) finishCrawl(ctx context.Context, crawlID string) {
+	finished, err := c.docStore.FinishCrawl(ctx, crawlID)
+	if err != nil {
+		log.Printf("Failed to finish crawl %s: %v", crawlID, err)
+		return
+	}
+	if finished {
+		log.Printf("Crawl %s finished", crawlID)
+	}
+}
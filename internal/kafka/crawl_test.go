@@ -0,0 +1,49 @@
+package kafka
+
+import (
+	"testing"
+
+	"tech-docs-ai/internal/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCrawlScope_Allows(t *testing.T) {
+	scope, err := newCrawlScope("https://www.example.com/docs/", &types.CrawlOptions{
+		SameDomain: true,
+		Include:    []string{`/docs/`},
+		Exclude:    []string{`/docs/archive/`, `\?print=1$`},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		link    string
+		allowed bool
+	}{
+		{"https://www.example.com/docs/intro", true},
+		{"https://example.com/docs/intro", true},
+		{"https://blog.example.com/docs/intro", false},
+		{"https://other.org/docs/intro", false},
+		{"https://www.example.com/pricing", false},
+		{"https://www.example.com/docs/archive/v1", false},
+		{"https://www.example.com/docs/intro?print=1", false},
+		{"https://www.example.com/docs/diagram.PNG", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, scope.allows(tt.link), tt.link)
+	}
+}
+
+func TestCrawlScope_AnyDomain(t *testing.T) {
+	scope, err := newCrawlScope("https://example.com/", &types.CrawlOptions{})
+	require.NoError(t, err)
+
+	assert.True(t, scope.allows("https://other.org/page"))
+}
+
+func TestNewCrawlScope_InvalidPattern(t *testing.T) {
+	_, err := newCrawlScope("https://example.com/", &types.CrawlOptions{Exclude: []string{"("}})
+	assert.Error(t, err)
+}
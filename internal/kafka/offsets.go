@@ -0,0 +1,85 @@
+package kafka
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// commitFunc commits the offsets of messages, such as (*kafka.Reader).CommitMessages.
+type commitFunc func(ctx context.Context, msgs ...kafka.Message) error
+
+// offsetTracker commits the offsets of messages that are processed
+// concurrently and finish in any order. A partition's offset only moves past
+// a message once it and every earlier message of the partition are done, so
+// messages that were in flight when a worker stopped are delivered again.
+type offsetTracker struct {
+	commit commitFunc
+
+	mu      sync.Mutex
+	pending map[int][]*trackedMessage
+}
+
+// trackedMessage is a fetched message that is waiting to be committed.
+type trackedMessage struct {
+	msg  kafka.Message
+	done bool
+}
+
+// newOffsetTracker creates a tracker that commits with commit.
+func newOffsetTracker(commit commitFunc) *offsetTracker {
+	return &offsetTracker{
+		commit:  commit,
+		pending: make(map[int][]*trackedMessage),
+	}
+}
+
+// track registers a fetched message before it is processed.
+func (t *offsetTracker) track(m kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Messages arrive in offset order, except when a rebalance delivers a partition's messages again
+	pending := t.pending[m.Partition]
+	i := sort.Search(len(pending), func(i int) bool {
+		return pending[i].msg.Offset > m.Offset
+	})
+	pending = append(pending, nil)
+	copy(pending[i+1:], pending[i:])
+	pending[i] = &trackedMessage{msg: m}
+	t.pending[m.Partition] = pending
+}
+
+// done marks a message as processed and commits the partition up to the
+// latest message before which every message is done. Commits run while the
+// tracker is locked so that a partition's offset never moves backwards.
+func (t *offsetTracker) done(ctx context.Context, m kafka.Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pending := t.pending[m.Partition]
+	for _, tracked := range pending {
+		if tracked.msg.Offset == m.Offset && !tracked.done {
+			tracked.done = true
+			break
+		}
+	}
+
+	var last *trackedMessage
+	for len(pending) > 0 && pending[0].done {
+		last = pending[0]
+		pending = pending[1:]
+	}
+	if len(pending) == 0 {
+		delete(t.pending, m.Partition)
+	} else {
+		t.pending[m.Partition] = pending
+	}
+
+	if last == nil {
+		return nil
+	}
+	return t.commit(ctx, last.msg)
+}
@@ -0,0 +1,69 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordCommits returns a commit func that appends the committed partitions and offsets to commits.
+func recordCommits(commits *[][2]int64) commitFunc {
+	return func(ctx context.Context, msgs ...kafka.Message) error {
+		for _, m := range msgs {
+			*commits = append(*commits, [2]int64{int64(m.Partition), m.Offset})
+		}
+		return nil
+	}
+}
+
+func TestOffsetTracker_CommitsInOrder(t *testing.T) {
+	ctx := context.Background()
+	var commits [][2]int64
+	tracker := newOffsetTracker(recordCommits(&commits))
+
+	messages := []kafka.Message{
+		{Partition: 0, Offset: 10},
+		{Partition: 0, Offset: 11},
+		{Partition: 0, Offset: 12},
+		{Partition: 1, Offset: 5},
+	}
+	for _, m := range messages {
+		tracker.track(m)
+	}
+
+	// Later messages finishing first must not commit past the unfinished one
+	require.NoError(t, tracker.done(ctx, messages[2]))
+	require.NoError(t, tracker.done(ctx, messages[1]))
+	assert.Empty(t, commits)
+
+	// Partitions are committed independently
+	require.NoError(t, tracker.done(ctx, messages[3]))
+	assert.Equal(t, [][2]int64{{1, 5}}, commits)
+
+	require.NoError(t, tracker.done(ctx, messages[0]))
+	assert.Equal(t, [][2]int64{{1, 5}, {0, 12}}, commits)
+	assert.Empty(t, tracker.pending)
+}
+
+func TestOffsetTracker_Redelivery(t *testing.T) {
+	ctx := context.Background()
+	var commits [][2]int64
+	tracker := newOffsetTracker(recordCommits(&commits))
+
+	tracker.track(kafka.Message{Partition: 0, Offset: 7})
+	tracker.track(kafka.Message{Partition: 0, Offset: 8})
+	// A rebalance delivers offset 7 again while the first copy is in flight
+	tracker.track(kafka.Message{Partition: 0, Offset: 7})
+
+	require.NoError(t, tracker.done(ctx, kafka.Message{Partition: 0, Offset: 8}))
+	require.NoError(t, tracker.done(ctx, kafka.Message{Partition: 0, Offset: 7}))
+	assert.Equal(t, [][2]int64{{0, 7}}, commits)
+
+	// Offset 8 is committed only once the second copy of 7 is done as well
+	require.NoError(t, tracker.done(ctx, kafka.Message{Partition: 0, Offset: 7}))
+	assert.Equal(t, [][2]int64{{0, 7}, {0, 8}}, commits)
+	assert.Empty(t, tracker.pending)
+}
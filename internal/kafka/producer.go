@@ -9,6 +9,9 @@ import (
 	"github.com/segmentio/kafka-go"
 )
 
+// sendTimeout caps how long a single SendMessage call may block.
+const sendTimeout = 10 * time.Second
+
 // Producer is a Kafka producer for sending messages
 type Producer struct {
 	writer *kafka.Writer
@@ -33,18 +36,23 @@ func NewProducer() *Producer {
 	}
 }
 
-// SendMessage sends a message to the specified Kafka topic
-func (p *Producer) SendMessage(topic string, message []byte) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	err := p.writer.WriteMessages(ctx, kafka.Message{
+// SendMessage sends a message to the specified Kafka topic. The write is
+// bounded by sendTimeout in addition to any deadline already set on ctx.
+func (p *Producer) SendMessage(ctx context.Context, topic string, message []byte) error {
+	return p.Send(ctx, kafka.Message{
 		Topic: topic,
 		Value: message,
 	})
+}
+
+// Send sends a message with its key and headers to the topic set on it,
+// bounded by sendTimeout like SendMessage.
+func (p *Producer) Send(ctx context.Context, m kafka.Message) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
 
-	if err != nil {
-		return fmt.Errorf("failed to send message to topic %s: %w", topic, err)
+	if err := p.writer.WriteMessages(ctx, m); err != nil {
+		return fmt.Errorf("failed to send message to topic %s: %w", m.Topic, err)
 	}
 
 	return nil
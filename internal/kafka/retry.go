@@ -0,0 +1,142 @@
+package kafka
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Topics of the scrape job pipeline. Failed jobs are retried through
+// RetryTopic and end up in DeadLetterTopic once they run out of attempts.
+const (
+	ScrapeJobsTopic = "scrape-jobs"
+	RetryTopic      = "scrape-jobs-retry"
+	DeadLetterTopic = "scrape-jobs-dlq"
+)
+
+// Consumer groups of the workers. The retry topic has its own group, so that
+// its reader joining, leaving or waiting out a backoff does not rebalance or
+// stall the readers of new jobs.
+const (
+	ScrapeJobsGroup = "scraper-workers"
+	RetryGroup      = "scraper-workers-retry"
+)
+
+// Headers set on retried and dead-lettered messages.
+const (
+	// attemptHeader is the attempt the message is for, counting from 1.
+	attemptHeader = "attempt"
+	// retryAtHeader is the RFC 3339 time before which a retry must not run.
+	retryAtHeader = "retry-at"
+	// errorHeader is the error of the last failed attempt.
+	errorHeader = "error"
+)
+
+// Retry policy defaults.
+const (
+	DefaultMaxAttempts    = 5
+	DefaultRetryBaseDelay = 30 * time.Second
+	DefaultRetryMaxDelay  = 30 * time.Minute
+)
+
+// retryPolicy bounds how often and how soon failed scrape jobs are retried.
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+// loadRetryPolicy reads the retry policy from SCRAPE_MAX_ATTEMPTS,
+// SCRAPE_RETRY_BASE_DELAY and SCRAPE_RETRY_MAX_DELAY, falling back to the
+// defaults for unset or invalid values.
+func loadRetryPolicy() retryPolicy {
+	policy := retryPolicy{
+		maxAttempts: DefaultMaxAttempts,
+		baseDelay:   DefaultRetryBaseDelay,
+		maxDelay:    DefaultRetryMaxDelay,
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("SCRAPE_MAX_ATTEMPTS")); err == nil && v > 0 {
+		policy.maxAttempts = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("SCRAPE_RETRY_BASE_DELAY")); err == nil && v > 0 {
+		policy.baseDelay = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("SCRAPE_RETRY_MAX_DELAY")); err == nil && v > 0 {
+		policy.maxDelay = v
+	}
+
+	return policy
+}
+
+// backoff returns how long to wait after the given failed attempt before the
+// next one. The delay doubles with every attempt up to maxDelay.
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := p.baseDelay
+	for i := 1; i < attempt && delay < p.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.maxDelay {
+		delay = p.maxDelay
+	}
+	return delay
+}
+
+// header returns the value of the message header with the given key, or "".
+func header(m kafka.Message, key string) string {
+	for _, h := range m.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// messageAttempt returns the attempt a message is for. Messages without an
+// attempt header are first attempts.
+func messageAttempt(m kafka.Message) int {
+	attempt, err := strconv.Atoi(header(m, attemptHeader))
+	if err != nil || attempt < 1 {
+		return 1
+	}
+	return attempt
+}
+
+// messageRetryAt returns the time before which a retried message must not be
+// processed, or the zero time if it can be processed right away.
+func messageRetryAt(m kafka.Message) time.Time {
+	at, err := time.Parse(time.RFC3339Nano, header(m, retryAtHeader))
+	if err != nil {
+		return time.Time{}
+	}
+	return at
+}
+
+// retryMessage copies m to the retry topic for the given attempt, to be run no earlier than at.
+func retryMessage(m kafka.Message, attempt int, at time.Time, cause string) kafka.Message {
+	return kafka.Message{
+		Topic: RetryTopic,
+		Key:   m.Key,
+		Value: m.Value,
+		Headers: []kafka.Header{
+			{Key: attemptHeader, Value: []byte(strconv.Itoa(attempt))},
+			{Key: retryAtHeader, Value: []byte(at.UTC().Format(time.RFC3339Nano))},
+			{Key: errorHeader, Value: []byte(cause)},
+		},
+	}
+}
+
+// deadLetterMessage copies m to the dead-letter topic after its last failed attempt.
+func deadLetterMessage(m kafka.Message, attempt int, cause string) kafka.Message {
+	return kafka.Message{
+		Topic: DeadLetterTopic,
+		Key:   m.Key,
+		Value: m.Value,
+		Headers: []kafka.Header{
+			{Key: attemptHeader, Value: []byte(strconv.Itoa(attempt))},
+			{Key: errorHeader, Value: []byte(cause)},
+		},
+	}
+}
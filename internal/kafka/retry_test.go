@@ -0,0 +1,52 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := retryPolicy{maxAttempts: 5, baseDelay: 10 * time.Second, maxDelay: time.Minute}
+
+	assert.Equal(t, 10*time.Second, policy.backoff(1))
+	assert.Equal(t, 20*time.Second, policy.backoff(2))
+	assert.Equal(t, 40*time.Second, policy.backoff(3))
+	assert.Equal(t, time.Minute, policy.backoff(4))
+	assert.Equal(t, time.Minute, policy.backoff(50))
+}
+
+func TestLoadRetryPolicy(t *testing.T) {
+	t.Setenv("SCRAPE_MAX_ATTEMPTS", "3")
+	t.Setenv("SCRAPE_RETRY_BASE_DELAY", "1s")
+	t.Setenv("SCRAPE_RETRY_MAX_DELAY", "invalid")
+
+	policy := loadRetryPolicy()
+
+	assert.Equal(t, retryPolicy{maxAttempts: 3, baseDelay: time.Second, maxDelay: DefaultRetryMaxDelay}, policy)
+}
+
+func TestRetryMessage(t *testing.T) {
+	original := kafka.Message{Topic: ScrapeJobsTopic, Key: []byte("job_1"), Value: []byte(`{"job_id":"job_1"}`)}
+	assert.Equal(t, 1, messageAttempt(original))
+	assert.True(t, messageRetryAt(original).IsZero())
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	retry := retryMessage(original, 2, at, "timeout")
+
+	assert.Equal(t, RetryTopic, retry.Topic)
+	assert.Equal(t, original.Key, retry.Key)
+	assert.Equal(t, original.Value, retry.Value)
+	assert.Equal(t, 2, messageAttempt(retry))
+	assert.True(t, at.Equal(messageRetryAt(retry)))
+	assert.Equal(t, "timeout", header(retry, errorHeader))
+
+	// Dead-lettering a retried message replaces its headers
+	dead := deadLetterMessage(retry, 5, "still failing")
+	assert.Equal(t, DeadLetterTopic, dead.Topic)
+	assert.Equal(t, 5, messageAttempt(dead))
+	assert.Equal(t, "still failing", header(dead, errorHeader))
+	assert.True(t, messageRetryAt(dead).IsZero())
+}
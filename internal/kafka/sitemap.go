@@ -0,0 +1,159 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"time"
+
+	"tech-docs-ai/internal/scraper"
+	"tech-docs-ai/internal/types"
+)
+
+// maxSitemapFiles caps how many sitemaps one sitemap job reads, counting the
+// ones listed in sitemap indexes.
+const maxSitemapFiles = 100
+
+// sitemapFilter decides which pages of a sitemap are scraped.
+type sitemapFilter struct {
+	include       []*regexp.Regexp
+	exclude       []*regexp.Regexp
+	modifiedSince time.Time
+}
+
+// newSitemapFilter compiles the filter of a sitemap job's options.
+func newSitemapFilter(opts *types.SitemapOptions) (*sitemapFilter, error) {
+	filter := &sitemapFilter{}
+	if opts.ModifiedSince != nil {
+		filter.modifiedSince = *opts.ModifiedSince
+	}
+
+	var err error
+	if filter.include, err = compilePatterns(opts.Include); err != nil {
+		return nil, err
+	}
+	if filter.exclude, err = compilePatterns(opts.Exclude); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+// modified reports whether an entry may have changed since modifiedSince.
+// Entries without a lastmod may have.
+func (f *sitemapFilter) modified(entry scraper.SitemapEntry) bool {
+	return entry.LastMod.IsZero() || f.modifiedSince.IsZero() || !entry.LastMod.Before(f.modifiedSince)
+}
+
+// allows reports whether the page at link is scraped, matching the patterns against its path.
+func (f *sitemapFilter) allows(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	if len(f.include) > 0 {
+		included := false
+		for _, re := range f.include {
+			if re.MatchString(u.Path) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, re := range f.exclude {
+		if re.MatchString(u.Path) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// runSitemap reads the job's sitemap, and the sitemaps it lists when it is
+// an index, and queues the selected pages as page jobs. Pages whose lastmod
+// is not after they were last fetched are skipped, and pages that changed
+// are fetched again unconditionally. The job stays running until all of its
+// pages have finished.
+func (c *Consumer) runSitemap(ctx context.Context, job types.ScrapeJob) jobResult {
+	if job.Sitemap == nil {
+		return jobFailed(job, fmt.Errorf("sitemap job has no sitemap options"))
+	}
+
+	filter, err := newSitemapFilter(job.Sitemap)
+	if err != nil {
+		return jobFailed(job, err)
+	}
+
+	pending := []string{job.URL}
+	seen := map[string]bool{job.URL: true}
+	var queued, unchanged, read int
+
+	for len(pending) > 0 && read < maxSitemapFiles {
+		sitemapURL := pending[0]
+		pending = pending[1:]
+		read++
+
+		sitemap, err := c.sitemapScraper.FetchSitemap(sitemapURL)
+		if err != nil {
+			// Without its root sitemap the job has nothing to do, so that is worth a retry
+			if sitemapURL == job.URL {
+				return jobFailed(job, err)
+			}
+			log.Printf("Skipping sitemap %s of job %s: %v", sitemapURL, job.JobID, err)
+			continue
+		}
+
+		for _, child := range sitemap.Sitemaps {
+			if !seen[child.URL] && filter.modified(child) {
+				seen[child.URL] = true
+				pending = append(pending, child.URL)
+			}
+		}
+
+		for _, entry := range sitemap.Pages {
+			pageURL, ok := scraper.NormalizeURL(entry.URL)
+			if !ok || !filter.modified(entry) || !filter.allows(pageURL) {
+				continue
+			}
+
+			page := types.ScrapeJob{
+				URL:      pageURL,
+				Category: job.Category,
+				Tags:     job.Tags,
+				ParentID: job.JobID,
+			}
+
+			stored, err := c.storedPage(ctx, pageURL)
+			if err != nil {
+				log.Printf("Failed to look up stored copy of %s: %v", pageURL, err)
+			}
+			if stored != nil && !entry.LastMod.IsZero() {
+				if !entry.LastMod.After(stored.FetchedAt) {
+					unchanged++
+					continue
+				}
+				page.Refresh = true
+			}
+
+			created, err := c.enqueuePage(ctx, page, job.Sitemap.MaxPages)
+			if err != nil {
+				return jobFailed(job, fmt.Errorf("failed to queue page %s: %w", pageURL, err))
+			}
+			if created {
+				queued++
+			}
+		}
+	}
+
+	if len(pending) > 0 {
+		log.Printf("Sitemap job %s read the limit of %d sitemaps, skipping %d more", job.JobID, maxSitemapFiles, len(pending))
+	}
+	log.Printf("Sitemap job %s queued %d pages and skipped %d unchanged ones", job.JobID, queued, unchanged)
+	return jobResult{status: types.JobRunning}
+}
@@ -0,0 +1,40 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"tech-docs-ai/internal/scraper"
+	"tech-docs-ai/internal/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSitemapFilter_Allows(t *testing.T) {
+	filter, err := newSitemapFilter(&types.SitemapOptions{
+		Include: []string{`^/docs/`},
+		Exclude: []string{`/archive/`},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, filter.allows("https://example.com/docs/intro"))
+	assert.False(t, filter.allows("https://example.com/blog/docs/intro"))
+	assert.False(t, filter.allows("https://example.com/docs/archive/v1"))
+}
+
+func TestSitemapFilter_Modified(t *testing.T) {
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	filter, err := newSitemapFilter(&types.SitemapOptions{ModifiedSince: &since})
+	require.NoError(t, err)
+
+	assert.True(t, filter.modified(scraper.SitemapEntry{LastMod: since.Add(time.Hour)}))
+	assert.True(t, filter.modified(scraper.SitemapEntry{LastMod: since}))
+	assert.False(t, filter.modified(scraper.SitemapEntry{LastMod: since.Add(-time.Hour)}))
+	assert.True(t, filter.modified(scraper.SitemapEntry{}), "entries without lastmod may have changed")
+}
+
+func TestNewSitemapFilter_InvalidPattern(t *testing.T) {
+	_, err := newSitemapFilter(&types.SitemapOptions{Include: []string{"("}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,416 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"tech-docs-ai/internal/types"
+
+	"github.com/lib/pq"
+)
+
+// scrapeJobColumns are the columns scanned by scanScrapeJob, in order.
+const scrapeJobColumns = `id, type, url, category, tags, status, error, document_ids, attempts, parent_id, depth, crawl_options, sitemap_options, refresh, created_at, started_at, finished_at, dead_lettered_at`
+
+// insertScrapeJobQuery records a job given the ID, type, URL, category, tags,
+// status, parent ID, depth, crawl options, sitemap options, refresh flag and
+// creation time.
+const insertScrapeJobQuery = `
+	INSERT INTO scrape_jobs (id, type, url, category, tags, status, parent_id, depth, crawl_options, sitemap_options, refresh, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+
+// CreateScrapeJob records a new scrape job.
+func (p *PostgresStore) CreateScrapeJob(ctx context.Context, job *types.ScrapeJobRecord) error {
+	args, err := insertScrapeJobArgs(job)
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.db.ExecContext(ctx, insertScrapeJobQuery, args...); err != nil {
+		return fmt.Errorf("failed to create scrape job: %w", err)
+	}
+
+	return nil
+}
+
+// CreateCrawlPage records a page job of a crawl or sitemap job unless the
+// parent job already visits its URL or has reached maxPages pages. It reports
+// whether the page was recorded.
+func (p *PostgresStore) CreateCrawlPage(ctx context.Context, page *types.ScrapeJobRecord, maxPages int) (bool, error) {
+	args, err := insertScrapeJobArgs(page)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, insertScrapeJobQuery+" ON CONFLICT (parent_id, url) WHERE parent_id IS NOT NULL DO NOTHING", args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to create crawl page: %w", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to create crawl page: %w", err)
+	}
+	if inserted == 0 {
+		return false, nil
+	}
+
+	// Counting on the crawl's row locks it, so concurrent workers cannot exceed the limit
+	result, err = tx.ExecContext(ctx,
+		"UPDATE scrape_jobs SET page_count = page_count + 1 WHERE id = $1 AND page_count < $2",
+		page.ParentID, maxPages)
+	if err != nil {
+		return false, fmt.Errorf("failed to count crawl page: %w", err)
+	}
+	counted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to count crawl page: %w", err)
+	}
+	if counted == 0 {
+		return false, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit crawl page: %w", err)
+	}
+
+	return true, nil
+}
+
+// insertScrapeJobArgs returns the arguments of insertScrapeJobQuery for job.
+func insertScrapeJobArgs(job *types.ScrapeJobRecord) ([]interface{}, error) {
+	jobType := job.Type
+	if jobType == "" {
+		jobType = types.JobTypePage
+	}
+
+	var crawlOptions, sitemapOptions []byte
+	var err error
+	if job.Crawl != nil {
+		if crawlOptions, err = json.Marshal(job.Crawl); err != nil {
+			return nil, fmt.Errorf("failed to marshal crawl options: %w", err)
+		}
+	}
+	if job.Sitemap != nil {
+		if sitemapOptions, err = json.Marshal(job.Sitemap); err != nil {
+			return nil, fmt.Errorf("failed to marshal sitemap options: %w", err)
+		}
+	}
+
+	return []interface{}{
+		job.JobID,
+		jobType,
+		job.URL,
+		job.Category,
+		pq.Array(nonNil(job.Tags)),
+		job.Status,
+		sql.NullString{String: job.ParentID, Valid: job.ParentID != ""},
+		job.Depth,
+		crawlOptions,
+		sitemapOptions,
+		job.Refresh,
+		job.CreatedAt,
+	}, nil
+}
+
+// StartScrapeJob marks a job as running its given attempt, counting from 1.
+// Jobs that were never recorded, such as ones queued by an older server, are
+// recorded first.
+func (p *PostgresStore) StartScrapeJob(ctx context.Context, job types.ScrapeJob, attempt int) error {
+	query := `
+	INSERT INTO scrape_jobs (id, type, url, category, tags, status, attempts, created_at, started_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+	ON CONFLICT (id) DO UPDATE SET
+		status = EXCLUDED.status,
+		attempts = EXCLUDED.attempts,
+		started_at = EXCLUDED.started_at,
+		finished_at = NULL
+	`
+
+	jobType := job.Type
+	if jobType == "" {
+		jobType = types.JobTypePage
+	}
+
+	_, err := p.db.ExecContext(ctx, query, job.JobID, jobType, job.URL, job.Category, pq.Array(nonNil(job.Tags)), types.JobRunning, attempt)
+	if err != nil {
+		return fmt.Errorf("failed to start scrape job: %w", err)
+	}
+
+	return nil
+}
+
+// FinishScrapeJob records the outcome of a job.
+func (p *PostgresStore) FinishScrapeJob(ctx context.Context, jobID string, status types.JobStatus, errMsg string, documentIDs []string) error {
+	query := `
+	UPDATE scrape_jobs SET status = $2, error = $3, document_ids = $4, finished_at = NOW()
+	WHERE id = $1
+	`
+
+	_, err := p.db.ExecContext(ctx, query, jobID, status, errMsg, pq.Array(nonNil(documentIDs)))
+	if err != nil {
+		return fmt.Errorf("failed to finish scrape job: %w", err)
+	}
+
+	return nil
+}
+
+// FinishCrawl records the outcome of a running crawl or sitemap job once
+// none of its pages is waiting or running any more: succeeded if any page was stored or
+// already stored, failed otherwise. It reports whether the crawl finished.
+func (p *PostgresStore) FinishCrawl(ctx context.Context, crawlID string) (bool, error) {
+	query := `
+	UPDATE scrape_jobs SET
+		status = CASE WHEN pages.stored THEN $3 ELSE $5 END,
+		error = CASE WHEN pages.stored THEN '' ELSE 'no page of the crawl succeeded' END,
+		finished_at = NOW()
+	FROM (
+		SELECT EXISTS (SELECT 1 FROM scrape_jobs WHERE parent_id = $1 AND status IN ($3, $4)) AS stored
+	) AS pages
+	WHERE id = $1 AND status = $2 AND NOT EXISTS (
+		SELECT 1 FROM scrape_jobs WHERE parent_id = $1 AND status IN ($6, $2, $7)
+	)
+	`
+
+	result, err := p.db.ExecContext(ctx, query, crawlID,
+		types.JobRunning, types.JobSucceeded, types.JobSkipped, types.JobFailed, types.JobQueued, types.JobRetrying)
+	if err != nil {
+		return false, fmt.Errorf("failed to finish crawl: %w", err)
+	}
+
+	finished, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to finish crawl: %w", err)
+	}
+
+	return finished > 0, nil
+}
+
+// CrawlProgress counts the pages of a crawl or sitemap job by status.
+func (p *PostgresStore) CrawlProgress(ctx context.Context, crawlID string) (*types.CrawlProgress, error) {
+	rows, err := p.db.QueryContext(ctx,
+		"SELECT status, COUNT(*) FROM scrape_jobs WHERE parent_id = $1 GROUP BY status", crawlID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get crawl progress: %w", err)
+	}
+	defer rows.Close()
+
+	progress := &types.CrawlProgress{}
+	for rows.Next() {
+		var status types.JobStatus
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan crawl progress: %w", err)
+		}
+
+		progress.Pages += count
+		switch status {
+		case types.JobQueued:
+			progress.Queued = count
+		case types.JobRunning:
+			progress.Running = count
+		case types.JobRetrying:
+			progress.Retrying = count
+		case types.JobSucceeded:
+			progress.Succeeded = count
+		case types.JobFailed:
+			progress.Failed = count
+		case types.JobSkipped:
+			progress.Skipped = count
+		case types.JobDisallowed:
+			progress.Disallowed = count
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate crawl progress: %w", err)
+	}
+
+	return progress, nil
+}
+
+// DeadLetterScrapeJob records that a job failed for good and was sent to the dead-letter topic.
+func (p *PostgresStore) DeadLetterScrapeJob(ctx context.Context, jobID string, errMsg string) error {
+	query := `
+	UPDATE scrape_jobs SET status = $2, error = $3, finished_at = NOW(), dead_lettered_at = NOW()
+	WHERE id = $1
+	`
+
+	_, err := p.db.ExecContext(ctx, query, jobID, types.JobFailed, errMsg)
+	if err != nil {
+		return fmt.Errorf("failed to dead-letter scrape job: %w", err)
+	}
+
+	return nil
+}
+
+// RequeueScrapeJob resets a dead-lettered job to queued with no attempts, so
+// it can be replayed. It reports whether a dead-lettered job was reset.
+func (p *PostgresStore) RequeueScrapeJob(ctx context.Context, jobID string) (bool, error) {
+	query := `
+	UPDATE scrape_jobs SET status = $2, error = '', document_ids = '{}', attempts = 0,
+		started_at = NULL, finished_at = NULL, dead_lettered_at = NULL
+	WHERE id = $1 AND dead_lettered_at IS NOT NULL
+	`
+
+	result, err := p.db.ExecContext(ctx, query, jobID, types.JobQueued)
+	if err != nil {
+		return false, fmt.Errorf("failed to requeue scrape job: %w", err)
+	}
+
+	requeued, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to requeue scrape job: %w", err)
+	}
+
+	return requeued > 0, nil
+}
+
+// GetScrapeJob retrieves a scrape job by ID. It returns nil if there is none.
+func (p *PostgresStore) GetScrapeJob(ctx context.Context, id string) (*types.ScrapeJobRecord, error) {
+	row := p.db.QueryRowContext(ctx, "SELECT "+scrapeJobColumns+" FROM scrape_jobs WHERE id = $1", id)
+
+	job, err := scanScrapeJob(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get scrape job: %w", err)
+	}
+
+	return job, nil
+}
+
+// ListScrapeJobs returns one page of scrape jobs, newest first, and the number
+// of matching jobs across all pages.
+func (p *PostgresStore) ListScrapeJobs(ctx context.Context, opts types.ScrapeJobListOptions) ([]*types.ScrapeJobRecord, int, error) {
+	where, args := listScrapeJobsFilter(opts)
+
+	var total int
+	if err := p.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM scrape_jobs"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count scrape jobs: %w", err)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM scrape_jobs%s ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d",
+		scrapeJobColumns, where, len(args)+1, len(args)+2)
+
+	rows, err := p.db.QueryContext(ctx, query, append(args, opts.Limit, opts.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list scrape jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*types.ScrapeJobRecord{}
+	for rows.Next() {
+		job, err := scanScrapeJob(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan scrape job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate scrape jobs: %w", err)
+	}
+
+	return jobs, total, nil
+}
+
+// listScrapeJobsFilter builds the WHERE clause and its arguments for ListScrapeJobs.
+func listScrapeJobsFilter(opts types.ScrapeJobListOptions) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if opts.Status != "" {
+		args = append(args, opts.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if opts.ParentID != "" {
+		args = append(args, opts.ParentID)
+		conditions = append(conditions, fmt.Sprintf("parent_id = $%d", len(args)))
+	}
+	if opts.DeadLettered {
+		conditions = append(conditions, "dead_lettered_at IS NOT NULL")
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanScrapeJob scans the scrapeJobColumns of a row.
+func scanScrapeJob(row rowScanner) (*types.ScrapeJobRecord, error) {
+	var job types.ScrapeJobRecord
+	var parentID sql.NullString
+	var crawlOptions, sitemapOptions []byte
+	var startedAt, finishedAt, deadLetteredAt sql.NullTime
+
+	err := row.Scan(
+		&job.JobID,
+		&job.Type,
+		&job.URL,
+		&job.Category,
+		pq.Array(&job.Tags),
+		&job.Status,
+		&job.Error,
+		pq.Array(&job.DocumentIDs),
+		&job.Attempts,
+		&parentID,
+		&job.Depth,
+		&crawlOptions,
+		&sitemapOptions,
+		&job.Refresh,
+		&job.CreatedAt,
+		&startedAt,
+		&finishedAt,
+		&deadLetteredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	job.ParentID = parentID.String
+	if len(crawlOptions) > 0 {
+		if err := json.Unmarshal(crawlOptions, &job.Crawl); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal crawl options: %w", err)
+		}
+	}
+	if len(sitemapOptions) > 0 {
+		if err := json.Unmarshal(sitemapOptions, &job.Sitemap); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal sitemap options: %w", err)
+		}
+	}
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	if deadLetteredAt.Valid {
+		job.DeadLetteredAt = &deadLetteredAt.Time
+	}
+
+	return &job, nil
+}
+
+// nonNil returns values, or an empty slice when it is nil, for NOT NULL array columns.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,23 @@
+package repo
+
+import (
+	"testing"
+
+	"tech-docs-ai/internal/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListScrapeJobsFilter(t *testing.T) {
+	where, args := listScrapeJobsFilter(types.ScrapeJobListOptions{})
+	assert.Empty(t, where)
+	assert.Empty(t, args)
+
+	where, args = listScrapeJobsFilter(types.ScrapeJobListOptions{Status: types.JobFailed, DeadLettered: true})
+	assert.Equal(t, " WHERE status = $1 AND dead_lettered_at IS NOT NULL", where)
+	assert.Equal(t, []interface{}{types.JobFailed}, args)
+
+	where, args = listScrapeJobsFilter(types.ScrapeJobListOptions{Status: types.JobQueued, ParentID: "job_1"})
+	assert.Equal(t, " WHERE status = $1 AND parent_id = $2", where)
+	assert.Equal(t, []interface{}{types.JobQueued, "job_1"}, args)
+}
@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0010_add_jobs.up.sql":           {Data: []byte("CREATE TABLE jobs ();")},
+		"0002_add_index.up.sql":          {Data: []byte("CREATE INDEX idx ON t(c);")},
+		"0002_add_index.down.sql":        {Data: []byte("DROP INDEX idx;")},
+		"0001_create_documents.up.sql":   {Data: []byte("CREATE TABLE documents ();")},
+		"0001_create_documents.down.sql": {Data: []byte("DROP TABLE documents;")},
+	}
+
+	migrations, err := LoadMigrations(fsys)
+	require.NoError(t, err)
+	require.Len(t, migrations, 3)
+
+	assert.Equal(t, Migration{Version: 1, Name: "create_documents", Up: "CREATE TABLE documents ();", Down: "DROP TABLE documents;"}, migrations[0])
+	assert.Equal(t, 2, migrations[1].Version)
+	assert.Equal(t, Migration{Version: 10, Name: "add_jobs", Up: "CREATE TABLE jobs ();"}, migrations[2])
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"bad file name", fstest.MapFS{"create_documents.sql": {}}},
+		{"duplicate version", fstest.MapFS{
+			"0001_a.up.sql": {Data: []byte("SELECT 1;")},
+			"0001_b.up.sql": {Data: []byte("SELECT 1;")},
+		}},
+		{"down without up", fstest.MapFS{"0001_a.down.sql": {Data: []byte("SELECT 1;")}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadMigrations(tt.fsys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewMigrator_Embedded(t *testing.T) {
+	migrator, err := NewMigrator(nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrator.migrations)
+
+	for i, m := range migrator.migrations {
+		assert.Equal(t, i+1, m.Version, "migration versions should have no gaps")
+		assert.NotEmpty(t, m.Down, "migration %d_%s should be reversible", m.Version, m.Name)
+	}
+}
+
+func TestNewMigrator_VectorSettings(t *testing.T) {
+	t.Setenv("VECTOR_STORE", "qdrant")
+	migrator, err := NewMigrator(nil)
+	require.NoError(t, err)
+	assert.Empty(t, migrator.settings)
+
+	t.Setenv("VECTOR_STORE", "pgvector")
+	t.Setenv("PGVECTOR_DIMENSION", "1024")
+	withVectors, err := NewMigrator(nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"techdocs.vector_dimension": "1024"}, withVectors.settings)
+
+	// The version sequence does not depend on the vector store
+	assert.Equal(t, migrator.migrations, withVectors.migrations)
+}
@@ -0,0 +1,16 @@
+-- IF NOT EXISTS lets databases created before migrations adopt this version.
+CREATE TABLE IF NOT EXISTS documents (
+	id VARCHAR(255) PRIMARY KEY,
+	title TEXT NOT NULL,
+	content TEXT NOT NULL,
+	category VARCHAR(100) NOT NULL,
+	tags TEXT[] NOT NULL,
+	author VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	metadata JSONB
+);
+
+CREATE INDEX IF NOT EXISTS idx_documents_category ON documents(category);
+CREATE INDEX IF NOT EXISTS idx_documents_tags ON documents USING GIN(tags);
+CREATE INDEX IF NOT EXISTS idx_documents_created_at ON documents(created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_documents_search;
+ALTER TABLE documents DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS immutable_array_to_string(text[], text);
@@ -0,0 +1,11 @@
+-- array_to_string is only STABLE, but generated columns need IMMUTABLE expressions
+CREATE OR REPLACE FUNCTION immutable_array_to_string(text[], text) RETURNS text
+	LANGUAGE sql IMMUTABLE AS $$ SELECT array_to_string($1, $2) $$;
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+	setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+	setweight(to_tsvector('english', coalesce(immutable_array_to_string(tags, ' '), '')), 'B') ||
+	setweight(to_tsvector('english', coalesce(content, '')), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_documents_search ON documents USING GIN(search_vector);
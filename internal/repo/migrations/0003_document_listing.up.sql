@@ -0,0 +1,3 @@
+-- Indexes for listing documents by author and newest first
+CREATE INDEX IF NOT EXISTS idx_documents_author ON documents(author);
+CREATE INDEX IF NOT EXISTS idx_documents_created_at_id ON documents(created_at DESC, id);
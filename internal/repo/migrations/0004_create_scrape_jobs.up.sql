@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS scrape_jobs (
+	id VARCHAR(255) PRIMARY KEY,
+	url TEXT NOT NULL,
+	category VARCHAR(100) NOT NULL DEFAULT '',
+	tags TEXT[] NOT NULL DEFAULT '{}',
+	status VARCHAR(20) NOT NULL,
+	error TEXT NOT NULL DEFAULT '',
+	document_ids TEXT[] NOT NULL DEFAULT '{}',
+	created_at TIMESTAMP NOT NULL,
+	started_at TIMESTAMP,
+	finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_scrape_jobs_status ON scrape_jobs(status);
+CREATE INDEX IF NOT EXISTS idx_scrape_jobs_created_at ON scrape_jobs(created_at DESC);
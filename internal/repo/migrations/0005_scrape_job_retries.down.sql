@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_scrape_jobs_dead_lettered_at;
+ALTER TABLE scrape_jobs DROP COLUMN IF EXISTS dead_lettered_at;
+ALTER TABLE scrape_jobs DROP COLUMN IF EXISTS attempts;
@@ -0,0 +1,5 @@
+-- Attempts made so far and when a job was given up on and dead-lettered
+ALTER TABLE scrape_jobs ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE scrape_jobs ADD COLUMN IF NOT EXISTS dead_lettered_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_scrape_jobs_dead_lettered_at ON scrape_jobs(dead_lettered_at DESC) WHERE dead_lettered_at IS NOT NULL;
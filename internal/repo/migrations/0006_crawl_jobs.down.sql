@@ -0,0 +1,7 @@
+DELETE FROM scrape_jobs WHERE parent_id IS NOT NULL;
+DROP INDEX IF EXISTS idx_scrape_jobs_parent_url;
+ALTER TABLE scrape_jobs DROP COLUMN IF EXISTS page_count;
+ALTER TABLE scrape_jobs DROP COLUMN IF EXISTS crawl_options;
+ALTER TABLE scrape_jobs DROP COLUMN IF EXISTS depth;
+ALTER TABLE scrape_jobs DROP COLUMN IF EXISTS parent_id;
+ALTER TABLE scrape_jobs DROP COLUMN IF EXISTS type;
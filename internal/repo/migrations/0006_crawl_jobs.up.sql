@@ -0,0 +1,10 @@
+-- Crawl jobs and the page jobs they spawn
+ALTER TABLE scrape_jobs ADD COLUMN IF NOT EXISTS type VARCHAR(20) NOT NULL DEFAULT 'page';
+ALTER TABLE scrape_jobs ADD COLUMN IF NOT EXISTS parent_id VARCHAR(255) REFERENCES scrape_jobs(id) ON DELETE CASCADE;
+ALTER TABLE scrape_jobs ADD COLUMN IF NOT EXISTS depth INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE scrape_jobs ADD COLUMN IF NOT EXISTS crawl_options JSONB;
+-- Pages a crawl job has queued, to enforce its page limit
+ALTER TABLE scrape_jobs ADD COLUMN IF NOT EXISTS page_count INTEGER NOT NULL DEFAULT 0;
+
+-- Each URL is visited once per crawl
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scrape_jobs_parent_url ON scrape_jobs(parent_id, url) WHERE parent_id IS NOT NULL;
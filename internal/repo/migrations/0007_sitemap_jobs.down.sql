@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_documents_url;
+ALTER TABLE scrape_jobs DROP COLUMN IF EXISTS refresh;
+ALTER TABLE scrape_jobs DROP COLUMN IF EXISTS sitemap_options;
@@ -0,0 +1,6 @@
+-- Sitemap jobs, and page jobs that replace the stored copy of their page
+ALTER TABLE scrape_jobs ADD COLUMN IF NOT EXISTS sitemap_options JSONB;
+ALTER TABLE scrape_jobs ADD COLUMN IF NOT EXISTS refresh BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Finding the stored copy of a page by its URL
+CREATE INDEX IF NOT EXISTS idx_documents_url ON documents((metadata->>'url'));
@@ -0,0 +1,12 @@
+-- What was last fetched from each scraped URL, for conditional re-fetching and change detection
+CREATE TABLE IF NOT EXISTS scraped_pages (
+	url TEXT PRIMARY KEY,
+	document_id VARCHAR(255) NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+	etag TEXT NOT NULL DEFAULT '',
+	last_modified TEXT NOT NULL DEFAULT '',
+	content_hash VARCHAR(64) NOT NULL,
+	fetched_at TIMESTAMP NOT NULL,
+	changed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_scraped_pages_document_id ON scraped_pages(document_id);
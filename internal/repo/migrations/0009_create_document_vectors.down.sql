@@ -0,0 +1,2 @@
+-- The vector extension is left installed, since other schemas may use it
+DROP TABLE IF EXISTS document_vectors;
@@ -0,0 +1,33 @@
+-- The chunk vectors of the pgvector vector store. The migrator passes
+-- PGVECTOR_DIMENSION as the techdocs.vector_dimension setting only when
+-- VECTOR_STORE is pgvector; without it this version does nothing, so every
+-- configuration has the same version sequence. IF NOT EXISTS lets tables
+-- created before this migration existed adopt this version.
+DO $$
+DECLARE
+	dimension TEXT := current_setting('techdocs.vector_dimension', true);
+BEGIN
+	IF dimension IS NULL OR dimension = '' THEN
+		RETURN;
+	END IF;
+
+	CREATE EXTENSION IF NOT EXISTS vector;
+
+	EXECUTE format('CREATE TABLE IF NOT EXISTS document_vectors (
+		document_id VARCHAR(255) NOT NULL,
+		chunk_index INTEGER NOT NULL,
+		embedding vector(%s) NOT NULL,
+		category VARCHAR(100),
+		source VARCHAR(100),
+		author VARCHAR(255),
+		tags TEXT[],
+		payload JSONB NOT NULL DEFAULT ''{}'',
+		PRIMARY KEY (document_id, chunk_index)
+	)', dimension::int);
+
+	CREATE INDEX IF NOT EXISTS idx_document_vectors_embedding ON document_vectors USING hnsw (embedding vector_cosine_ops);
+	CREATE INDEX IF NOT EXISTS idx_document_vectors_category ON document_vectors(category);
+	CREATE INDEX IF NOT EXISTS idx_document_vectors_source ON document_vectors(source);
+	CREATE INDEX IF NOT EXISTS idx_document_vectors_tags ON document_vectors USING GIN(tags);
+END
+$$;
@@ -0,0 +1 @@
+ALTER TABLE scraped_pages DROP COLUMN IF EXISTS overrides_hash;
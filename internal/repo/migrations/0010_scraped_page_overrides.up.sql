@@ -0,0 +1,3 @@
+-- A hash of the category and tags the page's job set. Re-fetches are only
+-- conditional while these are unchanged, since they change the document too.
+ALTER TABLE scraped_pages ADD COLUMN IF NOT EXISTS overrides_hash VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,70 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"tech-docs-ai/internal/types"
+)
+
+// GetScrapedPage returns the record of the page last fetched from url, or nil if there is none.
+func (p *PostgresStore) GetScrapedPage(ctx context.Context, url string) (*types.ScrapedPage, error) {
+	query := `
+	SELECT url, document_id, etag, last_modified, content_hash, overrides_hash, fetched_at, changed_at
+	FROM scraped_pages WHERE url = $1
+	`
+
+	var page types.ScrapedPage
+	err := p.db.QueryRowContext(ctx, query, url).Scan(
+		&page.URL,
+		&page.DocumentID,
+		&page.ETag,
+		&page.LastModified,
+		&page.ContentHash,
+		&page.OverridesHash,
+		&page.FetchedAt,
+		&page.ChangedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get scraped page: %w", err)
+	}
+
+	return &page, nil
+}
+
+// SaveScrapedPage records or replaces the record of a fetched page. The
+// document it refers to must already be stored.
+func (p *PostgresStore) SaveScrapedPage(ctx context.Context, page *types.ScrapedPage) error {
+	query := `
+	INSERT INTO scraped_pages (url, document_id, etag, last_modified, content_hash, overrides_hash, fetched_at, changed_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (url) DO UPDATE SET
+		document_id = EXCLUDED.document_id,
+		etag = EXCLUDED.etag,
+		last_modified = EXCLUDED.last_modified,
+		content_hash = EXCLUDED.content_hash,
+		overrides_hash = EXCLUDED.overrides_hash,
+		fetched_at = EXCLUDED.fetched_at,
+		changed_at = EXCLUDED.changed_at
+	`
+
+	_, err := p.db.ExecContext(ctx, query,
+		page.URL,
+		page.DocumentID,
+		page.ETag,
+		page.LastModified,
+		page.ContentHash,
+		page.OverridesHash,
+		page.FetchedAt,
+		page.ChangedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save scraped page: %w", err)
+	}
+
+	return nil
+}
@@ -1,10 +1,13 @@
 package repo
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"tech-docs-ai/internal/types"
@@ -20,6 +23,39 @@ type PostgresStore struct {
 
 // NewPostgresStore creates a new PostgreSQL document store.
 func NewPostgresStore() (*PostgresStore, error) {
+	db, err := OpenDB()
+	if err != nil {
+		return nil, err
+	}
+
+	store := &PostgresStore{db: db}
+
+	// Bring the schema up to date unless migrations are run separately
+	if autoMigrate() {
+		migrator, err := NewMigrator(db)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		if _, err := migrator.Up(context.Background()); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate schema: %w", err)
+		}
+	}
+
+	return store, nil
+}
+
+// autoMigrate reports whether pending migrations are applied on startup. Set
+// POSTGRES_AUTO_MIGRATE=false to apply them only with the migrate command.
+func autoMigrate() bool {
+	value, err := strconv.ParseBool(os.Getenv("POSTGRES_AUTO_MIGRATE"))
+	return err != nil || value
+}
+
+// OpenDB opens a connection pool to the PostgreSQL database configured by the
+// POSTGRES_* environment variables and checks that it is reachable.
+func OpenDB() (*sql.DB, error) {
 	// Get database connection details from environment
 	host := os.Getenv("POSTGRES_HOST")
 	if host == "" {
@@ -58,45 +94,26 @@ func NewPostgresStore() (*PostgresStore, error) {
 
 	// Test connection
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	store := &PostgresStore{db: db}
-
-	// Initialize database schema
-	if err := store.initSchema(); err != nil {
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
-	}
-
-	return store, nil
+	return db, nil
 }
 
-// initSchema creates the necessary database tables.
-func (p *PostgresStore) initSchema() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS documents (
-		id VARCHAR(255) PRIMARY KEY,
-		title TEXT NOT NULL,
-		content TEXT NOT NULL,
-		category VARCHAR(100) NOT NULL,
-		tags TEXT[] NOT NULL,
-		author VARCHAR(255) NOT NULL,
-		created_at TIMESTAMP NOT NULL,
-		updated_at TIMESTAMP NOT NULL,
-		metadata JSONB
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_documents_category ON documents(category);
-	CREATE INDEX IF NOT EXISTS idx_documents_tags ON documents USING GIN(tags);
-	CREATE INDEX IF NOT EXISTS idx_documents_created_at ON documents(created_at);
-	`
+// StoreDocument stores a document in the database.
+func (p *PostgresStore) StoreDocument(ctx context.Context, doc *types.Document) error {
+	return UpsertDocument(ctx, p.db, doc)
+}
 
-	_, err := p.db.Exec(query)
-	return err
+// Execer runs statements; both *sql.DB and *sql.Tx satisfy it.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 }
 
-// StoreDocument stores a document in the database.
-func (p *PostgresStore) StoreDocument(doc *types.Document) error {
+// UpsertDocument inserts or replaces a document using db, which may be a
+// transaction. It generates an ID when the document has none.
+func UpsertDocument(ctx context.Context, db Execer, doc *types.Document) error {
 	// Generate ID if not provided
 	if doc.ID == "" {
 		doc.ID = fmt.Sprintf("doc_%d", time.Now().UnixNano())
@@ -128,7 +145,7 @@ func (p *PostgresStore) StoreDocument(doc *types.Document) error {
 			metadata = EXCLUDED.metadata
 	`
 
-	_, err = p.db.Exec(query,
+	_, err = db.ExecContext(ctx, query,
 		doc.ID,
 		doc.Title,
 		doc.Content,
@@ -148,7 +165,7 @@ func (p *PostgresStore) StoreDocument(doc *types.Document) error {
 }
 
 // GetDocument retrieves a document by ID.
-func (p *PostgresStore) GetDocument(id string) (*types.Document, error) {
+func (p *PostgresStore) GetDocument(ctx context.Context, id string) (*types.Document, error) {
 	query := `
 	SELECT id, title, content, category, tags, author, created_at, updated_at, metadata
 	FROM documents WHERE id = $1
@@ -157,7 +174,7 @@ func (p *PostgresStore) GetDocument(id string) (*types.Document, error) {
 	var doc types.Document
 	var metadataJSON []byte
 
-	err := p.db.QueryRow(query, id).Scan(
+	err := p.db.QueryRowContext(ctx, query, id).Scan(
 		&doc.ID,
 		&doc.Title,
 		&doc.Content,
@@ -186,19 +203,156 @@ func (p *PostgresStore) GetDocument(id string) (*types.Document, error) {
 	return &doc, nil
 }
 
-// SearchDocuments searches for documents by text query.
-func (p *PostgresStore) SearchDocuments(query string, limit int) ([]*types.Document, error) {
-	// Simple text search using ILIKE
-	searchQuery := `
+// FindDocumentByURL retrieves the most recently updated document scraped
+// from url. It returns nil if there is none.
+func (p *PostgresStore) FindDocumentByURL(ctx context.Context, url string) (*types.Document, error) {
+	var id string
+	err := p.db.QueryRowContext(ctx,
+		"SELECT id FROM documents WHERE metadata->>'url' = $1 ORDER BY updated_at DESC LIMIT 1", url).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find document by URL: %w", err)
+	}
+
+	return p.GetDocument(ctx, id)
+}
+
+// ListDocuments returns one page of the documents matching opts, newest first,
+// and the number of matching documents across all pages.
+func (p *PostgresStore) ListDocuments(ctx context.Context, opts types.DocumentListOptions) ([]*types.Document, int, error) {
+	where, args := listDocumentsFilter(opts)
+
+	var total int
+	if err := p.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM documents"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count documents: %w", err)
+	}
+
+	query := fmt.Sprintf(`
 	SELECT id, title, content, category, tags, author, created_at, updated_at, metadata
-	FROM documents 
-	WHERE title ILIKE $1 OR content ILIKE $1 OR category ILIKE $1
-	ORDER BY created_at DESC
+	FROM documents%s
+	ORDER BY created_at DESC, id
+	LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+
+	rows, err := p.db.QueryContext(ctx, query, append(args, opts.Limit, opts.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents := []*types.Document{}
+	for rows.Next() {
+		var doc types.Document
+		var metadataJSON []byte
+
+		err := rows.Scan(
+			&doc.ID,
+			&doc.Title,
+			&doc.Content,
+			&doc.Category,
+			pq.Array(&doc.Tags),
+			&doc.Author,
+			&doc.CreatedAt,
+			&doc.UpdatedAt,
+			&metadataJSON,
+		)
+
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan document: %w", err)
+		}
+
+		// Parse metadata
+		if len(metadataJSON) > 0 {
+			if err := json.Unmarshal(metadataJSON, &doc.Metadata); err != nil {
+				return nil, 0, fmt.Errorf("failed to unmarshal metadata: %w", err)
+			}
+		}
+
+		documents = append(documents, &doc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate documents: %w", err)
+	}
+
+	return documents, total, nil
+}
+
+// listDocumentsFilter builds the WHERE clause and its arguments for ListDocuments.
+func listDocumentsFilter(opts types.DocumentListOptions) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if opts.Category != "" {
+		add("category = $%d", opts.Category)
+	}
+	if opts.Tag != "" {
+		// Containment rather than ANY so the GIN index on tags is used
+		add("tags @> $%d", pq.Array([]string{opts.Tag}))
+	}
+	if opts.Author != "" {
+		add("author = $%d", opts.Author)
+	}
+	if !opts.CreatedAfter.IsZero() {
+		add("created_at >= $%d", opts.CreatedAfter)
+	}
+	if !opts.CreatedBefore.IsZero() {
+		add("created_at < $%d", opts.CreatedBefore)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// DeleteDocument deletes a document by ID. It reports whether the document existed.
+func (p *PostgresStore) DeleteDocument(ctx context.Context, id string) (bool, error) {
+	return DeleteDocument(ctx, p.db, id)
+}
+
+// DeleteDocument deletes a document by ID using db, which may be a
+// transaction. It reports whether the document existed.
+func DeleteDocument(ctx context.Context, db Execer, id string) (bool, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM documents WHERE id = $1", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	return deleted > 0, nil
+}
+
+// searchHeadlineOptions configures the snippets of search results: up to two
+// fragments of the content with the matching terms in Markdown bold.
+const searchHeadlineOptions = `StartSel=**, StopSel=**, MaxFragments=2, MaxWords=35, MinWords=15, FragmentDelimiter=" ... "`
+
+// SearchDocuments runs a full-text search over title, tags and content, in
+// that order of weight. The query accepts web search syntax such as quoted
+// phrases, "or" and -exclusions. Results are ordered by relevance and carry
+// their rank and a highlighted snippet.
+func (p *PostgresStore) SearchDocuments(ctx context.Context, query string, limit int) ([]*types.Document, error) {
+	searchQuery := `
+	SELECT id, title, content, category, tags, author, created_at, updated_at, metadata,
+		ts_rank_cd(search_vector, q) AS rank,
+		ts_headline('english', content, q, $3) AS snippet
+	FROM documents, websearch_to_tsquery('english', $1) AS q
+	WHERE search_vector @@ q
+	ORDER BY rank DESC, updated_at DESC
 	LIMIT $2
 	`
 
-	searchTerm := "%" + query + "%"
-	rows, err := p.db.Query(searchQuery, searchTerm, limit)
+	rows, err := p.db.QueryContext(ctx, searchQuery, query, limit, searchHeadlineOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search documents: %w", err)
 	}
@@ -219,6 +373,8 @@ func (p *PostgresStore) SearchDocuments(query string, limit int) ([]*types.Docum
 			&doc.CreatedAt,
 			&doc.UpdatedAt,
 			&metadataJSON,
+			&doc.Rank,
+			&doc.Snippet,
 		)
 
 		if err != nil {
@@ -235,6 +391,10 @@ func (p *PostgresStore) SearchDocuments(query string, limit int) ([]*types.Docum
 		documents = append(documents, &doc)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate documents: %w", err)
+	}
+
 	return documents, nil
 }
 
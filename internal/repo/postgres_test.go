@@ -0,0 +1,26 @@
+package repo
+
+import (
+	"testing"
+	"time"
+
+	"tech-docs-ai/internal/types"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListDocumentsFilter(t *testing.T) {
+	where, args := listDocumentsFilter(types.DocumentListOptions{Limit: 20})
+	assert.Empty(t, where)
+	assert.Empty(t, args)
+
+	after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	where, args = listDocumentsFilter(types.DocumentListOptions{
+		Category:     "Go",
+		Tag:          "concurrency",
+		CreatedAfter: after,
+	})
+	assert.Equal(t, " WHERE category = $1 AND tags @> $2 AND created_at >= $3", where)
+	assert.Equal(t, []interface{}{"Go", pq.Array([]string{"concurrency"}), after}, args)
+}
@@ -0,0 +1,278 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UserAgent is sent with every request the scrapers make.
+const UserAgent = "Mozilla/5.0 (compatible; TechDocsAI/1.0; +https://github.com/techdocs-ai)"
+
+// robotsAgent is the product token matched against robots.txt user-agent lines.
+const robotsAgent = "TechDocsAI"
+
+// Politeness defaults.
+const (
+	DefaultHostConcurrency = 2
+	DefaultHostDelay       = time.Second
+	// robotsCacheTTL is how long a host's robots.txt is used before it is fetched again.
+	robotsCacheTTL = time.Hour
+	// maxCrawlDelay caps the crawl-delay a robots.txt can ask for.
+	maxCrawlDelay = 30 * time.Second
+)
+
+// ErrDisallowed is returned for requests that the site's robots.txt does not allow.
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
+// ErrNotModified is returned by conditional scrapes of pages that have not
+// changed since the validators they were given.
+var ErrNotModified = errors.New("page not modified")
+
+// Validators are the ETag and Last-Modified headers of a fetched page. Sent
+// back with a later request, they make it conditional on the page having changed.
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// setHeaders makes req conditional on the validators.
+func (v Validators) setHeaders(req *http.Request) {
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// responseValidators returns the validators of resp.
+func responseValidators(resp *http.Response) Validators {
+	return Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+}
+
+// Fetcher sends the scrapers' HTTP requests. It obeys each host's robots.txt
+// and, across every scraper sharing it, limits how many requests run against
+// a host at once and how soon one starts after the previous one.
+type Fetcher struct {
+	client       *http.Client
+	robotsClient *http.Client
+	concurrency  int
+	delay        time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// hostState is the robots.txt and request bookkeeping of one scheme and host.
+type hostState struct {
+	slots chan struct{}
+
+	mu   sync.Mutex
+	next time.Time
+
+	robotsMu      sync.Mutex
+	robots        *robotsRules
+	robotsExpires time.Time
+}
+
+// NewFetcher creates a fetcher whose per-host limits are read from
+// SCRAPE_HOST_CONCURRENCY and SCRAPE_HOST_DELAY, falling back to the
+// defaults for unset or invalid values. A robots.txt crawl-delay longer than
+// SCRAPE_HOST_DELAY takes its place for that host.
+func NewFetcher() *Fetcher {
+	f := &Fetcher{
+		robotsClient: &http.Client{Timeout: 30 * time.Second},
+		concurrency:  DefaultHostConcurrency,
+		delay:        DefaultHostDelay,
+		hosts:        make(map[string]*hostState),
+	}
+	f.client = &http.Client{
+		Timeout: 30 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// Allow up to 10 redirects
+			if len(via) >= 10 {
+				return fmt.Errorf("too many redirects")
+			}
+			// A redirect must not lead to a page robots.txt disallows
+			return f.checkRobots(req)
+		},
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("SCRAPE_HOST_CONCURRENCY")); err == nil && v > 0 {
+		f.concurrency = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("SCRAPE_HOST_DELAY")); err == nil && v >= 0 {
+		f.delay = v
+	}
+
+	return f
+}
+
+// Do sends req once robots.txt allows it and the host's limits leave room.
+// It returns an error wrapping ErrDisallowed for disallowed URLs. The host's
+// request slot is held until the response body is closed.
+func (f *Fetcher) Do(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", UserAgent)
+	}
+
+	host := f.host(req.URL)
+	rules, err := f.robots(req.Context(), host, req.URL)
+	if err != nil {
+		return nil, err
+	}
+	if !rules.allowed(req.URL.RequestURI()) {
+		return nil, fmt.Errorf("%w: %s", ErrDisallowed, req.URL)
+	}
+
+	ctx := req.Context()
+	select {
+	case host.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-host.slots }
+
+	if err := host.wait(ctx, f.interval(rules)); err != nil {
+		release()
+		return nil, err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// checkRobots returns an error wrapping ErrDisallowed if robots.txt does not allow req.
+func (f *Fetcher) checkRobots(req *http.Request) error {
+	rules, err := f.robots(req.Context(), f.host(req.URL), req.URL)
+	if err != nil {
+		return err
+	}
+	if !rules.allowed(req.URL.RequestURI()) {
+		return fmt.Errorf("%w: %s", ErrDisallowed, req.URL)
+	}
+	return nil
+}
+
+// host returns the state of u's scheme and host, creating it on first use.
+func (f *Fetcher) host(u *url.URL) *hostState {
+	key := strings.ToLower(u.Scheme + "://" + u.Host)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	host, ok := f.hosts[key]
+	if !ok {
+		host = &hostState{slots: make(chan struct{}, f.concurrency)}
+		f.hosts[key] = host
+	}
+	return host
+}
+
+// interval is the time between the starts of two requests to a host with the given rules.
+func (f *Fetcher) interval(rules *robotsRules) time.Duration {
+	delay := rules.crawlDelay
+	if delay > maxCrawlDelay {
+		delay = maxCrawlDelay
+	}
+	if delay < f.delay {
+		delay = f.delay
+	}
+	return delay
+}
+
+// robots returns the host's robots.txt rules, fetching them if they are not
+// cached. A missing robots.txt allows everything; a server error is returned
+// so the job is retried later rather than crawling without rules.
+func (f *Fetcher) robots(ctx context.Context, host *hostState, u *url.URL) (*robotsRules, error) {
+	host.robotsMu.Lock()
+	defer host.robotsMu.Unlock()
+
+	if host.robots != nil && time.Now().Before(host.robotsExpires) {
+		return host.robots, nil
+	}
+
+	robotsURL := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create robots.txt request: %w", err)
+	}
+	req.Header.Set("User-Agent", UserAgent)
+
+	resp, err := f.robotsClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch robots.txt: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var rules *robotsRules
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		rules = parseRobots(resp.Body, robotsAgent)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		rules = allowAll
+	default:
+		return nil, fmt.Errorf("failed to fetch robots.txt: HTTP error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	host.robots = rules
+	host.robotsExpires = time.Now().Add(robotsCacheTTL)
+	return rules, nil
+}
+
+// wait blocks until a request may start, at least interval after the
+// previous request to the host started.
+func (h *hostState) wait(ctx context.Context, interval time.Duration) error {
+	h.mu.Lock()
+	now := time.Now()
+	start := h.next
+	if start.Before(now) {
+		start = now
+	}
+	h.next = start.Add(interval)
+	h.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releasingBody frees the host's request slot when the response body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,138 @@
+package scraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFetcher returns a fetcher without a delay between requests.
+func newTestFetcher(concurrency int) *Fetcher {
+	f := NewFetcher()
+	f.concurrency = concurrency
+	f.delay = 0
+	return f
+}
+
+func TestFetcher_Robots(t *testing.T) {
+	var robotsFetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			atomic.AddInt32(&robotsFetches, 1)
+			w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+		case "/moved":
+			http.Redirect(w, r, "/private/page", http.StatusFound)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	f := newTestFetcher(2)
+	get := func(path string) error {
+		req, err := http.NewRequest("GET", server.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := f.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	assert.NoError(t, get("/docs/"))
+	assert.ErrorIs(t, get("/private/page"), ErrDisallowed)
+	assert.ErrorIs(t, get("/moved"), ErrDisallowed)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&robotsFetches), "robots.txt should be cached")
+}
+
+func TestFetcher_RobotsStatus(t *testing.T) {
+	for _, tt := range []struct {
+		status int
+		ok     bool
+	}{
+		{http.StatusNotFound, true},
+		{http.StatusServiceUnavailable, false},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/robots.txt" {
+				w.WriteHeader(tt.status)
+			}
+		}))
+
+		req, err := http.NewRequest("GET", server.URL+"/page", nil)
+		require.NoError(t, err)
+		resp, err := newTestFetcher(2).Do(req)
+		if tt.ok {
+			require.NoError(t, err, tt.status)
+			resp.Body.Close()
+		} else {
+			assert.Error(t, err, tt.status)
+			assert.NotErrorIs(t, err, ErrDisallowed)
+		}
+
+		server.Close()
+	}
+}
+
+func TestFetcher_HostConcurrency(t *testing.T) {
+	var running, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			return
+		}
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+	}))
+	defer server.Close()
+
+	f := newTestFetcher(2)
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", server.URL+"/page", nil)
+			if resp, err := f.Do(req); assert.NoError(t, err) {
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&peak))
+}
+
+func TestFetcher_HostDelay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nCrawl-delay: 0.05\n"))
+		}
+	}))
+	defer server.Close()
+
+	f := newTestFetcher(4)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", server.URL+"/page", nil)
+		resp, err := f.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	// The first request starts at once, the next two wait out the crawl-delay
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
@@ -0,0 +1,75 @@
+package scraper
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// extractLinks returns the absolute http(s) URLs of the page's links in
+// document order, normalized and without duplicates. Relative links are
+// resolved against the page's <base href> or, without one, against base.
+func extractLinks(doc *goquery.Document, base *url.URL) []string {
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if resolved, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			base = resolved
+		}
+	}
+
+	seen := make(map[string]bool)
+	var links []string
+	doc.Find("a[href]").Each(func(i int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		if rel, _ := a.Attr("rel"); strings.Contains(rel, "nofollow") {
+			return
+		}
+
+		resolved, err := base.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return
+		}
+		link, ok := normalizeURL(resolved)
+		if !ok || seen[link] {
+			return
+		}
+		seen[link] = true
+		links = append(links, link)
+	})
+
+	return links
+}
+
+// NormalizeURL returns the canonical form of an absolute http(s) URL, so
+// that different spellings of one page compare equal: the scheme and host
+// are lowercased, default ports and the fragment are dropped and an empty
+// path becomes "/".
+func NormalizeURL(rawURL string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", false
+	}
+	return normalizeURL(u)
+}
+
+func normalizeURL(u *url.URL) (string, bool) {
+	scheme := strings.ToLower(u.Scheme)
+	if (scheme != "http" && scheme != "https") || u.Host == "" {
+		return "", false
+	}
+
+	normalized := *u
+	normalized.Scheme = scheme
+	normalized.Host = strings.ToLower(u.Host)
+	if port := normalized.Port(); (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		normalized.Host = normalized.Hostname()
+	}
+	normalized.Fragment = ""
+	normalized.RawFragment = ""
+	normalized.User = nil
+	if normalized.Path == "" {
+		normalized.Path = "/"
+	}
+
+	return normalized.String(), true
+}
@@ -0,0 +1,67 @@
+package scraper
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractLinks(t *testing.T) {
+	html := `
+<html><body>
+	<a href="/docs/intro">Intro</a>
+	<a href="install#linux">Install</a>
+	<a href="install">Install again</a>
+	<a href="HTTPS://Example.com:443/docs/intro#top">Intro again</a>
+	<a href="https://other.org/page">Elsewhere</a>
+	<a href="mailto:docs@example.com">Mail</a>
+	<a href="javascript:void(0)">Script</a>
+	<a href="/login" rel="nofollow">Login</a>
+</body></html>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	require.NoError(t, err)
+	base, _ := url.Parse("https://example.com/docs/guide")
+
+	links := extractLinks(doc, base)
+
+	assert.Equal(t, []string{
+		"https://example.com/docs/intro",
+		"https://example.com/docs/install",
+		"https://other.org/page",
+	}, links)
+}
+
+func TestExtractLinks_BaseHref(t *testing.T) {
+	html := `<html><head><base href="https://cdn.example.com/v2/"></head><body><a href="api">API</a></body></html>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	require.NoError(t, err)
+	base, _ := url.Parse("https://example.com/docs/")
+
+	assert.Equal(t, []string{"https://cdn.example.com/v2/api"}, extractLinks(doc, base))
+}
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		ok       bool
+	}{
+		{"https://Example.COM", "https://example.com/", true},
+		{"http://example.com:80/a?b=1#c", "http://example.com/a?b=1", true},
+		{"https://example.com:8443/a", "https://example.com:8443/a", true},
+		{"ftp://example.com/file", "", false},
+		{"/relative/path", "", false},
+	}
+
+	for _, tt := range tests {
+		normalized, ok := NormalizeURL(tt.input)
+		assert.Equal(t, tt.ok, ok, tt.input)
+		assert.Equal(t, tt.expected, normalized, tt.input)
+	}
+}
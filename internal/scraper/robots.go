@@ -0,0 +1,158 @@
+package scraper
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRobotsSize caps how much of a robots.txt file is read. RFC 9309 asks
+// crawlers to parse at least the first 500 KiB.
+const maxRobotsSize = 500 << 10
+
+// robotsRules are the rules of a robots.txt file that apply to one user agent.
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// robotsRule is one Allow or Disallow line.
+type robotsRule struct {
+	allow   bool
+	length  int
+	pattern *regexp.Regexp
+}
+
+// robotsGroup is a group of rules and the user agents it names.
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// allowAll are the rules used when a site has no robots.txt.
+var allowAll = &robotsRules{}
+
+// parseRobots parses a robots.txt file and returns the rules for agent, the
+// product token of the crawler's user agent. Groups naming agent take
+// precedence over the "*" group; several groups for the same agent are merged.
+func parseRobots(r io.Reader, agent string) *robotsRules {
+	var groups []*robotsGroup
+	var current *robotsGroup
+	inAgents := false
+
+	scanner := bufio.NewScanner(io.LimitReader(r, maxRobotsSize))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// Consecutive user-agent lines share one group
+			if !inAgents {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+				inAgents = true
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inAgents = false
+			// An empty disallow allows everything, which is the default anyway
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{
+				allow:   key == "allow",
+				length:  len(value),
+				pattern: robotsPattern(value),
+			})
+		case "crawl-delay":
+			inAgents = false
+			if current == nil {
+				continue
+			}
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	agent = strings.ToLower(agent)
+	if rules := mergeGroups(groups, agent); rules != nil {
+		return rules
+	}
+	if rules := mergeGroups(groups, "*"); rules != nil {
+		return rules
+	}
+	return allowAll
+}
+
+// mergeGroups combines the groups that name agent, or returns nil if none does.
+func mergeGroups(groups []*robotsGroup, agent string) *robotsRules {
+	var merged *robotsRules
+	for _, group := range groups {
+		for _, name := range group.agents {
+			// Agents may be given with a version, as in "TechDocsAI/1.0"
+			name, _, _ = strings.Cut(name, "/")
+			if name != agent {
+				continue
+			}
+			if merged == nil {
+				merged = &robotsRules{}
+			}
+			merged.rules = append(merged.rules, group.rules...)
+			if group.crawlDelay > merged.crawlDelay {
+				merged.crawlDelay = group.crawlDelay
+			}
+			break
+		}
+	}
+	return merged
+}
+
+// robotsPattern compiles a path pattern, where "*" matches any characters and
+// a trailing "$" anchors the pattern to the end of the path.
+func robotsPattern(value string) *regexp.Regexp {
+	anchored := strings.HasSuffix(value, "$")
+	value = strings.TrimSuffix(value, "$")
+
+	parts := strings.Split(value, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// allowed reports whether path, including its query, may be fetched. The
+// most specific matching rule wins, and Allow wins a tie.
+func (r *robotsRules) allowed(path string) bool {
+	if path == "/robots.txt" {
+		return true
+	}
+
+	allow, length := true, -1
+	for _, rule := range r.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > length || (rule.length == length && rule.allow) {
+			allow, length = rule.allow, rule.length
+		}
+	}
+	return allow
+}
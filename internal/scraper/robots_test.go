@@ -0,0 +1,65 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testRobots = `
+# Rules for everyone
+User-agent: *
+Disallow: /private/
+Allow: /private/public-page
+Crawl-delay: 2
+
+User-agent: OtherBot
+User-agent: TechDocsAI/1.0
+Disallow: /drafts/
+Disallow: /*.pdf$
+Allow: /drafts/published/
+
+User-agent: techdocsai
+Crawl-delay: 5
+`
+
+func TestParseRobots_AgentGroups(t *testing.T) {
+	rules := parseRobots(strings.NewReader(testRobots), "TechDocsAI")
+
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{"/docs/intro", true},
+		// The groups naming the agent replace the "*" group
+		{"/private/notes", true},
+		{"/drafts/todo", false},
+		{"/drafts/published/post", true},
+		{"/guide.pdf", false},
+		{"/guide.pdf?download=1", true},
+		{"/robots.txt", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, rules.allowed(tt.path), tt.path)
+	}
+
+	assert.Equal(t, 5*time.Second, rules.crawlDelay)
+}
+
+func TestParseRobots_Wildcard(t *testing.T) {
+	rules := parseRobots(strings.NewReader(testRobots), "SomeBot")
+
+	assert.False(t, rules.allowed("/private/notes"))
+	assert.True(t, rules.allowed("/private/public-page"), "the longer allow rule wins")
+	assert.True(t, rules.allowed("/drafts/todo"))
+	assert.Equal(t, 2*time.Second, rules.crawlDelay)
+}
+
+func TestParseRobots_NoRules(t *testing.T) {
+	rules := parseRobots(strings.NewReader("<html><body>Not found</body></html>"), "TechDocsAI")
+
+	assert.True(t, rules.allowed("/anything"))
+	assert.Zero(t, rules.crawlDelay)
+}
@@ -0,0 +1,142 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxSitemapSize caps how much of a sitemap is read. The sitemap protocol
+// limits files to 50MB uncompressed.
+const maxSitemapSize = 50 << 20
+
+// SitemapEntry is a page listed in a sitemap. LastMod is zero when the
+// sitemap does not say when the page last changed.
+type SitemapEntry struct {
+	URL     string
+	LastMod time.Time
+}
+
+// Sitemap is a parsed sitemap file: either a list of pages or, for a
+// sitemap index, a list of further sitemaps.
+type Sitemap struct {
+	Pages    []SitemapEntry
+	Sitemaps []SitemapEntry
+}
+
+// SitemapScraper fetches and parses sitemap.xml files and sitemap indexes.
+type SitemapScraper struct {
+	fetcher *Fetcher
+}
+
+// NewSitemapScraper creates a new sitemap scraper that sends its requests through fetcher.
+func NewSitemapScraper(fetcher *Fetcher) *SitemapScraper {
+	return &SitemapScraper{
+		fetcher: fetcher,
+	}
+}
+
+// FetchSitemap fetches and parses the sitemap at sitemapURL. Gzip-compressed
+// sitemaps are decompressed.
+func (s *SitemapScraper) FetchSitemap(sitemapURL string) (*Sitemap, error) {
+	log.Printf("Fetching sitemap: %s", sitemapURL)
+
+	req, err := http.NewRequest("GET", sitemapURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept", "application/xml,text/xml;q=0.9,*/*;q=0.8")
+
+	resp, err := s.fetcher.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sitemap: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	return ParseSitemap(io.LimitReader(resp.Body, maxSitemapSize))
+}
+
+// sitemapXML matches both <urlset> and <sitemapindex> documents.
+type sitemapXML struct {
+	XMLName  xml.Name
+	URLs     []sitemapLocation `xml:"url"`
+	Sitemaps []sitemapLocation `xml:"sitemap"`
+}
+
+type sitemapLocation struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// ParseSitemap parses a sitemap or sitemap index, which may be gzip-compressed.
+func ParseSitemap(r io.Reader) (*Sitemap, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap: %w", err)
+		}
+		defer gz.Close()
+		r = io.LimitReader(gz, maxSitemapSize)
+	} else {
+		r = br
+	}
+
+	var parsed sitemapXML
+	if err := xml.NewDecoder(r).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap: %w", err)
+	}
+	if parsed.XMLName.Local != "urlset" && parsed.XMLName.Local != "sitemapindex" {
+		return nil, fmt.Errorf("not a sitemap: root element is <%s>", parsed.XMLName.Local)
+	}
+
+	return &Sitemap{
+		Pages:    sitemapEntries(parsed.URLs),
+		Sitemaps: sitemapEntries(parsed.Sitemaps),
+	}, nil
+}
+
+// sitemapEntries converts sitemap locations to entries, dropping ones without a URL.
+func sitemapEntries(locations []sitemapLocation) []SitemapEntry {
+	entries := make([]SitemapEntry, 0, len(locations))
+	for _, location := range locations {
+		loc := strings.TrimSpace(location.Loc)
+		if loc == "" {
+			continue
+		}
+		entries = append(entries, SitemapEntry{URL: loc, LastMod: parseLastMod(location.LastMod)})
+	}
+	return entries
+}
+
+// lastModLayouts are the W3C datetime forms allowed in <lastmod>.
+var lastModLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// parseLastMod parses a <lastmod> value, returning the zero time if it is missing or invalid.
+func parseLastMod(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range lastModLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,98 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testURLSet = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>https://example.com/docs/intro</loc><lastmod>2024-03-01T10:30:00+00:00</lastmod></url>
+	<url><loc> https://example.com/docs/install </loc><lastmod>2024-02-15</lastmod></url>
+	<url><loc>https://example.com/docs/faq</loc></url>
+	<url><loc></loc></url>
+</urlset>`
+
+func TestParseSitemap_URLSet(t *testing.T) {
+	sitemap, err := ParseSitemap(strings.NewReader(testURLSet))
+	require.NoError(t, err)
+
+	assert.Empty(t, sitemap.Sitemaps)
+	assert.Equal(t, []SitemapEntry{
+		{URL: "https://example.com/docs/intro", LastMod: time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)},
+		{URL: "https://example.com/docs/install", LastMod: time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC)},
+		{URL: "https://example.com/docs/faq"},
+	}, normalizeEntryTimes(sitemap.Pages))
+}
+
+func TestParseSitemap_Index(t *testing.T) {
+	index := `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+		<sitemap><loc>https://example.com/sitemap-docs.xml.gz</loc><lastmod>2024-03</lastmod></sitemap>
+	</sitemapindex>`
+
+	sitemap, err := ParseSitemap(strings.NewReader(index))
+	require.NoError(t, err)
+
+	assert.Empty(t, sitemap.Pages)
+	require.Len(t, sitemap.Sitemaps, 1)
+	assert.Equal(t, "https://example.com/sitemap-docs.xml.gz", sitemap.Sitemaps[0].URL)
+	assert.True(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC).Equal(sitemap.Sitemaps[0].LastMod))
+}
+
+func TestParseSitemap_Gzip(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(testURLSet))
+	require.NoError(t, gz.Close())
+
+	sitemap, err := ParseSitemap(&compressed)
+	require.NoError(t, err)
+	assert.Len(t, sitemap.Pages, 3)
+}
+
+func TestParseSitemap_Invalid(t *testing.T) {
+	_, err := ParseSitemap(strings.NewReader(`<html><body>Not found</body></html>`))
+	assert.Error(t, err)
+
+	_, err = ParseSitemap(strings.NewReader(`not xml`))
+	assert.Error(t, err)
+}
+
+func TestSitemapScraper_FetchSitemap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(testURLSet))
+	}))
+	defer server.Close()
+
+	s := NewSitemapScraper(NewFetcher())
+
+	sitemap, err := s.FetchSitemap(server.URL + "/sitemap.xml")
+	require.NoError(t, err)
+	assert.Len(t, sitemap.Pages, 3)
+
+	_, err = s.FetchSitemap(server.URL + "/missing.xml")
+	assert.Error(t, err)
+}
+
+// normalizeEntryTimes converts the entries' times to UTC so they compare with assert.Equal.
+func normalizeEntryTimes(entries []SitemapEntry) []SitemapEntry {
+	for i := range entries {
+		if !entries[i].LastMod.IsZero() {
+			entries[i].LastMod = entries[i].LastMod.UTC()
+		}
+	}
+	return entries
+}
@@ -15,27 +15,24 @@ import (
 
 // UniversalScraper scrapes content from any website with intelligent content extraction
 type UniversalScraper struct {
-	client *http.Client
+	fetcher *Fetcher
 }
 
-// NewUniversalScraper creates a new universal scraper
-func NewUniversalScraper() *UniversalScraper {
+// NewUniversalScraper creates a new universal scraper that sends its requests through fetcher
+func NewUniversalScraper(fetcher *Fetcher) *UniversalScraper {
 	return &UniversalScraper{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				// Allow up to 10 redirects
-				if len(via) >= 10 {
-					return fmt.Errorf("too many redirects")
-				}
-				return nil
-			},
-		},
+		fetcher: fetcher,
 	}
 }
 
 // ScrapePage scrapes content from any URL with intelligent content extraction
 func (s *UniversalScraper) ScrapePage(targetURL string) (*ScrapedContent, error) {
+	return s.ScrapePageIfModified(targetURL, Validators{})
+}
+
+// ScrapePageIfModified scrapes a URL like ScrapePage, but returns ErrNotModified
+// if the page has not changed since the cached validators
+func (s *UniversalScraper) ScrapePageIfModified(targetURL string, cached Validators) (*ScrapedContent, error) {
 	log.Printf("Universal scraping: %s", targetURL)
 
 	// Validate URL
@@ -51,16 +48,20 @@ func (s *UniversalScraper) ScrapePage(targetURL string) (*ScrapedContent, error)
 	}
 
 	// Set user agent to avoid blocking
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; TechDocsAI/1.0; +https://github.com/techdocs-ai)")
+	req.Header.Set("User-Agent", UserAgent)
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
+	cached.setHeaders(req)
 
-	resp, err := s.client.Do(req)
+	resp, err := s.fetcher.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch page: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, ErrNotModified
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
 	}
@@ -96,6 +97,12 @@ func (s *UniversalScraper) ScrapePage(targetURL string) (*ScrapedContent, error)
 	// Extract metadata
 	content.Metadata = s.extractMetadata(doc, targetURL, parsedURL)
 
+	// Extract links for crawling, relative to the page's final URL after redirects
+	content.Links = extractLinks(doc, resp.Request.URL)
+
+	// Keep the validators for re-fetching the page conditionally
+	content.Validators = responseValidators(resp)
+
 	return content, nil
 }
 
@@ -61,7 +61,7 @@ console.log(greet("World"));
 	defer server.Close()
 
 	// Test scraping
-	scraper := NewUniversalScraper()
+	scraper := NewUniversalScraper(NewFetcher())
 	content, err := scraper.ScrapePage(server.URL)
 
 	require.NoError(t, err)
@@ -109,7 +109,7 @@ func TestUniversalScraper_ExtractTitle(t *testing.T) {
 		},
 	}
 
-	scraper := NewUniversalScraper()
+	scraper := NewUniversalScraper(NewFetcher())
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -160,7 +160,7 @@ func TestUniversalScraper_ExtractCategoryFromURL(t *testing.T) {
 		},
 	}
 
-	scraper := NewUniversalScraper()
+	scraper := NewUniversalScraper(NewFetcher())
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -204,7 +204,7 @@ func TestUniversalScraper_DetectLanguage(t *testing.T) {
 		},
 	}
 
-	scraper := NewUniversalScraper()
+	scraper := NewUniversalScraper(NewFetcher())
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -232,7 +232,7 @@ func TestUniversalScraper_ConvertToDocument(t *testing.T) {
 		},
 	}
 
-	scraper := NewUniversalScraper()
+	scraper := NewUniversalScraper(NewFetcher())
 	doc := scraper.ConvertToDocument(content)
 
 	assert.NotNil(t, doc)
@@ -247,7 +247,7 @@ func TestUniversalScraper_ConvertToDocument(t *testing.T) {
 }
 
 func TestUniversalScraper_ErrorHandling(t *testing.T) {
-	scraper := NewUniversalScraper()
+	scraper := NewUniversalScraper(NewFetcher())
 
 	// Test invalid URL
 	_, err := scraper.ScrapePage("invalid-url")
@@ -267,7 +267,7 @@ func TestUniversalScraper_HTTPErrorHandling(t *testing.T) {
 	}))
 	defer server.Close()
 
-	scraper := NewUniversalScraper()
+	scraper := NewUniversalScraper(NewFetcher())
 	_, err := scraper.ScrapePage(server.URL)
 
 	assert.Error(t, err)
@@ -275,7 +275,7 @@ func TestUniversalScraper_HTTPErrorHandling(t *testing.T) {
 }
 
 func TestUniversalScraper_DeduplicateTags(t *testing.T) {
-	scraper := NewUniversalScraper()
+	scraper := NewUniversalScraper(NewFetcher())
 	
 	tags := []string{"javascript", "tutorial", "javascript", "documentation", "tutorial", "web"}
 	result := scraper.deduplicateTags(tags)
@@ -284,6 +284,32 @@ func TestUniversalScraper_DeduplicateTags(t *testing.T) {
 	assert.Equal(t, expected, result)
 }
 
+func TestUniversalScraper_ScrapePageIfModified(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 04 Mar 2024 10:00:00 GMT")
+		w.Write([]byte(`<html><body><h1>Cached Page</h1></body></html>`))
+	}))
+	defer server.Close()
+
+	scraper := NewUniversalScraper(newTestFetcher(1))
+
+	content, err := scraper.ScrapePage(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, Validators{ETag: `"v1"`, LastModified: "Mon, 04 Mar 2024 10:00:00 GMT"}, content.Validators)
+
+	_, err = scraper.ScrapePageIfModified(server.URL, content.Validators)
+	assert.ErrorIs(t, err, ErrNotModified)
+
+	content, err = scraper.ScrapePageIfModified(server.URL, Validators{ETag: `"v0"`})
+	require.NoError(t, err)
+	assert.Equal(t, "Cached Page", content.Title)
+}
+
 func BenchmarkUniversalScraper_ScrapePage(b *testing.B) {
 	testHTML := `
 <!DOCTYPE html>
@@ -302,7 +328,7 @@ func BenchmarkUniversalScraper_ScrapePage(b *testing.B) {
 	}))
 	defer server.Close()
 
-	scraper := NewUniversalScraper()
+	scraper := NewUniversalScraper(newTestFetcher(1))
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -14,41 +14,56 @@ import (
 
 // W3SchoolsScraper scrapes content from W3Schools website.
 type W3SchoolsScraper struct {
-	client *http.Client
+	fetcher *Fetcher
 }
 
-// NewW3SchoolsScraper creates a new W3Schools scraper.
-func NewW3SchoolsScraper() *W3SchoolsScraper {
+// NewW3SchoolsScraper creates a new W3Schools scraper that sends its requests through fetcher.
+func NewW3SchoolsScraper(fetcher *Fetcher) *W3SchoolsScraper {
 	return &W3SchoolsScraper{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		fetcher: fetcher,
 	}
 }
 
 // ScrapedContent represents the content extracted from a W3Schools page.
 type ScrapedContent struct {
-	URL       string            `json:"url"`
-	Title     string            `json:"title"`
-	Content   string            `json:"content"`
-	Category  string            `json:"category"`
-	Tags      []string          `json:"tags"`
-	Examples  []string          `json:"examples"`
-	Metadata  map[string]string `json:"metadata"`
-	Timestamp time.Time         `json:"timestamp"`
+	URL        string            `json:"url"`
+	Title      string            `json:"title"`
+	Content    string            `json:"content"`
+	Category   string            `json:"category"`
+	Tags       []string          `json:"tags"`
+	Examples   []string          `json:"examples"`
+	Metadata   map[string]string `json:"metadata"`
+	Links      []string          `json:"links,omitempty"` // absolute URLs linked from the page, for crawling
+	Validators Validators        `json:"validators"`      // cache validators of the response, for re-fetching conditionally
+	Timestamp  time.Time         `json:"timestamp"`
 }
 
 // ScrapePage scrapes a single W3Schools page.
 func (s *W3SchoolsScraper) ScrapePage(url string) (*ScrapedContent, error) {
+	return s.ScrapePageIfModified(url, Validators{})
+}
+
+// ScrapePageIfModified scrapes a W3Schools page like ScrapePage, but returns
+// ErrNotModified if the page has not changed since the cached validators.
+func (s *W3SchoolsScraper) ScrapePageIfModified(url string, cached Validators) (*ScrapedContent, error) {
 	log.Printf("Scraping: %s", url)
 
 	// Make HTTP request
-	resp, err := s.client.Get(url)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	cached.setHeaders(req)
+
+	resp, err := s.fetcher.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch page: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, ErrNotModified
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
 	}
@@ -87,6 +102,12 @@ func (s *W3SchoolsScraper) ScrapePage(url string) (*ScrapedContent, error) {
 	// Extract metadata
 	content.Metadata = s.extractMetadata(doc, url)
 
+	// Extract links for crawling
+	content.Links = extractLinks(doc, resp.Request.URL)
+
+	// Keep the validators for re-fetching the page conditionally
+	content.Validators = responseValidators(resp)
+
 	return content, nil
 }
 
@@ -15,6 +15,63 @@ type Document struct {
 	CreatedAt time.Time         `json:"created_at"`
 	UpdatedAt time.Time         `json:"updated_at"`
 	Metadata  map[string]string `json:"metadata"`
+	// Rank and Snippet are only set on keyword search results. Snippet is an
+	// excerpt of the content with the matching terms in **bold**.
+	Rank    float32 `json:"rank,omitempty"`
+	Snippet string  `json:"snippet,omitempty"`
+}
+
+// DocumentListOptions filters and pages a document listing. Empty fields do not filter.
+type DocumentListOptions struct {
+	Category string
+	Tag      string
+	Author   string
+	// CreatedAfter and CreatedBefore bound the creation time, inclusive and exclusive.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Limit         int
+	Offset        int
+}
+
+// DocumentList is one page of a document listing. Total counts every matching document.
+type DocumentList struct {
+	Documents []*Document `json:"documents"`
+	Total     int         `json:"total"`
+	Limit     int         `json:"limit"`
+	Offset    int         `json:"offset"`
+}
+
+// DocumentPatch is a partial document update. Nil fields are left unchanged;
+// a non-nil Metadata replaces the whole map.
+type DocumentPatch struct {
+	Title    *string           `json:"title"`
+	Content  *string           `json:"content"`
+	Category *string           `json:"category"`
+	Tags     *[]string         `json:"tags"`
+	Author   *string           `json:"author"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// Apply changes doc according to the patch.
+func (p DocumentPatch) Apply(doc *Document) {
+	if p.Title != nil {
+		doc.Title = *p.Title
+	}
+	if p.Content != nil {
+		doc.Content = *p.Content
+	}
+	if p.Category != nil {
+		doc.Category = *p.Category
+	}
+	if p.Tags != nil {
+		doc.Tags = *p.Tags
+	}
+	if p.Author != nil {
+		doc.Author = *p.Author
+	}
+	if p.Metadata != nil {
+		doc.Metadata = p.Metadata
+	}
 }
 
 // SearchResult represents a search result from vector database.
@@ -22,6 +79,67 @@ type SearchResult struct {
 	ID       string                 `json:"id"`
 	Score    float32                `json:"score"`
 	Metadata map[string]interface{} `json:"metadata"`
+	Vector   []float32              `json:"vector,omitempty"`
+}
+
+// SearchOptions tunes a vector search. The zero value searches without a
+// filter using the vector store's configured defaults.
+type SearchOptions struct {
+	// Filter restricts the search to points whose payload matches it.
+	Filter *VectorFilter `json:"filter,omitempty"`
+	// ScoreThreshold drops results scoring below it. Nil uses the collection's threshold.
+	ScoreThreshold *float32 `json:"score_threshold,omitempty"`
+	// HNSWEf is the size of the candidate list used by the HNSW index. Higher is more accurate but slower.
+	HNSWEf *int `json:"hnsw_ef,omitempty"`
+	// Exact disables the index and compares against every point.
+	Exact bool `json:"exact,omitempty"`
+	// Offset skips that many of the best results, for paging.
+	Offset int `json:"offset,omitempty"`
+	// WithVectors returns the stored vector of each result.
+	WithVectors bool `json:"with_vectors,omitempty"`
+}
+
+// VectorFilter restricts a vector search by payload fields. A point matches
+// when it meets every Must condition, at least one Should condition (when
+// there are any) and none of the MustNot conditions.
+type VectorFilter struct {
+	Must    []FieldCondition `json:"must,omitempty"`
+	Should  []FieldCondition `json:"should,omitempty"`
+	MustNot []FieldCondition `json:"must_not,omitempty"`
+}
+
+// FieldCondition matches a payload field against any of Values. For list
+// fields such as tags it matches when any element is one of Values.
+type FieldCondition struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values"`
+}
+
+// IsEmpty reports whether the filter has no conditions.
+func (f *VectorFilter) IsEmpty() bool {
+	return f == nil || len(f.Must)+len(f.Should)+len(f.MustNot) == 0
+}
+
+// VectorPoint is the vector of one document chunk and its payload, to be
+// stored in the vector database. DocumentID and ChunkIndex identify the point.
+type VectorPoint struct {
+	DocumentID string
+	ChunkIndex int
+	Vector     []float32
+	Payload    map[string]interface{}
+}
+
+// Source identifies a document that was used as context for a chat answer.
+// Score is the similarity of the document's best matching chunk to the
+// question; Rank is its fused keyword and vector search score, 1 for a
+// document ranked first by both.
+type Source struct {
+	DocumentID string  `json:"document_id"`
+	Title      string  `json:"title"`
+	URL        string  `json:"url,omitempty"`
+	Category   string  `json:"category"`
+	Score      float32 `json:"score"`
+	Rank       float32 `json:"rank"`
 }
 
 // ScrapeJob represents a scraping job message.
@@ -30,6 +148,171 @@ type ScrapeJob struct {
 	Category string   `json:"category"`
 	Tags     []string `json:"tags"`
 	JobID    string   `json:"job_id"`
+	// Type is the kind of job. Empty means JobTypePage.
+	Type JobType `json:"type,omitempty"`
+	// ParentID, Depth and Crawl are set on the page jobs of a crawl: the ID of
+	// the crawl job, the number of links followed from the seed URL and the
+	// crawl's options. A crawl job sets only Crawl.
+	ParentID string        `json:"parent_id,omitempty"`
+	Depth    int           `json:"depth,omitempty"`
+	Crawl    *CrawlOptions `json:"crawl,omitempty"`
+	// Sitemap is set on sitemap jobs.
+	Sitemap *SitemapOptions `json:"sitemap,omitempty"`
+	// Refresh fetches the page unconditionally, as it is known to have changed since it was stored.
+	Refresh bool `json:"refresh,omitempty"`
+}
+
+// JobType is the kind of a scrape job.
+type JobType string
+
+// Scrape job types. A page job scrapes one URL. A crawl job starts from a
+// seed URL and follows links, and a sitemap job reads the pages listed in a
+// sitemap; both scrape every page as a page job of its own.
+const (
+	JobTypePage    JobType = "page"
+	JobTypeCrawl   JobType = "crawl"
+	JobTypeSitemap JobType = "sitemap"
+)
+
+// HasPages reports whether jobs of the type spawn page jobs.
+func (t JobType) HasPages() bool {
+	return t == JobTypeCrawl || t == JobTypeSitemap
+}
+
+// CrawlOptions limits which pages a crawl visits.
+type CrawlOptions struct {
+	// MaxDepth is how many links away from the seed URL the crawl goes. Zero scrapes only the seed.
+	MaxDepth int `json:"max_depth"`
+	// MaxPages caps the number of pages the crawl scrapes.
+	MaxPages int `json:"max_pages"`
+	// Include and Exclude are regular expressions matched against page URLs.
+	// With Include set, a page must match one of them; it must match none of Exclude.
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	// SameDomain keeps the crawl on the seed URL's host.
+	SameDomain bool `json:"same_domain"`
+}
+
+// SitemapOptions selects which pages of a sitemap are scraped.
+type SitemapOptions struct {
+	// Include and Exclude are regular expressions matched against page URL paths.
+	// With Include set, a page must match one of them; it must match none of Exclude.
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	// ModifiedSince skips pages whose lastmod is before it.
+	ModifiedSince *time.Time `json:"modified_since,omitempty"`
+	// MaxPages caps the number of pages the job scrapes.
+	MaxPages int `json:"max_pages"`
+}
+
+// CrawlProgress counts the pages of a crawl or sitemap job by status.
+type CrawlProgress struct {
+	Pages      int `json:"pages"`
+	Queued     int `json:"queued"`
+	Running    int `json:"running"`
+	Retrying   int `json:"retrying"`
+	Succeeded  int `json:"succeeded"`
+	Failed     int `json:"failed"`
+	Skipped    int `json:"skipped"`
+	Disallowed int `json:"disallowed"`
+}
+
+// JobStatus is the state of a scrape job.
+type JobStatus string
+
+// Scrape job states. A job is queued when it is sent to Kafka, running while
+// a worker processes it, retrying while it waits to be attempted again after
+// a failure, and ends as succeeded, failed, skipped (when the content was
+// already stored) or disallowed (when robots.txt does not allow the URL).
+const (
+	JobQueued     JobStatus = "queued"
+	JobRunning    JobStatus = "running"
+	JobRetrying   JobStatus = "retrying"
+	JobSucceeded  JobStatus = "succeeded"
+	JobFailed     JobStatus = "failed"
+	JobSkipped    JobStatus = "skipped"
+	JobDisallowed JobStatus = "disallowed"
+)
+
+// ScrapeJobRecord is the tracked state of a scrape job.
+type ScrapeJobRecord struct {
+	JobID    string    `json:"job_id"`
+	Type     JobType   `json:"type"`
+	URL      string    `json:"url"`
+	Category string    `json:"category"`
+	Tags     []string  `json:"tags"`
+	Status   JobStatus `json:"status"`
+	// ParentID, Depth, Crawl, Sitemap and Refresh are as in ScrapeJob.
+	ParentID string          `json:"parent_id,omitempty"`
+	Depth    int             `json:"depth,omitempty"`
+	Crawl    *CrawlOptions   `json:"crawl,omitempty"`
+	Sitemap  *SitemapOptions `json:"sitemap,omitempty"`
+	Refresh  bool            `json:"refresh,omitempty"`
+	// Progress is set on crawl and sitemap jobs.
+	Progress *CrawlProgress `json:"progress,omitempty"`
+	// Error explains why a failed job failed or why a job was skipped.
+	Error string `json:"error,omitempty"`
+	// DocumentIDs are the documents the job stored, or found already stored when skipped.
+	DocumentIDs []string   `json:"document_ids"`
+	Attempts    int        `json:"attempts"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	// DeadLetteredAt is set when the job failed its last attempt and was sent to the dead-letter topic.
+	DeadLetteredAt *time.Time `json:"dead_lettered_at,omitempty"`
+}
+
+// Job returns the message that queues the job.
+func (r *ScrapeJobRecord) Job() ScrapeJob {
+	return ScrapeJob{
+		URL:      r.URL,
+		Category: r.Category,
+		Tags:     r.Tags,
+		JobID:    r.JobID,
+		Type:     r.Type,
+		ParentID: r.ParentID,
+		Depth:    r.Depth,
+		Crawl:    r.Crawl,
+		Sitemap:  r.Sitemap,
+		Refresh:  r.Refresh,
+	}
+}
+
+// ScrapeJobListOptions filters and pages a scrape job listing. An empty Status does not filter.
+type ScrapeJobListOptions struct {
+	Status JobStatus
+	// DeadLettered lists only the jobs waiting in the dead-letter topic.
+	DeadLettered bool
+	// ParentID lists only the pages of the crawl or sitemap job with that ID.
+	ParentID string
+	Limit    int
+	Offset   int
+}
+
+// ScrapeJobList is one page of a scrape job listing. Total counts every matching job.
+type ScrapeJobList struct {
+	Jobs   []*ScrapeJobRecord `json:"jobs"`
+	Total  int                `json:"total"`
+	Limit  int                `json:"limit"`
+	Offset int                `json:"offset"`
+}
+
+// ScrapedPage records what was last fetched from a URL, so the page can be
+// re-fetched conditionally and a changed page updates the document stored from it.
+type ScrapedPage struct {
+	URL        string `json:"url"`
+	DocumentID string `json:"document_id"`
+	// ETag and LastModified are the validators the server sent with the page.
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	// ContentHash is a SHA-256 of the stored document's title, content, category and tags.
+	ContentHash string `json:"content_hash"`
+	// OverridesHash is a SHA-256 of the category and tags the page's job set,
+	// or empty if it set neither.
+	OverridesHash string    `json:"overrides_hash,omitempty"`
+	FetchedAt     time.Time `json:"fetched_at"`
+	// ChangedAt is when the content last differed from the stored copy.
+	ChangedAt time.Time `json:"changed_at"`
 }
 
 // ChatSession represents a chat session
@@ -41,6 +324,59 @@ type ChatSession struct {
 	Messages  []*ChatMessage `json:"messages"`
 }
 
+// Roles of the messages in a conversation with an LLM.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// LLMMessage is a single role-tagged message sent to a chat model.
+type LLMMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// GenerationOptions tunes how an LLM generates a response. Nil or empty fields
+// fall back to the configured defaults.
+type GenerationOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	NumCtx      *int     `json:"num_ctx,omitempty"`
+	NumPredict  *int     `json:"num_predict,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+}
+
+// WithDefaults returns the options with every unset field taken from defaults.
+func (o GenerationOptions) WithDefaults(defaults GenerationOptions) GenerationOptions {
+	if o.Temperature == nil {
+		o.Temperature = defaults.Temperature
+	}
+	if o.TopP == nil {
+		o.TopP = defaults.TopP
+	}
+	if o.NumCtx == nil {
+		o.NumCtx = defaults.NumCtx
+	}
+	if o.NumPredict == nil {
+		o.NumPredict = defaults.NumPredict
+	}
+	if len(o.Stop) == 0 {
+		o.Stop = defaults.Stop
+	}
+	if o.Seed == nil {
+		o.Seed = defaults.Seed
+	}
+	return o
+}
+
+// IsZero reports whether no option is set.
+func (o GenerationOptions) IsZero() bool {
+	return o.Temperature == nil && o.TopP == nil && o.NumCtx == nil &&
+		o.NumPredict == nil && len(o.Stop) == 0 && o.Seed == nil
+}
+
 // ChatMessage represents a chat message
 type ChatMessage struct {
 	ID        string    `json:"id"`
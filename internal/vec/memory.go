@@ -0,0 +1,367 @@
+package vec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"tech-docs-ai/internal/types"
+)
+
+// Distance is the metric used to compare vectors. The names match Qdrant's.
+type Distance string
+
+// Supported distance metrics.
+const (
+	DistanceCosine Distance = "Cosine"
+	DistanceDot    Distance = "Dot"
+	DistanceEuclid Distance = "Euclid"
+)
+
+// ParseDistance returns the distance metric with the given name, ignoring case.
+func ParseDistance(name string) (Distance, error) {
+	for _, d := range []Distance{DistanceCosine, DistanceDot, DistanceEuclid} {
+		if strings.EqualFold(name, string(d)) {
+			return d, nil
+		}
+	}
+	return "", fmt.Errorf("unknown distance %q", name)
+}
+
+// MemoryStore is an in-process vector store for tests and single-binary
+// deployments. Every search compares against all points. When it has a file
+// path, every change is written to that file and the points are loaded from
+// it on start.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	distance  Distance
+	dimension int
+	points    map[string]memoryPoint
+	path      string
+	// defaults fills in the search options a request leaves unset
+	defaults types.SearchOptions
+}
+
+// memoryPoint is a stored point, also the on-disk format.
+type memoryPoint struct {
+	DocumentID string                 `json:"document_id"`
+	ChunkIndex int                    `json:"chunk_index"`
+	Vector     []float32              `json:"vector"`
+	Payload    map[string]interface{} `json:"payload"`
+}
+
+// memorySnapshot is the content of a persisted MemoryStore.
+type memorySnapshot struct {
+	Distance Distance               `json:"distance"`
+	Points   map[string]memoryPoint `json:"points"`
+}
+
+// NewMemoryStore creates an empty, non-persistent store using the given distance.
+func NewMemoryStore(distance Distance) *MemoryStore {
+	return &MemoryStore{
+		distance: distance,
+		points:   make(map[string]memoryPoint),
+	}
+}
+
+// OpenMemoryStore creates a store persisted to path, loading the points
+// already saved there. A missing file starts an empty store.
+func OpenMemoryStore(path string, distance Distance) (*MemoryStore, error) {
+	store := NewMemoryStore(distance)
+	store.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vector store file: %w", err)
+	}
+
+	var snapshot memorySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode vector store file: %w", err)
+	}
+	if snapshot.Distance != distance {
+		return nil, fmt.Errorf("vector store file uses %s distance, not %s", snapshot.Distance, distance)
+	}
+
+	for id, p := range snapshot.Points {
+		store.points[id] = p
+		store.dimension = len(p.Vector)
+	}
+	return store, nil
+}
+
+// NewMemoryStoreFromEnv creates a memory store configured by the
+// VECTOR_MEMORY_DISTANCE (default Cosine) and VECTOR_MEMORY_PATH environment
+// variables. Without a path nothing is persisted. Cosine stores use the same
+// score threshold settings as Qdrant.
+func NewMemoryStoreFromEnv() (*MemoryStore, error) {
+	distance := DistanceCosine
+	if name := os.Getenv("VECTOR_MEMORY_DISTANCE"); name != "" {
+		d, err := ParseDistance(name)
+		if err != nil {
+			return nil, err
+		}
+		distance = d
+	}
+
+	store := NewMemoryStore(distance)
+	if path := os.Getenv("VECTOR_MEMORY_PATH"); path != "" {
+		var err error
+		if store, err = OpenMemoryStore(path, distance); err != nil {
+			return nil, err
+		}
+	}
+
+	if distance == DistanceCosine {
+		store.defaults = searchDefaults(StoreMemory)
+	}
+	return store, nil
+}
+
+// StoreVectors stores the points, replacing any point with the same document ID and chunk index.
+// All vectors in a store must have the same dimension.
+func (m *MemoryStore) StoreVectors(ctx context.Context, points []types.VectorPoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dimension := m.dimension
+	for i, p := range points {
+		if len(p.Vector) == 0 {
+			return fmt.Errorf("empty vector at index %d", i)
+		}
+		if p.DocumentID == "" {
+			return fmt.Errorf("missing document ID at index %d", i)
+		}
+		if dimension == 0 {
+			dimension = len(p.Vector)
+		}
+		if len(p.Vector) != dimension {
+			return fmt.Errorf("vector at index %d has dimension %d, expected %d", i, len(p.Vector), dimension)
+		}
+	}
+
+	m.dimension = dimension
+	for _, p := range points {
+		m.points[PointID(p.DocumentID, p.ChunkIndex)] = memoryPoint{
+			DocumentID: p.DocumentID,
+			ChunkIndex: p.ChunkIndex,
+			Vector:     append([]float32(nil), p.Vector...),
+			Payload:    p.Payload,
+		}
+	}
+
+	return m.save()
+}
+
+// DeleteByDocumentID removes every point that belongs to the given document.
+func (m *MemoryStore) DeleteByDocumentID(ctx context.Context, documentID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, p := range m.points {
+		if p.DocumentID == documentID {
+			delete(m.points, id)
+		}
+	}
+
+	return m.save()
+}
+
+// DeleteStaleChunks removes the points of the given document whose chunk index is chunkCount or higher.
+func (m *MemoryStore) DeleteStaleChunks(ctx context.Context, documentID string, chunkCount int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, p := range m.points {
+		if p.DocumentID == documentID && p.ChunkIndex >= chunkCount {
+			delete(m.points, id)
+		}
+	}
+
+	return m.save()
+}
+
+// SearchVector returns the points most similar to vector that match the filter.
+// As in Qdrant, Euclid scores are distances: lower is closer, and the score
+// threshold is the largest distance kept.
+func (m *MemoryStore) SearchVector(ctx context.Context, vector []float32, limit int, opts types.SearchOptions) ([]types.SearchResult, error) {
+	if len(vector) == 0 {
+		return nil, fmt.Errorf("empty vector")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.dimension != 0 && len(vector) != m.dimension {
+		return nil, fmt.Errorf("query vector has dimension %d, expected %d", len(vector), m.dimension)
+	}
+
+	threshold := opts.ScoreThreshold
+	if threshold == nil {
+		threshold = m.defaults.ScoreThreshold
+	}
+
+	var results []types.SearchResult
+	for id, p := range m.points {
+		if !matchesFilter(p, opts.Filter) {
+			continue
+		}
+
+		score := m.score(vector, p.Vector)
+		if threshold != nil && *threshold > 0 && !m.within(score, *threshold) {
+			continue
+		}
+
+		result := types.SearchResult{ID: id, Score: score, Metadata: p.Payload}
+		if opts.WithVectors {
+			result.Vector = append([]float32(nil), p.Vector...)
+		}
+		results = append(results, result)
+	}
+
+	// Best match first; ties are broken by ID so results are stable
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return m.within(results[i].Score, results[j].Score)
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	if opts.Offset >= len(results) {
+		return nil, nil
+	}
+	results = results[opts.Offset:]
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// score compares two vectors of the same dimension using the store's distance.
+func (m *MemoryStore) score(a, b []float32) float32 {
+	var dot, normA, normB, squared float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		normA += x * x
+		normB += y * y
+		squared += (x - y) * (x - y)
+	}
+
+	switch m.distance {
+	case DistanceDot:
+		return float32(dot)
+	case DistanceEuclid:
+		return float32(math.Sqrt(squared))
+	default:
+		if normA == 0 || normB == 0 {
+			return 0
+		}
+		return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+	}
+}
+
+// within reports whether score is at least as good as threshold.
+func (m *MemoryStore) within(score, threshold float32) bool {
+	if m.distance == DistanceEuclid {
+		return score <= threshold
+	}
+	return score >= threshold
+}
+
+// save writes the store to its file, if it has one. The file is replaced
+// atomically so a crash never leaves it half written. Callers hold m.mu.
+func (m *MemoryStore) save() error {
+	if m.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(memorySnapshot{Distance: m.distance, Points: m.points})
+	if err != nil {
+		return fmt.Errorf("failed to encode vector store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(m.path), filepath.Base(m.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create vector store file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write vector store file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write vector store file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), m.path); err != nil {
+		return fmt.Errorf("failed to replace vector store file: %w", err)
+	}
+	return nil
+}
+
+// matchesFilter reports whether a point's payload satisfies the filter.
+func matchesFilter(p memoryPoint, filter *types.VectorFilter) bool {
+	if filter.IsEmpty() {
+		return true
+	}
+
+	for _, cond := range filter.Must {
+		if !matchesCondition(p, cond) {
+			return false
+		}
+	}
+	for _, cond := range filter.MustNot {
+		if matchesCondition(p, cond) {
+			return false
+		}
+	}
+	if len(filter.Should) == 0 {
+		return true
+	}
+	for _, cond := range filter.Should {
+		if matchesCondition(p, cond) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesCondition reports whether the payload field has one of the condition's
+// values. For list fields it is enough that one element does.
+func matchesCondition(p memoryPoint, cond types.FieldCondition) bool {
+	var fieldValues []string
+	switch v := p.Payload[cond.Key].(type) {
+	case nil:
+		if cond.Key == "document_id" {
+			fieldValues = []string{p.DocumentID}
+		}
+	case []string:
+		fieldValues = v
+	case []interface{}:
+		for _, item := range v {
+			fieldValues = append(fieldValues, fmt.Sprint(item))
+		}
+	default:
+		fieldValues = []string{fmt.Sprint(v)}
+	}
+
+	for _, fv := range fieldValues {
+		for _, want := range cond.Values {
+			if fv == want {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,220 @@
+package vec
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"tech-docs-ai/internal/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Compile-time checks that both stores satisfy Store.
+var (
+	_ Store = (*QdrantClient)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
+
+// testPoints are three chunks of two documents in different categories.
+func testPoints() []types.VectorPoint {
+	return []types.VectorPoint{
+		{DocumentID: "doc_go", ChunkIndex: 0, Vector: []float32{1, 0}, Payload: map[string]interface{}{"document_id": "doc_go", "category": "Go", "tags": []string{"concurrency"}}},
+		{DocumentID: "doc_go", ChunkIndex: 1, Vector: []float32{0.8, 0.6}, Payload: map[string]interface{}{"document_id": "doc_go", "category": "Go", "tags": []string{"channels"}}},
+		{DocumentID: "doc_css", ChunkIndex: 0, Vector: []float32{0, 2}, Payload: map[string]interface{}{"document_id": "doc_css", "category": "CSS"}},
+	}
+}
+
+func resultIDs(results []types.SearchResult) []string {
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func TestMemoryStore_Distances(t *testing.T) {
+	tests := []struct {
+		distance Distance
+		expected []string
+		scores   []float32
+	}{
+		{DistanceCosine, []string{PointID("doc_go", 0), PointID("doc_go", 1), PointID("doc_css", 0)}, []float32{1, 0.8, 0}},
+		{DistanceDot, []string{PointID("doc_go", 0), PointID("doc_go", 1), PointID("doc_css", 0)}, []float32{2, 1.6, 0}},
+		{DistanceEuclid, []string{PointID("doc_go", 0), PointID("doc_go", 1), PointID("doc_css", 0)}, []float32{1, 1.3416408, 2.828427}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.distance), func(t *testing.T) {
+			store := NewMemoryStore(tt.distance)
+			require.NoError(t, store.StoreVectors(context.Background(), testPoints()))
+
+			results, err := store.SearchVector(context.Background(), []float32{2, 0}, 10, types.SearchOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, resultIDs(results))
+			for i, score := range tt.scores {
+				assert.InDelta(t, score, results[i].Score, 0.0001)
+			}
+		})
+	}
+}
+
+func TestMemoryStore_SearchOptions(t *testing.T) {
+	store := NewMemoryStore(DistanceCosine)
+	require.NoError(t, store.StoreVectors(context.Background(), testPoints()))
+
+	threshold := float32(0.9)
+	results, err := store.SearchVector(context.Background(), []float32{1, 0}, 10, types.SearchOptions{ScoreThreshold: &threshold})
+	require.NoError(t, err)
+	assert.Equal(t, []string{PointID("doc_go", 0)}, resultIDs(results))
+	assert.Nil(t, results[0].Vector)
+
+	results, err = store.SearchVector(context.Background(), []float32{1, 0}, 1, types.SearchOptions{Offset: 1, WithVectors: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{PointID("doc_go", 1)}, resultIDs(results))
+	assert.Equal(t, []float32{0.8, 0.6}, results[0].Vector)
+
+	results, err = store.SearchVector(context.Background(), []float32{1, 0}, 10, types.SearchOptions{Offset: 5})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestMemoryStore_EuclidThreshold(t *testing.T) {
+	store := NewMemoryStore(DistanceEuclid)
+	require.NoError(t, store.StoreVectors(context.Background(), testPoints()))
+
+	// For Euclid the threshold is the largest distance kept
+	threshold := float32(0.6)
+	results, err := store.SearchVector(context.Background(), []float32{0.9, 0.1}, 10, types.SearchOptions{ScoreThreshold: &threshold})
+	require.NoError(t, err)
+	assert.Equal(t, []string{PointID("doc_go", 0), PointID("doc_go", 1)}, resultIDs(results))
+}
+
+func TestMemoryStore_Filter(t *testing.T) {
+	store := NewMemoryStore(DistanceCosine)
+	require.NoError(t, store.StoreVectors(context.Background(), testPoints()))
+
+	tests := []struct {
+		name     string
+		filter   *types.VectorFilter
+		expected []string
+	}{
+		{
+			name:     "must",
+			filter:   &types.VectorFilter{Must: []types.FieldCondition{{Key: "category", Values: []string{"CSS"}}}},
+			expected: []string{PointID("doc_css", 0)},
+		},
+		{
+			name:     "list field matches any element",
+			filter:   &types.VectorFilter{Must: []types.FieldCondition{{Key: "tags", Values: []string{"channels", "generics"}}}},
+			expected: []string{PointID("doc_go", 1)},
+		},
+		{
+			name:     "must not",
+			filter:   &types.VectorFilter{MustNot: []types.FieldCondition{{Key: "category", Values: []string{"Go"}}}},
+			expected: []string{PointID("doc_css", 0)},
+		},
+		{
+			name: "should",
+			filter: &types.VectorFilter{Should: []types.FieldCondition{
+				{Key: "tags", Values: []string{"concurrency"}},
+				{Key: "category", Values: []string{"CSS"}},
+			}},
+			expected: []string{PointID("doc_go", 0), PointID("doc_css", 0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := store.SearchVector(context.Background(), []float32{1, 0}, 10, types.SearchOptions{Filter: tt.filter})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, resultIDs(results))
+		})
+	}
+}
+
+func TestMemoryStore_ReplaceAndDelete(t *testing.T) {
+	store := NewMemoryStore(DistanceCosine)
+	ctx := context.Background()
+	require.NoError(t, store.StoreVectors(ctx, testPoints()))
+
+	// Storing a chunk again replaces it
+	require.NoError(t, store.StoreVectors(ctx, []types.VectorPoint{{DocumentID: "doc_css", ChunkIndex: 0, Vector: []float32{1, 0}}}))
+	results, err := store.SearchVector(ctx, []float32{1, 0}, 10, types.SearchOptions{})
+	require.NoError(t, err)
+	assert.Len(t, results, 3)
+
+	require.NoError(t, store.DeleteByDocumentID(ctx, "doc_go"))
+	results, err = store.SearchVector(ctx, []float32{1, 0}, 10, types.SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{PointID("doc_css", 0)}, resultIDs(results))
+}
+
+func TestMemoryStore_DeleteStaleChunks(t *testing.T) {
+	store := NewMemoryStore(DistanceCosine)
+	ctx := context.Background()
+	require.NoError(t, store.StoreVectors(ctx, testPoints()))
+
+	// Only doc_go's chunks from index 1 on go
+	require.NoError(t, store.DeleteStaleChunks(ctx, "doc_go", 1))
+	results, err := store.SearchVector(ctx, []float32{1, 0}, 10, types.SearchOptions{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{PointID("doc_go", 0), PointID("doc_css", 0)}, resultIDs(results))
+}
+
+func TestMemoryStore_InvalidVectors(t *testing.T) {
+	store := NewMemoryStore(DistanceCosine)
+	ctx := context.Background()
+
+	assert.Error(t, store.StoreVectors(ctx, []types.VectorPoint{{DocumentID: "doc", Vector: nil}}))
+	assert.Error(t, store.StoreVectors(ctx, []types.VectorPoint{{Vector: []float32{1}}}))
+
+	require.NoError(t, store.StoreVectors(ctx, testPoints()))
+	err := store.StoreVectors(ctx, []types.VectorPoint{{DocumentID: "doc", Vector: []float32{1, 2, 3}}})
+	assert.ErrorContains(t, err, "dimension 3, expected 2")
+
+	_, err = store.SearchVector(ctx, []float32{1, 2, 3}, 10, types.SearchOptions{})
+	assert.Error(t, err)
+}
+
+func TestMemoryStore_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.json")
+	ctx := context.Background()
+
+	store, err := OpenMemoryStore(path, DistanceCosine)
+	require.NoError(t, err)
+	require.NoError(t, store.StoreVectors(ctx, testPoints()))
+	require.NoError(t, store.DeleteByDocumentID(ctx, "doc_css"))
+
+	reopened, err := OpenMemoryStore(path, DistanceCosine)
+	require.NoError(t, err)
+
+	filter := &types.VectorFilter{Must: []types.FieldCondition{{Key: "tags", Values: []string{"channels"}}}}
+	results, err := reopened.SearchVector(ctx, []float32{1, 0}, 10, types.SearchOptions{Filter: filter})
+	require.NoError(t, err)
+	require.Equal(t, []string{PointID("doc_go", 1)}, resultIDs(results))
+	assert.Equal(t, "doc_go", results[0].Metadata["document_id"])
+
+	_, err = OpenMemoryStore(path, DistanceDot)
+	assert.ErrorContains(t, err, "uses Cosine distance")
+}
+
+func TestNewStore(t *testing.T) {
+	t.Setenv("VECTOR_STORE", "memory")
+	t.Setenv("VECTOR_MEMORY_DISTANCE", "euclid")
+	t.Setenv("VECTOR_MEMORY_PATH", "")
+
+	store, err := NewStore()
+	require.NoError(t, err)
+	require.IsType(t, &MemoryStore{}, store)
+	assert.Equal(t, DistanceEuclid, store.(*MemoryStore).distance)
+
+	t.Setenv("VECTOR_MEMORY_DISTANCE", "manhattan")
+	_, err = NewStore()
+	assert.Error(t, err)
+
+	t.Setenv("VECTOR_STORE", "faiss")
+	_, err = NewStore()
+	assert.Error(t, err)
+}
@@ -0,0 +1,375 @@
+package vec
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"tech-docs-ai/internal/repo"
+	"tech-docs-ai/internal/types"
+
+	"github.com/lib/pq"
+)
+
+// pgVectorTable is the table holding one row per document chunk.
+const pgVectorTable = "document_vectors"
+
+// DefaultPgVectorDimension is the embedding size used when PGVECTOR_DIMENSION is not set.
+const DefaultPgVectorDimension = repo.DefaultPgVectorDimension
+
+// pgPayloadColumns are payload fields copied into their own columns so they can be filtered and indexed.
+var pgPayloadColumns = []string{"category", "source", "author"}
+
+// PgVectorStore is a vector store backed by the pgvector extension in the
+// same PostgreSQL database as the documents. Distances are cosine, so scores
+// are comparable to Qdrant's.
+type PgVectorStore struct {
+	db        *sql.DB
+	dimension int
+	// defaults fills in the search options a request leaves unset
+	defaults types.SearchOptions
+}
+
+// NewPgVectorStore connects to the PostgreSQL database configured by the
+// POSTGRES_* environment variables. The vector table is created by the
+// schema migrations when they run with VECTOR_STORE set to pgvector;
+// PGVECTOR_DIMENSION sets its embedding size.
+func NewPgVectorStore() (*PgVectorStore, error) {
+	db, err := repo.OpenDB()
+	if err != nil {
+		return nil, err
+	}
+
+	return &PgVectorStore{
+		db:        db,
+		dimension: repo.PgVectorDimension(),
+		defaults:  searchDefaults(pgVectorTable),
+	}, nil
+}
+
+// StoreVectors stores the points in one transaction, replacing any row with
+// the same document ID and chunk index.
+func (s *PgVectorStore) StoreVectors(ctx context.Context, points []types.VectorPoint) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return s.insertPoints(ctx, tx, points)
+	})
+}
+
+// StoreDocumentWithVectors stores a document and replaces its vectors in one
+// transaction, so readers never see a document without its chunks or chunks
+// of a revision that was not stored. Every point must belong to the document.
+func (s *PgVectorStore) StoreDocumentWithVectors(ctx context.Context, doc *types.Document, points []types.VectorPoint) error {
+	if doc.ID == "" {
+		return fmt.Errorf("document has no ID")
+	}
+	for i, p := range points {
+		if p.DocumentID != doc.ID {
+			return fmt.Errorf("point at index %d belongs to document %q, not %q", i, p.DocumentID, doc.ID)
+		}
+	}
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := repo.UpsertDocument(ctx, tx, doc); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+pgVectorTable+" WHERE document_id = $1", doc.ID); err != nil {
+			return fmt.Errorf("failed to delete previous vectors: %w", err)
+		}
+		return s.insertPoints(ctx, tx, points)
+	})
+}
+
+// DeleteDocumentWithVectors deletes a document and its vectors in one
+// transaction. It reports whether the document existed.
+func (s *PgVectorStore) DeleteDocumentWithVectors(ctx context.Context, documentID string) (bool, error) {
+	var deleted bool
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+pgVectorTable+" WHERE document_id = $1", documentID); err != nil {
+			return fmt.Errorf("failed to delete vectors: %w", err)
+		}
+		var err error
+		deleted, err = repo.DeleteDocument(ctx, tx, documentID)
+		return err
+	})
+	return deleted, err
+}
+
+// DeleteByDocumentID removes every row that belongs to the given document.
+func (s *PgVectorStore) DeleteByDocumentID(ctx context.Context, documentID string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM "+pgVectorTable+" WHERE document_id = $1", documentID); err != nil {
+		return fmt.Errorf("failed to delete vectors: %w", err)
+	}
+	return nil
+}
+
+// DeleteStaleChunks removes the rows of the given document whose chunk index is chunkCount or higher.
+func (s *PgVectorStore) DeleteStaleChunks(ctx context.Context, documentID string, chunkCount int) error {
+	if _, err := s.db.ExecContext(ctx,
+		"DELETE FROM "+pgVectorTable+" WHERE document_id = $1 AND chunk_index >= $2", documentID, chunkCount); err != nil {
+		return fmt.Errorf("failed to delete stale vectors: %w", err)
+	}
+	return nil
+}
+
+// insertPoints upserts points within tx.
+func (s *PgVectorStore) insertPoints(ctx context.Context, tx *sql.Tx, points []types.VectorPoint) error {
+	for i, p := range points {
+		if len(p.Vector) == 0 {
+			return fmt.Errorf("empty vector at index %d", i)
+		}
+		if p.DocumentID == "" {
+			return fmt.Errorf("missing document ID at index %d", i)
+		}
+		if len(p.Vector) != s.dimension {
+			return fmt.Errorf("vector at index %d has dimension %d, expected %d", i, len(p.Vector), s.dimension)
+		}
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO `+pgVectorTable+` (document_id, chunk_index, embedding, category, source, author, tags, payload)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (document_id, chunk_index) DO UPDATE SET
+			embedding = EXCLUDED.embedding,
+			category = EXCLUDED.category,
+			source = EXCLUDED.source,
+			author = EXCLUDED.author,
+			tags = EXCLUDED.tags,
+			payload = EXCLUDED.payload
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare vector insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, p := range points {
+		payloadJSON, err := json.Marshal(p.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payload: %w", err)
+		}
+
+		args := []interface{}{p.DocumentID, p.ChunkIndex, vectorLiteral(p.Vector)}
+		for _, column := range pgPayloadColumns {
+			args = append(args, payloadString(p.Payload, column))
+		}
+		args = append(args, pq.Array(payloadStrings(p.Payload, "tags")), payloadJSON)
+
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return fmt.Errorf("failed to store vectors: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SearchVector returns the chunks most similar to vector that match the
+// filter and score threshold. Unset options use the table's defaults.
+func (s *PgVectorStore) SearchVector(ctx context.Context, vector []float32, limit int, opts types.SearchOptions) ([]types.SearchResult, error) {
+	if len(vector) == 0 {
+		return nil, fmt.Errorf("empty vector")
+	}
+	if opts.ScoreThreshold == nil {
+		opts.ScoreThreshold = s.defaults.ScoreThreshold
+	}
+	if opts.HNSWEf == nil {
+		opts.HNSWEf = s.defaults.HNSWEf
+	}
+
+	query, args := pgSearchQuery(vector, limit, opts)
+
+	var results []types.SearchResult
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		// Search parameters only apply to this transaction
+		if opts.HNSWEf != nil {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", *opts.HNSWEf)); err != nil {
+				return fmt.Errorf("failed to set hnsw.ef_search: %w", err)
+			}
+		}
+		if opts.Exact {
+			if _, err := tx.ExecContext(ctx, "SET LOCAL enable_indexscan = off"); err != nil {
+				return fmt.Errorf("failed to disable index scan: %w", err)
+			}
+		}
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to search vectors: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var documentID string
+			var chunkIndex int
+			var score float64
+			var payloadJSON []byte
+			var embedding sql.NullString
+
+			if err := rows.Scan(&documentID, &chunkIndex, &score, &payloadJSON, &embedding); err != nil {
+				return fmt.Errorf("failed to scan search result: %w", err)
+			}
+
+			result := types.SearchResult{
+				ID:    PointID(documentID, chunkIndex),
+				Score: float32(score),
+			}
+			if err := json.Unmarshal(payloadJSON, &result.Metadata); err != nil {
+				return fmt.Errorf("failed to unmarshal payload: %w", err)
+			}
+			if embedding.Valid {
+				if result.Vector, err = parseVector(embedding.String); err != nil {
+					return err
+				}
+			}
+			results = append(results, result)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Close closes the database connection.
+func (s *PgVectorStore) Close() error {
+	return s.db.Close()
+}
+
+// inTx runs fn in a transaction, committing when it succeeds.
+func (s *PgVectorStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// pgSearchQuery builds the similarity search statement and its arguments.
+// The score is the cosine similarity, 1 minus pgvector's cosine distance.
+func pgSearchQuery(vector []float32, limit int, opts types.SearchOptions) (string, []interface{}) {
+	args := []interface{}{vectorLiteral(vector)}
+
+	embedding := "NULL"
+	if opts.WithVectors {
+		embedding = "embedding::text"
+	}
+
+	var where []string
+	if !opts.Filter.IsEmpty() {
+		where = append(where, pgFilter(opts.Filter, &args))
+	}
+	if opts.ScoreThreshold != nil && *opts.ScoreThreshold > 0 {
+		args = append(args, *opts.ScoreThreshold)
+		where = append(where, fmt.Sprintf("1 - (embedding <=> $1) >= $%d", len(args)))
+	}
+
+	query := fmt.Sprintf("SELECT document_id, chunk_index, 1 - (embedding <=> $1) AS score, payload, %s FROM %s", embedding, pgVectorTable)
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	args = append(args, limit, opts.Offset)
+	query += fmt.Sprintf(" ORDER BY embedding <=> $1 LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	return query, args
+}
+
+// pgFilter translates a payload filter into a SQL condition, appending its arguments to args.
+func pgFilter(filter *types.VectorFilter, args *[]interface{}) string {
+	var clauses []string
+	for _, cond := range filter.Must {
+		clauses = append(clauses, pgCondition(cond, args))
+	}
+	for _, cond := range filter.MustNot {
+		clauses = append(clauses, "NOT COALESCE("+pgCondition(cond, args)+", false)")
+	}
+	if len(filter.Should) > 0 {
+		var should []string
+		for _, cond := range filter.Should {
+			should = append(should, pgCondition(cond, args))
+		}
+		clauses = append(clauses, "("+strings.Join(should, " OR ")+")")
+	}
+	return strings.Join(clauses, " AND ")
+}
+
+// pgCondition matches one field against any of the condition's values, using
+// the payload columns where there is one and the JSON payload otherwise.
+func pgCondition(cond types.FieldCondition, args *[]interface{}) string {
+	*args = append(*args, pq.Array(cond.Values))
+	values := fmt.Sprintf("$%d", len(*args))
+
+	switch cond.Key {
+	case "tags":
+		return "tags && " + values
+	case "document_id", "category", "source", "author":
+		return cond.Key + " = ANY(" + values + ")"
+	default:
+		*args = append(*args, cond.Key)
+		return fmt.Sprintf("payload->>$%d = ANY(%s)", len(*args), values)
+	}
+}
+
+// vectorLiteral formats a vector in pgvector's text format, e.g. [1,0.5,2].
+func vectorLiteral(vector []float32) string {
+	parts := make([]string, len(vector))
+	for i, v := range vector {
+		parts[i] = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// parseVector parses a vector in pgvector's text format.
+func parseVector(text string) ([]float32, error) {
+	text = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(text), "["), "]")
+	if text == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(text, ",")
+	vector := make([]float32, len(parts))
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse vector: %w", err)
+		}
+		vector[i] = float32(v)
+	}
+	return vector, nil
+}
+
+// payloadString returns a string payload field, or nil so the column is NULL.
+func payloadString(payload map[string]interface{}, key string) interface{} {
+	if v, ok := payload[key].(string); ok && v != "" {
+		return v
+	}
+	return nil
+}
+
+// payloadStrings returns a list payload field as strings.
+func payloadStrings(payload map[string]interface{}, key string) []string {
+	switch v := payload[key].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,79 @@
+package vec
+
+import (
+	"testing"
+
+	"tech-docs-ai/internal/types"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ DocumentIndexer = (*PgVectorStore)(nil)
+
+func TestPgSearchQuery(t *testing.T) {
+	query, args := pgSearchQuery([]float32{0.5, 1}, 5, types.SearchOptions{})
+
+	assert.Equal(t, "SELECT document_id, chunk_index, 1 - (embedding <=> $1) AS score, payload, NULL FROM document_vectors ORDER BY embedding <=> $1 LIMIT $2 OFFSET $3", query)
+	assert.Equal(t, []interface{}{"[0.5,1]", 5, 0}, args)
+}
+
+func TestPgSearchQuery_Options(t *testing.T) {
+	threshold := float32(0.7)
+	filter := &types.VectorFilter{
+		Must:    []types.FieldCondition{{Key: "category", Values: []string{"Go"}}},
+		Should:  []types.FieldCondition{{Key: "tags", Values: []string{"a", "b"}}, {Key: "level", Values: []string{"beginner"}}},
+		MustNot: []types.FieldCondition{{Key: "source", Values: []string{"ai-response"}}},
+	}
+
+	query, args := pgSearchQuery([]float32{1}, 10, types.SearchOptions{
+		Filter:         filter,
+		ScoreThreshold: &threshold,
+		Offset:         20,
+		WithVectors:    true,
+	})
+
+	assert.Equal(t, "SELECT document_id, chunk_index, 1 - (embedding <=> $1) AS score, payload, embedding::text FROM document_vectors"+
+		" WHERE category = ANY($2) AND NOT COALESCE(source = ANY($3), false) AND (tags && $4 OR payload->>$6 = ANY($5))"+
+		" AND 1 - (embedding <=> $1) >= $7 ORDER BY embedding <=> $1 LIMIT $8 OFFSET $9", query)
+	assert.Equal(t, []interface{}{
+		"[1]",
+		pq.Array([]string{"Go"}),
+		pq.Array([]string{"ai-response"}),
+		pq.Array([]string{"a", "b"}),
+		pq.Array([]string{"beginner"}),
+		"level",
+		threshold,
+		10,
+		20,
+	}, args)
+}
+
+func TestVectorLiteral_RoundTrip(t *testing.T) {
+	vector := []float32{0.1, -2.5, 3e-7, 0}
+
+	literal := vectorLiteral(vector)
+	assert.Equal(t, "[0.1,-2.5,0.0000003,0]", literal)
+
+	parsed, err := parseVector(literal)
+	require.NoError(t, err)
+	assert.Equal(t, vector, parsed)
+
+	_, err = parseVector("[1,x]")
+	assert.Error(t, err)
+}
+
+func TestPayloadColumns(t *testing.T) {
+	payload := map[string]interface{}{
+		"category": "Go",
+		"author":   "",
+		"tags":     []interface{}{"a", "b"},
+	}
+
+	assert.Equal(t, "Go", payloadString(payload, "category"))
+	assert.Nil(t, payloadString(payload, "author"))
+	assert.Nil(t, payloadString(payload, "source"))
+	assert.Equal(t, []string{"a", "b"}, payloadStrings(payload, "tags"))
+	assert.Equal(t, []string{"x"}, payloadStrings(map[string]interface{}{"tags": []string{"x"}}, "tags"))
+}
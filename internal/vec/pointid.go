@@ -0,0 +1,28 @@
+package vec
+
+import (
+	"crypto/sha1"
+	"fmt"
+)
+
+// pointNamespace is the UUID namespace under which point IDs are derived.
+var pointNamespace = [16]byte{
+	0x3c, 0x1e, 0x9a, 0x52, 0x7d, 0x41, 0x4b, 0x8e,
+	0x9f, 0x0a, 0x6e, 0x23, 0xd4, 0x58, 0xb1, 0x07,
+}
+
+// PointID returns the ID of the vector point for a document chunk. It is a
+// UUIDv5, so storing the same chunk again overwrites the existing point.
+func PointID(documentID string, chunkIndex int) string {
+	h := sha1.New()
+	h.Write(pointNamespace[:])
+	h.Write([]byte(fmt.Sprintf("%s#%d", documentID, chunkIndex)))
+	sum := h.Sum(nil)
+
+	var u [16]byte
+	copy(u[:], sum)
+	u[6] = (u[6] & 0x0f) | 0x50 // version 5
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
@@ -2,19 +2,28 @@ package vec
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"tech-docs-ai/internal/types"
 )
 
+// DefaultScoreThreshold is the minimum similarity a search result needs when
+// no threshold is configured for the collection.
+const DefaultScoreThreshold float32 = 0.7
+
 // QdrantClient is a real implementation for vector storage using Qdrant.
 type QdrantClient struct {
 	apiURL     string
 	collection string
+	// defaults fills in the search options a request leaves unset
+	defaults   types.SearchOptions
 	httpClient *http.Client
 }
 
@@ -33,6 +42,7 @@ func NewQdrantClient() *QdrantClient {
 	client := &QdrantClient{
 		apiURL:     apiURL,
 		collection: collection,
+		defaults:   searchDefaults(collection),
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
@@ -44,6 +54,25 @@ func NewQdrantClient() *QdrantClient {
 	return client
 }
 
+// searchDefaults reads the default search options for a collection.
+// QDRANT_SCORE_THRESHOLD and QDRANT_HNSW_EF apply to every collection, and
+// QDRANT_SCORE_THRESHOLD_<COLLECTION> (upper-cased) overrides the threshold
+// for a single one. A threshold of 0 turns the cut-off off.
+func searchDefaults(collection string) types.SearchOptions {
+	threshold := DefaultScoreThreshold
+	for _, key := range []string{"QDRANT_SCORE_THRESHOLD", "QDRANT_SCORE_THRESHOLD_" + strings.ToUpper(collection)} {
+		if v, err := strconv.ParseFloat(os.Getenv(key), 32); err == nil {
+			threshold = float32(v)
+		}
+	}
+
+	opts := types.SearchOptions{ScoreThreshold: &threshold}
+	if v, err := strconv.Atoi(os.Getenv("QDRANT_HNSW_EF")); err == nil && v > 0 {
+		opts.HNSWEf = &v
+	}
+	return opts
+}
+
 // ensureCollection creates the collection if it doesn't exist.
 func (c *QdrantClient) ensureCollection() error {
 	// Check if collection exists
@@ -89,24 +118,98 @@ func (c *QdrantClient) ensureCollection() error {
 	return nil
 }
 
-// StoreVector stores a vector in Qdrant with metadata.
-func (c *QdrantClient) StoreVector(vector []float32, metadata map[string]interface{}) error {
-	if len(vector) == 0 {
-		return fmt.Errorf("empty vector")
+// maxUpsertBatch is the largest number of points sent in one upsert request.
+const maxUpsertBatch = 256
+
+// StoreVectors stores many vectors in Qdrant, upserting up to maxUpsertBatch points per request.
+// Point IDs are derived from the document ID and chunk index, so storing a chunk again replaces it.
+func (c *QdrantClient) StoreVectors(ctx context.Context, points []types.VectorPoint) error {
+	for i, p := range points {
+		if len(p.Vector) == 0 {
+			return fmt.Errorf("empty vector at index %d", i)
+		}
+		if p.DocumentID == "" {
+			return fmt.Errorf("missing document ID at index %d", i)
+		}
+	}
+
+	for start := 0; start < len(points); start += maxUpsertBatch {
+		end := start + maxUpsertBatch
+		if end > len(points) {
+			end = len(points)
+		}
+
+		batch := make([]map[string]interface{}, 0, end-start)
+		for _, p := range points[start:end] {
+			batch = append(batch, map[string]interface{}{
+				"id":      PointID(p.DocumentID, p.ChunkIndex),
+				"vector":  p.Vector,
+				"payload": p.Payload,
+			})
+		}
+
+		if err := c.upsertPoints(ctx, batch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteByDocumentID removes every point that belongs to the given document.
+func (c *QdrantClient) DeleteByDocumentID(ctx context.Context, documentID string) error {
+	return c.deletePoints(ctx, qdrantFilter(&types.VectorFilter{
+		Must: []types.FieldCondition{{Key: "document_id", Values: []string{documentID}}},
+	}))
+}
+
+// DeleteStaleChunks removes the points of the given document whose chunk index is chunkCount or higher.
+func (c *QdrantClient) DeleteStaleChunks(ctx context.Context, documentID string, chunkCount int) error {
+	filter := qdrantFilter(&types.VectorFilter{
+		Must: []types.FieldCondition{{Key: "document_id", Values: []string{documentID}}},
+	})
+	filter["must"] = append(filter["must"].([]map[string]interface{}), map[string]interface{}{
+		"key":   "chunk_index",
+		"range": map[string]interface{}{"gte": chunkCount},
+	})
+	return c.deletePoints(ctx, filter)
+}
+
+// deletePoints deletes the points matching a Qdrant filter.
+func (c *QdrantClient) deletePoints(ctx context.Context, filter map[string]interface{}) error {
+	deleteBody := map[string]interface{}{
+		"filter": filter,
+	}
+
+	jsonData, err := json.Marshal(deleteBody)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delete request: %w", err)
+	}
+
+	deleteURL := fmt.Sprintf("%s/collections/%s/points/delete?wait=true", c.apiURL, c.collection)
+	req, err := http.NewRequestWithContext(ctx, "POST", deleteURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete vectors: %w", err)
 	}
+	defer resp.Body.Close()
 
-	// Generate a unique numeric ID for the vector
-	vectorID := time.Now().UnixNano()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to delete vectors with status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
 
-	// Prepare the upsert request
+// upsertPoints sends a single upsert request for the given points.
+func (c *QdrantClient) upsertPoints(ctx context.Context, points []map[string]interface{}) error {
 	upsertBody := map[string]interface{}{
-		"points": []map[string]interface{}{
-			{
-				"id":      vectorID,
-				"vector":  vector,
-				"payload": metadata,
-			},
-		},
+		"points": points,
 	}
 
 	jsonData, err := json.Marshal(upsertBody)
@@ -115,7 +218,7 @@ func (c *QdrantClient) StoreVector(vector []float32, metadata map[string]interfa
 	}
 
 	upsertURL := fmt.Sprintf("%s/collections/%s/points", c.apiURL, c.collection)
-	req, err := http.NewRequest("PUT", upsertURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "PUT", upsertURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -123,19 +226,21 @@ func (c *QdrantClient) StoreVector(vector []float32, metadata map[string]interfa
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to store vector: %w", err)
+		return fmt.Errorf("failed to store vectors: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to store vector with status: %d", resp.StatusCode)
+		return fmt.Errorf("failed to store vectors with status: %d", resp.StatusCode)
 	}
 
 	return nil
 }
 
 // SearchVector searches for similar vectors in Qdrant and returns results with metadata.
-func (c *QdrantClient) SearchVector(vector []float32, limit int) ([]types.SearchResult, error) {
+// Qdrant applies the filter and score threshold itself, so results that do not
+// match never leave the vector store. Unset options use the collection's defaults.
+func (c *QdrantClient) SearchVector(ctx context.Context, vector []float32, limit int, opts types.SearchOptions) ([]types.SearchResult, error) {
 	if len(vector) == 0 {
 		return nil, fmt.Errorf("empty vector")
 	}
@@ -145,7 +250,34 @@ func (c *QdrantClient) SearchVector(vector []float32, limit int) ([]types.Search
 		"vector":       vector,
 		"limit":        limit,
 		"with_payload": true,
-		"with_vectors": false,
+		"with_vectors": opts.WithVectors,
+	}
+	if !opts.Filter.IsEmpty() {
+		searchBody["filter"] = qdrantFilter(opts.Filter)
+	}
+	if opts.Offset > 0 {
+		searchBody["offset"] = opts.Offset
+	}
+
+	threshold := opts.ScoreThreshold
+	if threshold == nil {
+		threshold = c.defaults.ScoreThreshold
+	}
+	if threshold != nil && *threshold > 0 {
+		searchBody["score_threshold"] = *threshold
+	}
+
+	params := map[string]interface{}{}
+	if opts.HNSWEf != nil {
+		params["hnsw_ef"] = *opts.HNSWEf
+	} else if c.defaults.HNSWEf != nil {
+		params["hnsw_ef"] = *c.defaults.HNSWEf
+	}
+	if opts.Exact {
+		params["exact"] = true
+	}
+	if len(params) > 0 {
+		searchBody["params"] = params
 	}
 
 	jsonData, err := json.Marshal(searchBody)
@@ -154,7 +286,13 @@ func (c *QdrantClient) SearchVector(vector []float32, limit int) ([]types.Search
 	}
 
 	searchURL := fmt.Sprintf("%s/collections/%s/points/search", c.apiURL, c.collection)
-	resp, err := c.httpClient.Post(searchURL, "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", searchURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search vectors: %w", err)
 	}
@@ -190,6 +328,16 @@ func (c *QdrantClient) SearchVector(vector []float32, limit int) ([]types.Search
 					result.Metadata = payload
 				}
 
+				// Extract the stored vector, present when it was requested
+				if values, ok := point["vector"].([]interface{}); ok {
+					result.Vector = make([]float32, 0, len(values))
+					for _, v := range values {
+						if f, ok := v.(float64); ok {
+							result.Vector = append(result.Vector, float32(f))
+						}
+					}
+				}
+
 				results = append(results, result)
 			}
 		}
@@ -197,3 +345,34 @@ func (c *QdrantClient) SearchVector(vector []float32, limit int) ([]types.Search
 
 	return results, nil
 }
+
+// qdrantFilter translates a payload filter into Qdrant's filter JSON.
+func qdrantFilter(filter *types.VectorFilter) map[string]interface{} {
+	result := map[string]interface{}{}
+	if len(filter.Must) > 0 {
+		result["must"] = qdrantConditions(filter.Must)
+	}
+	if len(filter.Should) > 0 {
+		result["should"] = qdrantConditions(filter.Should)
+	}
+	if len(filter.MustNot) > 0 {
+		result["must_not"] = qdrantConditions(filter.MustNot)
+	}
+	return result
+}
+
+// qdrantConditions translates field conditions into Qdrant match conditions.
+func qdrantConditions(conditions []types.FieldCondition) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(conditions))
+	for _, cond := range conditions {
+		match := map[string]interface{}{"any": cond.Values}
+		if len(cond.Values) == 1 {
+			match = map[string]interface{}{"value": cond.Values[0]}
+		}
+		result = append(result, map[string]interface{}{
+			"key":   cond.Key,
+			"match": match,
+		})
+	}
+	return result
+}
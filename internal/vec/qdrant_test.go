@@ -0,0 +1,292 @@
+package vec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"tech-docs-ai/internal/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestQdrantClient creates a client for serverURL without touching the collection.
+func newTestQdrantClient(serverURL string) *QdrantClient {
+	return &QdrantClient{
+		apiURL:     serverURL,
+		collection: "test",
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+type upsertRequest struct {
+	Points []struct {
+		ID      interface{}            `json:"id"`
+		Vector  []float32              `json:"vector"`
+		Payload map[string]interface{} `json:"payload"`
+	} `json:"points"`
+}
+
+func TestQdrantClient_StoreVectors(t *testing.T) {
+	var batches []upsertRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/collections/test/points", r.URL.Path)
+
+		var req upsertRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		batches = append(batches, req)
+
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	client := newTestQdrantClient(server.URL)
+
+	points := make([]types.VectorPoint, maxUpsertBatch+1)
+	for i := range points {
+		points[i] = types.VectorPoint{
+			DocumentID: "doc_1",
+			ChunkIndex: i,
+			Vector:     []float32{float32(i)},
+			Payload:    map[string]interface{}{"chunk_index": i},
+		}
+	}
+
+	require.NoError(t, client.StoreVectors(context.Background(), points))
+
+	require.Len(t, batches, 2)
+	assert.Len(t, batches[0].Points, maxUpsertBatch)
+	assert.Len(t, batches[1].Points, 1)
+	assert.Equal(t, []float32{float32(maxUpsertBatch)}, batches[1].Points[0].Vector)
+	assert.EqualValues(t, maxUpsertBatch, batches[1].Points[0].Payload["chunk_index"])
+	assert.Equal(t, PointID("doc_1", 0), batches[0].Points[0].ID)
+	assert.Equal(t, PointID("doc_1", maxUpsertBatch), batches[1].Points[0].ID)
+}
+
+func TestQdrantClient_StoreVectors_EmptyVector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("no request expected for invalid points")
+	}))
+	defer server.Close()
+
+	client := newTestQdrantClient(server.URL)
+
+	err := client.StoreVectors(context.Background(), []types.VectorPoint{{DocumentID: "doc_1", Vector: []float32{0.1}}, {DocumentID: "doc_1"}})
+	assert.Error(t, err)
+}
+
+func TestQdrantClient_StoreVectors_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := newTestQdrantClient(server.URL)
+
+	err := client.StoreVectors(context.Background(), []types.VectorPoint{{DocumentID: "doc_1", Vector: []float32{0.1}}})
+	assert.Error(t, err)
+}
+
+func TestQdrantClient_StoreVectors_MissingDocumentID(t *testing.T) {
+	client := newTestQdrantClient("http://unused")
+
+	err := client.StoreVectors(context.Background(), []types.VectorPoint{{Vector: []float32{0.1}}})
+	assert.Error(t, err)
+}
+
+func TestQdrantClient_DeleteByDocumentID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/collections/test/points/delete", r.URL.Path)
+
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, map[string]interface{}{
+			"must": []interface{}{
+				map[string]interface{}{
+					"key":   "document_id",
+					"match": map[string]interface{}{"value": "doc_1"},
+				},
+			},
+		}, req["filter"])
+
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	client := newTestQdrantClient(server.URL)
+
+	require.NoError(t, client.DeleteByDocumentID(context.Background(), "doc_1"))
+}
+
+func TestQdrantClient_DeleteStaleChunks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/collections/test/points/delete", r.URL.Path)
+
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, map[string]interface{}{
+			"must": []interface{}{
+				map[string]interface{}{
+					"key":   "document_id",
+					"match": map[string]interface{}{"value": "doc_1"},
+				},
+				map[string]interface{}{
+					"key":   "chunk_index",
+					"range": map[string]interface{}{"gte": float64(3)},
+				},
+			},
+		}, req["filter"])
+
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	client := newTestQdrantClient(server.URL)
+
+	require.NoError(t, client.DeleteStaleChunks(context.Background(), "doc_1", 3))
+}
+
+func TestPointID(t *testing.T) {
+	id := PointID("doc_1", 0)
+
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, id)
+	assert.Equal(t, id, PointID("doc_1", 0))
+	assert.NotEqual(t, id, PointID("doc_1", 1))
+	assert.NotEqual(t, id, PointID("doc_2", 0))
+}
+
+func TestQdrantClient_SearchVector_Filter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/collections/test/points/search", r.URL.Path)
+
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, map[string]interface{}{
+			"must": []interface{}{
+				map[string]interface{}{"key": "category", "match": map[string]interface{}{"value": "Go"}},
+			},
+			"should": []interface{}{
+				map[string]interface{}{"key": "tags", "match": map[string]interface{}{"any": []interface{}{"a", "b"}}},
+			},
+			"must_not": []interface{}{
+				map[string]interface{}{"key": "source", "match": map[string]interface{}{"value": "ai-response"}},
+			},
+		}, req["filter"])
+
+		w.Write([]byte(`{"result":[{"id":"p1","score":0.9,"payload":{"document_id":"doc_1"}}]}`))
+	}))
+	defer server.Close()
+
+	client := newTestQdrantClient(server.URL)
+
+	filter := &types.VectorFilter{
+		Must:    []types.FieldCondition{{Key: "category", Values: []string{"Go"}}},
+		Should:  []types.FieldCondition{{Key: "tags", Values: []string{"a", "b"}}},
+		MustNot: []types.FieldCondition{{Key: "source", Values: []string{"ai-response"}}},
+	}
+	results, err := client.SearchVector(context.Background(), []float32{0.1}, 5, types.SearchOptions{Filter: filter})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "p1", results[0].ID)
+	assert.Equal(t, "doc_1", results[0].Metadata["document_id"])
+}
+
+func TestQdrantClient_SearchVector_NoFilter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.NotContains(t, req, "filter")
+		assert.NotContains(t, req, "score_threshold")
+		assert.NotContains(t, req, "params")
+
+		w.Write([]byte(`{"result":[]}`))
+	}))
+	defer server.Close()
+
+	client := newTestQdrantClient(server.URL)
+
+	_, err := client.SearchVector(context.Background(), []float32{0.1}, 5, types.SearchOptions{Filter: &types.VectorFilter{}})
+	require.NoError(t, err)
+}
+
+func TestQdrantClient_SearchVector_Options(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.InDelta(t, 0.5, req["score_threshold"], 0.0001)
+		assert.Equal(t, float64(10), req["offset"])
+		assert.Equal(t, true, req["with_vectors"])
+		assert.Equal(t, map[string]interface{}{"hnsw_ef": float64(128), "exact": true}, req["params"])
+
+		w.Write([]byte(`{"result":[{"id":"p1","score":0.6,"payload":{},"vector":[0.25,0.5]}]}`))
+	}))
+	defer server.Close()
+
+	client := newTestQdrantClient(server.URL)
+
+	threshold := float32(0.5)
+	ef := 128
+	results, err := client.SearchVector(context.Background(), []float32{0.1}, 5, types.SearchOptions{
+		ScoreThreshold: &threshold,
+		HNSWEf:         &ef,
+		Exact:          true,
+		Offset:         10,
+		WithVectors:    true,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, []float32{0.25, 0.5}, results[0].Vector)
+}
+
+func TestQdrantClient_SearchVector_CollectionDefaults(t *testing.T) {
+	var thresholds []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		thresholds = append(thresholds, req["score_threshold"])
+		assert.Equal(t, map[string]interface{}{"hnsw_ef": float64(64)}, req["params"])
+
+		w.Write([]byte(`{"result":[]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("QDRANT_SCORE_THRESHOLD", "0.6")
+	t.Setenv("QDRANT_SCORE_THRESHOLD_TEST", "0.8")
+	t.Setenv("QDRANT_HNSW_EF", "64")
+
+	client := newTestQdrantClient(server.URL)
+	client.defaults = searchDefaults("test")
+
+	// The request's threshold wins over the collection's, and 0 turns it off
+	override := float32(0.3)
+	disabled := float32(0)
+	for _, opts := range []types.SearchOptions{{}, {ScoreThreshold: &override}, {ScoreThreshold: &disabled}} {
+		_, err := client.SearchVector(context.Background(), []float32{0.1}, 5, opts)
+		require.NoError(t, err)
+	}
+
+	require.Len(t, thresholds, 3)
+	assert.InDelta(t, 0.8, thresholds[0], 0.0001)
+	assert.InDelta(t, 0.3, thresholds[1], 0.0001)
+	assert.Nil(t, thresholds[2])
+}
+
+func TestSearchDefaults(t *testing.T) {
+	t.Setenv("QDRANT_SCORE_THRESHOLD", "")
+	t.Setenv("QDRANT_HNSW_EF", "")
+
+	opts := searchDefaults("other")
+	require.NotNil(t, opts.ScoreThreshold)
+	assert.Equal(t, DefaultScoreThreshold, *opts.ScoreThreshold)
+	assert.Nil(t, opts.HNSWEf)
+
+	t.Setenv("QDRANT_SCORE_THRESHOLD", "0.6")
+	opts = searchDefaults("other")
+	assert.InDelta(t, 0.6, *opts.ScoreThreshold, 0.0001)
+}
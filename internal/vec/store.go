@@ -0,0 +1,55 @@
+package vec
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"tech-docs-ai/internal/types"
+)
+
+// Supported values for the VECTOR_STORE environment variable.
+const (
+	StoreQdrant   = "qdrant"
+	StoreMemory   = "memory"
+	StorePgVector = "pgvector"
+)
+
+// Store is a vector database that holds the embedded chunks of documents.
+type Store interface {
+	// StoreVectors stores points, replacing any point with the same document ID and chunk index.
+	StoreVectors(ctx context.Context, points []types.VectorPoint) error
+	// DeleteByDocumentID removes every point that belongs to the given document.
+	DeleteByDocumentID(ctx context.Context, documentID string) error
+	// DeleteStaleChunks removes the points of the given document whose chunk
+	// index is chunkCount or higher, left over from a longer revision.
+	DeleteStaleChunks(ctx context.Context, documentID string, chunkCount int) error
+	// SearchVector returns the points most similar to vector, best match first.
+	SearchVector(ctx context.Context, vector []float32, limit int, opts types.SearchOptions) ([]types.SearchResult, error)
+}
+
+// NewStore creates the vector store selected by the VECTOR_STORE environment
+// variable. It defaults to Qdrant.
+func NewStore() (Store, error) {
+	name := strings.ToLower(strings.TrimSpace(os.Getenv("VECTOR_STORE")))
+
+	switch name {
+	case "", StoreQdrant:
+		return NewQdrantClient(), nil
+	case StoreMemory:
+		return NewMemoryStoreFromEnv()
+	case StorePgVector:
+		return NewPgVectorStore()
+	default:
+		return nil, fmt.Errorf("unknown vector store %q", name)
+	}
+}
+
+// DocumentIndexer is implemented by vector stores that share a database with
+// the documents and can store or delete a document and its vectors atomically.
+type DocumentIndexer interface {
+	StoreDocumentWithVectors(ctx context.Context, doc *types.Document, points []types.VectorPoint) error
+	// DeleteDocumentWithVectors reports whether the document existed.
+	DeleteDocumentWithVectors(ctx context.Context, documentID string) (bool, error)
+}